@@ -1,16 +1,29 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/charmbracelet/log"
 	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/pricing"
 	"github.com/sonnes/chitragupt/reader"
+	"github.com/sonnes/chitragupt/reader/aider"
 	"github.com/sonnes/chitragupt/reader/claude"
+	"github.com/sonnes/chitragupt/reader/codex"
+	"github.com/sonnes/chitragupt/reader/cursor"
+	"github.com/sonnes/chitragupt/reader/gemini"
+	"github.com/sonnes/chitragupt/reader/native"
+	"github.com/sonnes/chitragupt/reader/opencode"
 	"github.com/sonnes/chitragupt/redact"
 	"github.com/sonnes/chitragupt/render"
 	htmlrender "github.com/sonnes/chitragupt/render/html"
+	jsonrender "github.com/sonnes/chitragupt/render/json"
+	"github.com/sonnes/chitragupt/render/markdown"
 	"github.com/sonnes/chitragupt/render/terminal"
 	"github.com/urfave/cli/v3"
 )
@@ -19,21 +32,54 @@ import (
 type app struct {
 	readers   map[string]func() reader.Reader
 	renderers map[string]func() render.Renderer
+
+	// includeReadResults keeps Read tool output in readers that drop it.
+	includeReadResults bool
+	// mergeResumed stitches resumed sessions into the session they continue.
+	mergeResumed bool
+	// transcriptsDir is the directory or archive file the cg reader searches.
+	transcriptsDir string
+
+	// report collects warnings from every reader the app creates.
+	report *reader.Report
 }
 
 func newApp() *app {
-	return &app{
-		readers: map[string]func() reader.Reader{
-			"claude": func() reader.Reader { return &claude.Reader{} },
-		},
-		renderers: map[string]func() render.Renderer{
-			"terminal": func() render.Renderer { return terminal.New() },
-			"html":     func() render.Renderer { return htmlrender.New() },
+	a := &app{report: &reader.Report{}}
+	a.readers = map[string]func() reader.Reader{
+		"claude": func() reader.Reader {
+			return &claude.Reader{
+				IncludeReadResults: a.includeReadResults,
+				MergeResumed:       a.mergeResumed,
+				Report:             a.report,
+			}
 		},
+		"codex":    func() reader.Reader { return &codex.Reader{Report: a.report} },
+		"opencode": func() reader.Reader { return &opencode.Reader{Report: a.report} },
+		"cursor":   func() reader.Reader { return &cursor.Reader{Report: a.report} },
+		"gemini":   func() reader.Reader { return &gemini.Reader{Report: a.report} },
+		"aider":    func() reader.Reader { return &aider.Reader{Report: a.report} },
+		"cg":       func() reader.Reader { return &native.Reader{Dir: a.transcriptsDir, Report: a.report} },
 	}
+	a.renderers = map[string]func() render.Renderer{
+		"terminal": func() render.Renderer { return terminal.New() },
+		"html":     func() render.Renderer { return htmlrender.New() },
+		"markdown": func() render.Renderer { return markdown.New() },
+		"json":     func() render.Renderer { return jsonrender.New(true) },
+	}
+	return a
 }
 
+// reader returns the named reader. An empty name returns an autoReader that
+// detects the agent for each call.
 func (a *app) reader(name string) (reader.Reader, error) {
+	if name == "" {
+		readers := make(map[string]reader.Reader, len(a.readers))
+		for n, fn := range a.readers {
+			readers[n] = fn()
+		}
+		return newAutoReader(readers), nil
+	}
 	fn, ok := a.readers[name]
 	if !ok {
 		return nil, fmt.Errorf("unknown agent %q", name)
@@ -41,6 +87,14 @@ func (a *app) reader(name string) (reader.Reader, error) {
 	return fn(), nil
 }
 
+// logWarnings logs the warnings readers have reported so far. They are shown
+// with --log warn.
+func (a *app) logWarnings() {
+	for _, w := range a.report.Warnings() {
+		log.Warn(w.Message, "kind", w.Kind, "path", w.Path, "line", w.Line)
+	}
+}
+
 func (a *app) renderer(name string) (render.Renderer, error) {
 	fn, ok := a.renderers[name]
 	if !ok {
@@ -49,6 +103,130 @@ func (a *app) renderer(name string) (render.Renderer, error) {
 	return fn(), nil
 }
 
+// autoReader is used when --agent is omitted. Files and directories are
+// matched to a reader with reader.Detect; session lookups and project or full
+// scans try every agent's reader in name order. Normalized transcripts are only
+// read when detected from a path, so archived copies do not duplicate the
+// sessions they were rendered from.
+type autoReader struct {
+	readers map[string]reader.Reader
+	names   []string
+}
+
+func newAutoReader(readers map[string]reader.Reader) *autoReader {
+	names := make([]string, 0, len(readers))
+	for name := range readers {
+		if _, ok := readers[name].(*native.Reader); ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &autoReader{readers: readers, names: names}
+}
+
+func (a *autoReader) ReadFile(path string) (*core.Transcript, error) {
+	name, err := reader.Detect(path, a.readers)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("detected agent", "agent", name, "path", path)
+	return a.readers[name].ReadFile(path)
+}
+
+// StreamFile implements reader.StreamReader, streaming from the detected
+// reader when it supports it.
+func (a *autoReader) StreamFile(path string) (*core.Transcript, core.MessageSeq, error) {
+	name, err := reader.Detect(path, a.readers)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debug("detected agent", "agent", name, "path", path)
+	return reader.Stream(a.readers[name], path)
+}
+
+func (a *autoReader) ReadSession(sessionID string) (*core.Transcript, error) {
+	for _, name := range a.names {
+		t, err := a.readers[name].ReadSession(sessionID)
+		if err == nil {
+			log.Debug("detected agent", "agent", name, "session", sessionID)
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("session %s not found for any agent", sessionID)
+}
+
+// TailFile implements reader.TailReader, tailing with the detected reader
+// when it supports it.
+func (a *autoReader) TailFile(path string) (reader.Tail, error) {
+	name, err := reader.Detect(path, a.readers)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("detected agent", "agent", name, "path", path)
+	tr, ok := a.readers[name].(reader.TailReader)
+	if !ok {
+		return nil, fmt.Errorf("agent %q does not support tail", name)
+	}
+	return tr.TailFile(path)
+}
+
+// TailSession implements reader.TailReader, trying every reader that
+// supports tail.
+func (a *autoReader) TailSession(sessionID string) (reader.Tail, error) {
+	for _, name := range a.names {
+		tr, ok := a.readers[name].(reader.TailReader)
+		if !ok {
+			continue
+		}
+		tail, err := tr.TailSession(sessionID)
+		if err == nil {
+			log.Debug("detected agent", "agent", name, "session", sessionID)
+			return tail, nil
+		}
+	}
+	return nil, fmt.Errorf("session %s not found for any agent", sessionID)
+}
+
+func (a *autoReader) ReadProject(project string) ([]*core.Transcript, error) {
+	return a.collect(func(r reader.Reader) ([]*core.Transcript, error) {
+		return r.ReadProject(project)
+	})
+}
+
+func (a *autoReader) ReadAll() ([]*core.Transcript, error) {
+	return a.collect(reader.Reader.ReadAll)
+}
+
+// collect merges the results of fn across all readers. Readers that fail are
+// skipped, with a warning unless their data is simply missing; an error is
+// returned only if every reader fails.
+func (a *autoReader) collect(fn func(reader.Reader) ([]*core.Transcript, error)) ([]*core.Transcript, error) {
+	var all []*core.Transcript
+	var firstErr error
+	failed := 0
+	for _, name := range a.names {
+		transcripts, err := fn(a.readers[name])
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				log.Debug("skip agent", "agent", name, "error", err)
+			} else {
+				log.Warn("skip agent", "agent", name, "error", err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		all = append(all, transcripts...)
+	}
+	if failed == len(a.names) && firstErr != nil {
+		return nil, firstErr
+	}
+	return all, nil
+}
+
 // readTranscripts dispatches to the appropriate Reader method based on CLI flags.
 // Exactly one of --file, --session, --project, or --all must be set.
 func readTranscripts(r reader.Reader, cmd *cli.Command) ([]*core.Transcript, error) {
@@ -131,3 +309,23 @@ func newRedactor(cmd *cli.Command) (*redact.Redactor, error) {
 
 	return redact.New(cfg), nil
 }
+
+// newEstimator builds a cost Estimator from the --pricing file, or from the
+// default pricing file when it exists, on top of the built-in prices.
+func newEstimator(cmd *cli.Command) (*pricing.Estimator, error) {
+	path, explicit := cmd.String("pricing"), true
+	if path == "" {
+		path, explicit = pricing.DefaultPath(), false
+	}
+	table := pricing.Default()
+	if path != "" {
+		t, err := pricing.LoadFile(path)
+		switch {
+		case err == nil:
+			table = t
+		case explicit || !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+	return pricing.New(table), nil
+}
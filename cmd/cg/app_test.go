@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/sonnes/chitragupt/reader/claude"
+	"github.com/sonnes/chitragupt/reader/codex"
+	"github.com/sonnes/chitragupt/reader/native"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const codexFixture = "../../reader/codex/testdata/simple.jsonl"
+
+// setupCodexDir creates a fake codex sessions dir with one rollout per session.
+func setupCodexDir(t *testing.T, sessionIDs ...string) *codex.Reader {
+	t.Helper()
+	dayDir := filepath.Join(t.TempDir(), "2026", "01", "01")
+	require.NoError(t, os.MkdirAll(dayDir, 0o755))
+
+	data, err := os.ReadFile(codexFixture)
+	require.NoError(t, err)
+	for _, id := range sessionIDs {
+		path := filepath.Join(dayDir, "rollout-2026-01-01T09-00-00-"+id+".jsonl")
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	return &codex.Reader{Dir: filepath.Dir(filepath.Dir(filepath.Dir(dayDir)))}
+}
+
+func TestAutoReaderReadFile(t *testing.T) {
+	r := newAutoReader(map[string]reader.Reader{
+		"claude": setupClaudeDir(t, nil),
+		"codex":  setupCodexDir(t),
+	})
+
+	tr, err := r.ReadFile(testdataFixture)
+	require.NoError(t, err)
+	assert.Equal(t, "claude", tr.Agent)
+
+	tr, err = r.ReadFile(codexFixture)
+	require.NoError(t, err)
+	assert.Equal(t, "codex", tr.Agent)
+
+	_, err = r.ReadFile("app.go")
+	assert.Error(t, err)
+}
+
+func TestAutoReaderReadSession(t *testing.T) {
+	r := newAutoReader(map[string]reader.Reader{
+		"claude": setupClaudeDir(t, map[string]string{"sess-1": testdataFixture}),
+		"codex":  setupCodexDir(t, "sess-2"),
+	})
+
+	tr, err := r.ReadSession("sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, "claude", tr.Agent)
+
+	tr, err = r.ReadSession("sess-2")
+	require.NoError(t, err)
+	assert.Equal(t, "codex", tr.Agent)
+
+	_, err = r.ReadSession("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestAutoReaderReadAll(t *testing.T) {
+	t.Run("merges readers and skips missing data", func(t *testing.T) {
+		r := newAutoReader(map[string]reader.Reader{
+			"claude": setupClaudeDir(t, map[string]string{"sess-1": testdataFixture}),
+			"codex":  setupCodexDir(t, "sess-2", "sess-3"),
+			"other":  &codex.Reader{Dir: filepath.Join(t.TempDir(), "missing")},
+		})
+
+		transcripts, err := r.ReadAll()
+		require.NoError(t, err)
+		assert.Len(t, transcripts, 3)
+	})
+
+	t.Run("warns about readers that fail for other reasons", func(t *testing.T) {
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+		t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+		r := newAutoReader(map[string]reader.Reader{
+			"broken":  &claude.Reader{Dir: codexFixture},
+			"codex":   setupCodexDir(t, "sess-2"),
+			"missing": &codex.Reader{Dir: filepath.Join(t.TempDir(), "missing")},
+		})
+
+		transcripts, err := r.ReadAll()
+		require.NoError(t, err)
+		assert.Len(t, transcripts, 1)
+		assert.Contains(t, logs.String(), "WARN skip agent agent=broken")
+		assert.NotContains(t, logs.String(), "agent=missing")
+	})
+
+	t.Run("fails when every reader fails", func(t *testing.T) {
+		r := newAutoReader(map[string]reader.Reader{
+			"codex": &codex.Reader{Dir: filepath.Join(t.TempDir(), "missing")},
+		})
+
+		_, err := r.ReadAll()
+		assert.Error(t, err)
+	})
+}
+
+func TestAutoReaderNative(t *testing.T) {
+	const transcripts = "../../reader/native/testdata/transcripts"
+	r := newAutoReader(map[string]reader.Reader{
+		"cg":     &native.Reader{Dir: transcripts},
+		"claude": setupClaudeDir(t, map[string]string{"sess-1": testdataFixture}),
+	})
+
+	// Normalized files are detected from their path...
+	tr, err := r.ReadFile(filepath.Join(transcripts, "sess-sub", "index.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "sess-main-1", tr.SessionID)
+
+	// ...but archived copies are not scanned alongside the agents' sessions.
+	all, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+	_, err = r.ReadSession("sess-main-1")
+	assert.Error(t, err)
+}
+
+func TestAppTranscriptsDir(t *testing.T) {
+	a := newApp()
+	a.transcriptsDir = "../../reader/native/testdata/archive.jsonl"
+
+	r, err := a.reader("cg")
+	require.NoError(t, err)
+	all, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/urfave/cli/v3"
+)
+
+func doctorCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "doctor",
+		Usage: "Check session logs for data the readers skip",
+		Description: `Reads every session (or those of one project) and reports the files that
+could not be read, lines that are not valid JSON and content blocks of
+unknown types. Readers skip these silently when rendering.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "agent",
+				Aliases: []string{"a"},
+				Usage:   "Agent name (claude, codex, opencode, cursor, gemini, aider, cg); all agents when omitted",
+			},
+			&cli.StringFlag{
+				Name:    "project",
+				Aliases: []string{"p"},
+				Usage:   "Project name (checks the sessions in the project only)",
+			},
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "Transcripts directory or archive file read by --agent cg (default: .transcripts)",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			a := newApp()
+			a.transcriptsDir = cmd.String("dir")
+
+			r, err := a.reader(cmd.String("agent"))
+			if err != nil {
+				return err
+			}
+
+			n, err := checkSessions(r, cmd.String("project"))
+			if err != nil {
+				return err
+			}
+
+			writeDoctor(os.Stdout, n, a.report.Warnings())
+			return nil
+		},
+	}
+}
+
+// checkSessions reads the sessions of project, or all sessions when project
+// is empty, and returns how many were read. Warnings go to the reader's report.
+func checkSessions(r reader.Reader, project string) (int, error) {
+	if project == "" {
+		ts, err := r.ReadAll()
+		return len(ts), err
+	}
+	project, err := filepath.Abs(project)
+	if err != nil {
+		return 0, err
+	}
+	ts, err := r.ReadProject(strings.ReplaceAll(project, "/", "-"))
+	return len(ts), err
+}
+
+// writeDoctor prints a summary of warnings by kind followed by each warning.
+func writeDoctor(w io.Writer, sessions int, warnings []reader.Warning) {
+	fmt.Fprintf(w, "Read %d sessions, %d warnings\n", sessions, len(warnings))
+	if len(warnings) == 0 {
+		return
+	}
+
+	counts := make(map[reader.WarningKind]int)
+	for _, warn := range warnings {
+		counts[warn.Kind]++
+	}
+	fmt.Fprintln(w)
+	for _, kind := range []reader.WarningKind{reader.WarnSkippedFile, reader.WarnMalformedLine, reader.WarnUnknownBlock} {
+		if counts[kind] > 0 {
+			fmt.Fprintf(w, "  %-16s %d\n", kind, counts[kind])
+		}
+	}
+	fmt.Fprintln(w)
+	for _, warn := range warnings {
+		fmt.Fprintln(w, warn)
+	}
+}
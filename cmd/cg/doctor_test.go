@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDoctor(t *testing.T) {
+	var buf bytes.Buffer
+	writeDoctor(&buf, 2, []reader.Warning{
+		{Kind: reader.WarnMalformedLine, Path: "/s/a.jsonl", Line: 2, Message: "unexpected end of JSON input"},
+		{Kind: reader.WarnSkippedFile, Path: "/s/b.jsonl", Message: "no messages found"},
+		{Kind: reader.WarnMalformedLine, Path: "/s/a.jsonl", Line: 9, Message: "invalid character"},
+	})
+	assert.Equal(t, `Read 2 sessions, 3 warnings
+
+  skipped_file     1
+  malformed_line   2
+
+/s/a.jsonl:2: malformed_line: unexpected end of JSON input
+/s/b.jsonl: skipped_file: no messages found
+/s/a.jsonl:9: malformed_line: invalid character
+`, buf.String())
+
+	buf.Reset()
+	writeDoctor(&buf, 5, nil)
+	assert.Equal(t, "Read 5 sessions, 0 warnings\n", buf.String())
+}
@@ -20,7 +20,7 @@ hook auto-commits transcripts when you commit code.`,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "agent",
-				Usage:   "Agent name (claude)",
+				Usage:   "Agent name (claude, opencode)",
 				Value:   "claude",
 				Aliases: []string{"a"},
 			},
@@ -48,8 +48,8 @@ hook auto-commits transcripts when you commit code.`,
 				Branch:  cmd.String("branch"),
 			}
 
-			if cfg.Agent != "claude" {
-				return fmt.Errorf("unsupported agent %q; currently only 'claude' is supported", cfg.Agent)
+			if cfg.Agent != "claude" && cfg.Agent != "opencode" {
+				return fmt.Errorf("unsupported agent %q; currently only 'claude' and 'opencode' are supported", cfg.Agent)
 			}
 
 			if err := install.Run(cfg); err != nil {
@@ -26,6 +26,10 @@ func main() {
 				Usage: "Log level: debug, info, warn, error",
 				Value: "error",
 			},
+			&cli.StringFlag{
+				Name:  "pricing",
+				Usage: "JSON file of model prices that add to or replace the built-in ones (default: cg/pricing.json in the user config directory)",
+			},
 		},
 		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 			level, err := log.ParseLevel(cmd.String("log"))
@@ -42,6 +46,10 @@ func main() {
 			uninstallCmd(),
 			indexCmd(),
 			manifestCmd(),
+			tailCmd(),
+			doctorCmd(),
+			validateCmd(),
+			patchCmd(),
 		},
 	}
 
@@ -27,20 +27,24 @@ func manifestUpsertCmd() *cli.Command {
 	return &cli.Command{
 		Name:  "upsert",
 		Usage: "Add or update a session entry in the manifest",
-		Description: `Parses a raw session file, extracts metadata, and upserts the entry
-into the manifest file. Called by the SessionEnd hook after rendering.`,
+		Description: `Parses a raw session file (or a session looked up by ID), extracts
+metadata, and upserts the entry into the manifest file. Called by the
+SessionEnd hook after rendering.`,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "agent",
-				Aliases:  []string{"a"},
-				Usage:    "Agent name (claude, codex, opencode, cursor)",
-				Required: true,
+				Name:    "agent",
+				Aliases: []string{"a"},
+				Usage:   "Agent name (claude, codex, opencode, cursor, gemini, aider, cg); detected when omitted",
 			},
 			&cli.StringFlag{
-				Name:     "file",
-				Aliases:  []string{"f"},
-				Usage:    "Path to the raw session file",
-				Required: true,
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "Path to the raw session file",
+			},
+			&cli.StringFlag{
+				Name:    "session",
+				Aliases: []string{"s"},
+				Usage:   "Session ID (alternative to --file)",
 			},
 			&cli.StringFlag{
 				Name:     "manifest",
@@ -48,6 +52,10 @@ into the manifest file. Called by the SessionEnd hook after rendering.`,
 				Usage:    "Path to manifest.json",
 				Required: true,
 			},
+			&cli.BoolFlag{
+				Name:  "merge-resumed",
+				Usage: "Merge resumed sessions into the session they continue",
+			},
 			&cli.StringFlag{
 				Name:     "href",
 				Usage:    "Relative link to the rendered transcript page",
@@ -56,18 +64,37 @@ into the manifest file. Called by the SessionEnd hook after rendering.`,
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			a := newApp()
+			a.mergeResumed = cmd.Bool("merge-resumed")
 
 			r, err := a.reader(cmd.String("agent"))
 			if err != nil {
 				return err
 			}
+			defer a.logWarnings()
 
-			t, err := r.ReadFile(cmd.String("file"))
+			file, session := cmd.String("file"), cmd.String("session")
+			if (file == "") == (session == "") {
+				return fmt.Errorf("exactly one of --file or --session is required")
+			}
+
+			var t *core.Transcript
+			if file != "" {
+				t, err = r.ReadFile(file)
+			} else {
+				t, err = r.ReadSession(session)
+			}
 			if err != nil {
 				return fmt.Errorf("read session: %w", err)
 			}
 
 			computeDiffStatsTree(t)
+			estimator, err := newEstimator(cmd)
+			if err != nil {
+				return err
+			}
+			if err := core.Chain(t, estimator); err != nil {
+				return fmt.Errorf("estimate costs: %w", err)
+			}
 
 			entry := core.NewManifestEntry(t, cmd.String("href"))
 
@@ -103,20 +130,30 @@ raw source files, and rebuilds the manifest from scratch.`,
 			&cli.StringFlag{
 				Name:    "agent",
 				Aliases: []string{"a"},
-				Usage:   "Agent name, selects the reader for raw sources",
-				Value:   "claude",
+				Usage:   "Agent name, selects the reader for raw sources; detected per session when omitted",
+			},
+			&cli.BoolFlag{
+				Name:  "merge-resumed",
+				Usage: "Merge resumed sessions into the session they continue",
 			},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			a := newApp()
+			a.mergeResumed = cmd.Bool("merge-resumed")
 
 			r, err := a.reader(cmd.String("agent"))
 			if err != nil {
 				return err
 			}
+			defer a.logWarnings()
+
+			estimator, err := newEstimator(cmd)
+			if err != nil {
+				return err
+			}
 
 			dir := cmd.String("dir")
-			m, skipped, err := repairManifest(dir, r)
+			m, skipped, err := repairManifest(dir, r, estimator)
 			if err != nil {
 				return err
 			}
@@ -133,9 +170,9 @@ raw source files, and rebuilds the manifest from scratch.`,
 }
 
 // repairManifest scans dir for session directories, re-parses raw sources via
-// the reader, and builds a new manifest. Returns the manifest, count of skipped
-// sessions, and any fatal error.
-func repairManifest(dir string, r reader.Reader) (*manifest.Manifest, int, error) {
+// the reader, applies transformers, and builds a new manifest. Returns the
+// manifest, count of skipped sessions, and any fatal error.
+func repairManifest(dir string, r reader.Reader, transformers ...core.Transformer) (*manifest.Manifest, int, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, 0, fmt.Errorf("read transcripts directory: %w", err)
@@ -170,6 +207,9 @@ func repairManifest(dir string, r reader.Reader) (*manifest.Manifest, int, error
 		}
 
 		computeDiffStatsTree(t)
+		if err := core.Chain(t, transformers...); err != nil {
+			return nil, 0, err
+		}
 		me := core.NewManifestEntry(t, href)
 		m.Upsert(me)
 	}
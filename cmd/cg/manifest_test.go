@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"github.com/sonnes/chitragupt/manifest"
+	"github.com/sonnes/chitragupt/pricing"
+	"github.com/sonnes/chitragupt/reader"
 	"github.com/sonnes/chitragupt/reader/claude"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -159,6 +161,22 @@ func TestRepairManifest(t *testing.T) {
 	}
 }
 
+func TestRepairManifestEstimatesCost(t *testing.T) {
+	transcriptsDir := setupTranscriptsDir(t, map[string][]string{
+		"sess-1": {"index.html"},
+	})
+	reader := setupClaudeDir(t, map[string]string{
+		"sess-1": testdataFixture,
+	})
+
+	m, _, err := repairManifest(transcriptsDir, reader, pricing.New(pricing.Default()))
+	require.NoError(t, err)
+	require.Len(t, m.Entries, 1)
+	require.NotNil(t, m.Entries[0].Usage)
+	assert.Positive(t, m.Entries[0].Usage.Cost)
+	assert.True(t, m.Entries[0].Usage.CostEstimated)
+}
+
 func TestRepairManifestSkipsNonSessionEntries(t *testing.T) {
 	dir := filepath.Join(t.TempDir(), ".transcripts")
 	require.NoError(t, os.MkdirAll(dir, 0o755))
@@ -258,3 +276,26 @@ func TestDetectSessionHref(t *testing.T) {
 		})
 	}
 }
+
+func TestRepairManifestMixedAgents(t *testing.T) {
+	transcriptsDir := setupTranscriptsDir(t, map[string][]string{
+		"sess-1": {"index.html"},
+		"sess-2": {"index.html"},
+	})
+	r := newAutoReader(map[string]reader.Reader{
+		"claude": setupClaudeDir(t, map[string]string{"sess-1": testdataFixture}),
+		"codex":  setupCodexDir(t, "sess-2"),
+	})
+
+	m, skipped, err := repairManifest(transcriptsDir, r)
+	require.NoError(t, err)
+	assert.Equal(t, 0, skipped)
+	require.Len(t, m.Entries, 2)
+
+	agents := map[string]bool{}
+	for _, e := range m.Entries {
+		agents[e.Agent] = true
+	}
+	assert.True(t, agents["claude"])
+	assert.True(t, agents["codex"])
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/urfave/cli/v3"
+)
+
+func patchCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "patch",
+		Usage: "Print the file changes of a session as a unified diff",
+		Description: `Replays the Write, Edit and MultiEdit calls of a session, including its
+sub-agents, and prints one unified diff per changed file, with paths relative
+to the session's working directory. The output can be compared with a commit
+or applied to another branch with git apply or patch -p1.
+
+Without --base, the content files had before the session is unknown: files
+the session wrote are shown as created, and edits as hunks of the replaced
+text without surrounding context. patch -p1 and git apply --unidiff-zero
+locate such hunks by content, so they apply only when the edits replaced
+whole lines. With --base, files are read from that
+git revision of the session's directory and diffed in full.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "agent",
+				Aliases: []string{"a"},
+				Usage:   "Agent name (claude); detected when omitted",
+			},
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "Path to a session file",
+			},
+			&cli.StringFlag{
+				Name:    "session",
+				Aliases: []string{"s"},
+				Usage:   "Session ID to reconstruct",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-failed",
+				Usage: "Leave out tool calls whose result is an error",
+			},
+			&cli.StringFlag{
+				Name:  "base",
+				Usage: "Git revision holding the files as they were before the session. Example: --base=HEAD~1",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			a := newApp()
+			r, err := a.reader(cmd.String("agent"))
+			if err != nil {
+				return err
+			}
+			defer a.logWarnings()
+
+			file, session := cmd.String("file"), cmd.String("session")
+			if (file == "") == (session == "") {
+				return fmt.Errorf("exactly one of --file or --session is required")
+			}
+
+			var t *core.Transcript
+			if file != "" {
+				t, err = r.ReadFile(file)
+			} else {
+				t, err = r.ReadSession(session)
+			}
+			if err != nil {
+				return err
+			}
+
+			opts := core.PatchOptions{SkipFailed: cmd.Bool("skip-failed")}
+			if rev := cmd.String("base"); rev != "" {
+				if t.Dir == "" {
+					return fmt.Errorf("--base needs the session's working directory, which is not recorded")
+				}
+				opts.Base = gitBase(t.Dir, rev)
+			}
+			return writePatches(os.Stdout, core.Patches(t, opts))
+		},
+	}
+}
+
+// writePatches writes the diffs of patches one after another.
+func writePatches(w io.Writer, patches []core.FilePatch) error {
+	for _, p := range patches {
+		if _, err := io.WriteString(w, p.Diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitBase returns a core.PatchOptions.Base that reads files from revision rev
+// of the git repository holding dir. Files outside dir, or missing at rev,
+// are reported as not existing.
+func gitBase(dir, rev string) func(string) (string, bool) {
+	return func(path string) (string, bool) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return "", false
+		}
+		out, err := exec.Command("git", "-C", dir, "show", rev+":./"+filepath.ToSlash(rel)).Output()
+		if err != nil {
+			return "", false
+		}
+		return string(out), true
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gitRepo creates a repository in a temporary directory with files committed
+// and returns its path.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	git("add", "-A")
+	git("commit", "-q", "-m", "base")
+	return dir
+}
+
+func TestGitBase(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"pkg/a.go": "package pkg\n"})
+	base := gitBase(filepath.Join(dir, "pkg"), "HEAD")
+
+	content, ok := base(filepath.Join(dir, "pkg", "a.go"))
+	assert.True(t, ok)
+	assert.Equal(t, "package pkg\n", content)
+
+	content, ok = base("a.go")
+	assert.True(t, ok)
+	assert.Equal(t, "package pkg\n", content)
+
+	_, ok = base(filepath.Join(dir, "pkg", "missing.go"))
+	assert.False(t, ok)
+	_, ok = base("/etc/hosts")
+	assert.False(t, ok)
+}
+
+func TestWritePatchesApplies(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(1)\n}\n"})
+	tr := &core.Transcript{Dir: dir, Messages: []core.Message{{Role: core.RoleAssistant, Content: []core.ContentBlock{
+		{Type: core.BlockToolUse, ToolUseID: "t1", Name: "Edit", Input: map[string]any{
+			"file_path": filepath.Join(dir, "main.go"), "old_string": "\tprintln(1)\n", "new_string": "\tprintln(2)\n",
+		}},
+		{Type: core.BlockToolUse, ToolUseID: "t2", Name: "Write", Input: map[string]any{
+			"file_path": filepath.Join(dir, "doc.go"), "content": "// Package main.\npackage main\n",
+		}},
+	}}}}
+
+	tests := []struct {
+		name  string
+		opts  core.PatchOptions
+		flags []string
+	}{
+		{"with base", core.PatchOptions{Base: gitBase(dir, "HEAD")}, nil},
+		// Hunks of edits to unknown content have no context lines.
+		{"without base", core.PatchOptions{}, []string{"--unidiff-zero"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, writePatches(&buf, core.Patches(tr, tt.opts)))
+
+			args := append([]string{"-C", dir, "apply", "--check"}, tt.flags...)
+			cmd := exec.Command("git", append(args, "-")...)
+			cmd.Stdin = &buf
+			out, err := cmd.CombinedOutput()
+			assert.NoError(t, err, string(out))
+		})
+	}
+}
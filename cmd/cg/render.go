@@ -8,6 +8,9 @@ import (
 
 	"github.com/sonnes/chitragupt/compact"
 	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/pricing"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/sonnes/chitragupt/redact"
 	"github.com/sonnes/chitragupt/render"
 	"github.com/urfave/cli/v3"
 )
@@ -18,10 +21,9 @@ func renderCmd() *cli.Command {
 		Usage: "Convert a session file to a transcript",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "agent",
-				Aliases:  []string{"a"},
-				Usage:    "Agent name (claude, codex, opencode, cursor)",
-				Required: true,
+				Name:    "agent",
+				Aliases: []string{"a"},
+				Usage:   "Agent name (claude, codex, opencode, cursor, gemini, aider, cg); detected when omitted",
 			},
 			&cli.StringFlag{
 				Name:    "file",
@@ -61,6 +63,18 @@ func renderCmd() *cli.Command {
 				Aliases: []string{"c"},
 				Usage:   "Enable compact mode. Use --compact=no-thinking to also strip thinking blocks",
 			},
+			&cli.BoolFlag{
+				Name:  "include-read-results",
+				Usage: "Keep the output of Read tool calls, which is dropped by default",
+			},
+			&cli.BoolFlag{
+				Name:  "merge-resumed",
+				Usage: "Merge resumed sessions into the session they continue",
+			},
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "Transcripts directory or archive file read by --agent cg (default: .transcripts)",
+			},
 			&cli.StringFlag{
 				Name:    "out",
 				Aliases: []string{"o"},
@@ -69,21 +83,64 @@ func renderCmd() *cli.Command {
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			a := newApp()
+			a.includeReadResults = cmd.Bool("include-read-results")
+			a.mergeResumed = cmd.Bool("merge-resumed")
+			a.transcriptsDir = cmd.String("dir")
 
 			r, err := a.reader(cmd.String("agent"))
 			if err != nil {
 				return err
 			}
+			defer a.logWarnings()
 
-			transcripts, err := readTranscripts(r, cmd)
+			redactor, err := newRedactor(cmd)
 			if err != nil {
 				return err
 			}
 
-			redactor, err := newRedactor(cmd)
+			estimator, err := newEstimator(cmd)
 			if err != nil {
 				return err
 			}
+
+			var compactor *compact.Compactor
+			if v := cmd.String("compact"); v != "" {
+				cfg := compact.Config{}
+				if v == "no-thinking" {
+					cfg.StripThinking = true
+				}
+				compactor = compact.New(cfg)
+			}
+
+			formats := cmd.StringSlice("format")
+			if len(formats) == 0 {
+				formats = []string{"terminal"}
+			}
+
+			outDir := cmd.String("out")
+
+			if len(formats) > 1 && outDir == "" {
+				return fmt.Errorf("--out is required when specifying multiple formats")
+			}
+
+			// A single file rendered only to streaming formats never holds
+			// the whole session in memory.
+			if file := cmd.String("file"); file != "" && cmd.String("session") == "" &&
+				cmd.String("project") == "" && !cmd.Bool("all") {
+				rnds, ok, err := streamRenderers(a, formats)
+				if err != nil {
+					return err
+				}
+				if ok {
+					return renderStream(r, file, formats, rnds, outDir, redactor, estimator, compactor)
+				}
+			}
+
+			transcripts, err := readTranscripts(r, cmd)
+			if err != nil {
+				return err
+			}
+
 			if redactor != nil {
 				for _, t := range transcripts {
 					if err := core.Chain(t, redactor); err != nil {
@@ -96,14 +153,12 @@ func renderCmd() *cli.Command {
 			// Apply to sub-agents too.
 			for _, t := range transcripts {
 				computeDiffStatsTree(t)
+				if err := core.Chain(t, estimator); err != nil {
+					return fmt.Errorf("estimate costs: %w", err)
+				}
 			}
 
-			if v := cmd.String("compact"); v != "" {
-				cfg := compact.Config{}
-				if v == "no-thinking" {
-					cfg.StripThinking = true
-				}
-				compactor := compact.New(cfg)
+			if compactor != nil {
 				for _, t := range transcripts {
 					if err := core.Chain(t, compactor); err != nil {
 						return fmt.Errorf("compact: %w", err)
@@ -111,17 +166,6 @@ func renderCmd() *cli.Command {
 				}
 			}
 
-			formats := cmd.StringSlice("format")
-			if len(formats) == 0 {
-				formats = []string{"terminal"}
-			}
-
-			outDir := cmd.String("out")
-
-			if len(formats) > 1 && outDir == "" {
-				return fmt.Errorf("--out is required when specifying multiple formats")
-			}
-
 			if outDir == "" {
 				rnd, err := a.renderer(formats[0])
 				if err != nil {
@@ -157,9 +201,114 @@ func renderCmd() *cli.Command {
 	}
 }
 
-// computeDiffStatsTree computes DiffStats for a transcript and all its sub-agents.
+// streamRenderer is a renderer that also supports streaming; sub-agents are
+// rendered with Render.
+type streamRenderer interface {
+	render.Renderer
+	render.StreamRenderer
+}
+
+// streamRenderers returns the renderers for formats, and whether every one of
+// them supports streaming.
+func streamRenderers(a *app, formats []string) ([]streamRenderer, bool, error) {
+	rnds := make([]streamRenderer, 0, len(formats))
+	for _, format := range formats {
+		rnd, err := a.renderer(format)
+		if err != nil {
+			return nil, false, err
+		}
+		sr, ok := rnd.(streamRenderer)
+		if !ok {
+			return nil, false, nil
+		}
+		rnds = append(rnds, sr)
+	}
+	return rnds, true, nil
+}
+
+// renderStream renders the session file at path without materializing its
+// messages. The file is read once for the header, once for diff stats and
+// costs, and once per format; sub-agents are small and rendered the usual way.
+func renderStream(r reader.Reader, path string, formats []string, rnds []streamRenderer,
+	outDir string, redactor *redact.Redactor, estimator *pricing.Estimator, compactor *compact.Compactor) error {
+	t, messages, err := reader.Stream(r, path)
+	if err != nil {
+		return err
+	}
+
+	var transformers []core.Transformer
+	if redactor != nil {
+		transformers = append(transformers, redactor)
+	}
+	if err := core.Chain(t, transformers...); err != nil {
+		return fmt.Errorf("redact: %w", err)
+	}
+
+	// Compute diff stats BEFORE compact, which mutates tool input strings.
+	// The same pass sets the header's cost, which is rendered first.
+	t.DiffStats, t.Files, err = core.ComputeChangesSeq(estimator.PriceStream(t, core.TransformStream(messages, transformers...)))
+	if err != nil {
+		return err
+	}
+	for _, sub := range t.SubAgents {
+		computeDiffStatsTree(sub)
+	}
+	if err := core.Chain(t, estimator); err != nil {
+		return fmt.Errorf("estimate costs: %w", err)
+	}
+
+	if compactor != nil {
+		if err := core.Chain(t, compactor); err != nil {
+			return fmt.Errorf("compact: %w", err)
+		}
+		transformers = append(transformers, compactor)
+	}
+	messages = estimator.PriceStream(t, core.TransformStream(messages, transformers...))
+
+	if outDir == "" {
+		if err := rnds[0].RenderStream(os.Stdout, t, messages); err != nil {
+			return fmt.Errorf("render: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	for i, format := range formats {
+		ext := formatExtension(format)
+		mainPath := filepath.Join(outDir, "index"+ext)
+		if err := renderStreamFile(rnds[i], t, messages, mainPath); err != nil {
+			return err
+		}
+		for _, sub := range t.SubAgents {
+			safeName := filepath.Base(sub.SessionID)
+			subPath := filepath.Join(outDir, "agent-"+safeName+ext)
+			if err := renderFile(rnds[i], sub, subPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func renderStreamFile(rnd render.StreamRenderer, t *core.Transcript, messages core.MessageSeq, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := rnd.RenderStream(f, t, messages); err != nil {
+		return fmt.Errorf("render main transcript: %w", err)
+	}
+	return nil
+}
+
+// computeDiffStatsTree computes DiffStats and the file ledger for a transcript
+// and all its sub-agents.
 func computeDiffStatsTree(t *core.Transcript) {
-	t.DiffStats = core.ComputeDiffStats(t)
+	t.DiffStats, t.Files = core.ComputeChanges(t)
 	for _, sub := range t.SubAgents {
 		computeDiffStatsTree(sub)
 	}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonnes/chitragupt/compact"
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/pricing"
+	"github.com/sonnes/chitragupt/reader/claude"
+	"github.com/sonnes/chitragupt/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderStreamMatchesRender(t *testing.T) {
+	path := filepath.Join("..", "..", "reader", "claude", "testdata", "tool_loop.jsonl")
+	redactor := redact.New(redact.Config{Secrets: true, PII: true})
+	compactor := compact.New(compact.Config{StripThinking: true})
+	estimator := pricing.New(pricing.Default())
+
+	a := newApp()
+	formats := []string{"json", "markdown", "terminal"}
+	rnds, ok, err := streamRenderers(a, formats)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	outDir := t.TempDir()
+	require.NoError(t, renderStream(&claude.Reader{}, path, formats, rnds, outDir, redactor, estimator, compactor))
+
+	want, err := (&claude.Reader{}).ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, core.Chain(want, redactor))
+	computeDiffStatsTree(want)
+	require.NoError(t, core.Chain(want, estimator, compactor))
+
+	for i, format := range formats {
+		var buf bytes.Buffer
+		require.NoError(t, rnds[i].Render(&buf, want))
+
+		got, err := os.ReadFile(filepath.Join(outDir, "index"+formatExtension(format)))
+		require.NoError(t, err)
+		assert.Equal(t, buf.String(), string(got), format)
+	}
+}
+
+func TestStreamRenderersFallsBack(t *testing.T) {
+	_, ok, err := streamRenderers(newApp(), []string{"json", "html"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = streamRenderers(newApp(), []string{"pdf"})
+	assert.EqualError(t, err, `unknown output format "pdf"`)
+}
@@ -20,10 +20,9 @@ func serveCmd() *cli.Command {
 		Usage: "Serve sessions for browsing in a local web UI",
 		Flags: []cli.Flag{
 			&cli.StringFlag{
-				Name:     "agent",
-				Aliases:  []string{"a"},
-				Usage:    "Agent name (claude, codex, opencode, cursor)",
-				Required: true,
+				Name:    "agent",
+				Aliases: []string{"a"},
+				Usage:   "Agent name (claude, codex, opencode, cursor, gemini, aider, cg); detected when omitted",
 			},
 			&cli.StringFlag{
 				Name:    "project",
@@ -34,6 +33,18 @@ func serveCmd() *cli.Command {
 				Name:  "all",
 				Usage: "Serve all sessions",
 			},
+			&cli.BoolFlag{
+				Name:  "include-read-results",
+				Usage: "Keep the output of Read tool calls, which is dropped by default",
+			},
+			&cli.BoolFlag{
+				Name:  "merge-resumed",
+				Usage: "Merge resumed sessions into the session they continue",
+			},
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "Transcripts directory or archive file read by --agent cg (default: .transcripts)",
+			},
 			&cli.IntFlag{
 				Name:  "port",
 				Usage: "Port to listen on",
@@ -58,6 +69,9 @@ func serveCmd() *cli.Command {
 			}
 
 			a := newApp()
+			a.includeReadResults = cmd.Bool("include-read-results")
+			a.mergeResumed = cmd.Bool("merge-resumed")
+			a.transcriptsDir = cmd.String("dir")
 
 			r, err := a.reader(cmd.String("agent"))
 			if err != nil {
@@ -73,6 +87,7 @@ func serveCmd() *cli.Command {
 			if err != nil {
 				return err
 			}
+			a.logWarnings()
 
 			redactor, err := newRedactor(cmd)
 			if err != nil {
@@ -86,8 +101,15 @@ func serveCmd() *cli.Command {
 				}
 			}
 
+			estimator, err := newEstimator(cmd)
+			if err != nil {
+				return err
+			}
 			for _, t := range transcripts {
 				computeDiffStatsTree(t)
+				if err := core.Chain(t, estimator); err != nil {
+					return fmt.Errorf("estimate costs: %w", err)
+				}
 			}
 
 			sort.Slice(transcripts, func(i, j int) bool {
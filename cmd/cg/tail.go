@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/sonnes/chitragupt/render/terminal"
+	"github.com/urfave/cli/v3"
+)
+
+func tailCmd() *cli.Command {
+	return &cli.Command{
+		Name:  "tail",
+		Usage: "Print a session as turn cards, optionally following it as it is written",
+		Description: `Prints a session through the terminal renderer. With --follow, cg keeps
+the session file open and prints each prompt, tool call and reply as the
+agent writes it, until interrupted.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "agent",
+				Aliases: []string{"a"},
+				Usage:   "Agent name (claude); detected when omitted",
+			},
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "Path to a session file",
+			},
+			&cli.StringFlag{
+				Name:    "session",
+				Aliases: []string{"s"},
+				Usage:   "Session ID to tail",
+			},
+			&cli.BoolFlag{
+				Name:  "follow",
+				Usage: "Keep printing new messages as they are written",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "How often to check the session file for new lines when following",
+				Value: time.Second,
+			},
+			&cli.BoolFlag{
+				Name:  "no-redact",
+				Usage: "Disable redaction of secrets and PII",
+			},
+			&cli.StringSliceFlag{
+				Name:    "redact",
+				Aliases: []string{"r"},
+				Usage:   "Allowlist of rules to redact. Example: --redact=secrets,pii",
+			},
+			&cli.BoolFlag{
+				Name:  "include-read-results",
+				Usage: "Keep the output of Read tool calls, which is dropped by default",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			a := newApp()
+			a.includeReadResults = cmd.Bool("include-read-results")
+
+			r, err := a.reader(cmd.String("agent"))
+			if err != nil {
+				return err
+			}
+			defer a.logWarnings()
+
+			tr, ok := r.(reader.TailReader)
+			if !ok {
+				return fmt.Errorf("agent %q does not support tail", cmd.String("agent"))
+			}
+
+			file, session := cmd.String("file"), cmd.String("session")
+			if (file == "") == (session == "") {
+				return fmt.Errorf("exactly one of --file or --session is required")
+			}
+
+			var tail reader.Tail
+			if file != "" {
+				tail, err = tr.TailFile(file)
+			} else {
+				tail, err = tr.TailSession(session)
+			}
+			if err != nil {
+				return err
+			}
+
+			redactor, err := newRedactor(cmd)
+			if err != nil {
+				return err
+			}
+			estimator, err := newEstimator(cmd)
+			if err != nil {
+				return err
+			}
+			var transformers []core.Transformer
+			if redactor != nil {
+				transformers = append(transformers, redactor)
+			}
+
+			// Read what is already written so the header is known.
+			first, err := tail.Next()
+			if err != nil {
+				return err
+			}
+			header := tail.Header()
+			if err := core.Chain(header, transformers...); err != nil {
+				return fmt.Errorf("redact: %w", err)
+			}
+			if err := core.Chain(header, estimator); err != nil {
+				return fmt.Errorf("estimate costs: %w", err)
+			}
+
+			if cmd.Bool("follow") {
+				var stop context.CancelFunc
+				ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+				defer stop()
+			}
+
+			messages := tailMessages(ctx, tail, first, cmd.Bool("follow"), cmd.Duration("interval"))
+			messages = estimator.PriceStream(header, core.TransformStream(messages, transformers...))
+			if cmd.Bool("follow") {
+				return terminal.New().RenderLive(os.Stdout, header, messages)
+			}
+			return terminal.New().RenderStream(os.Stdout, header, messages)
+		},
+	}
+}
+
+// tailMessages yields first, then the messages tail reads after it. When
+// follow is set it checks for new lines every interval until ctx is done;
+// otherwise it stops at the end of the file. Held-back messages are flushed
+// at the end.
+func tailMessages(ctx context.Context, tail reader.Tail, first []core.Message, follow bool, interval time.Duration) core.MessageSeq {
+	return func(yield func(core.Message, error) bool) {
+		messages := first
+		var err error
+		for {
+			for _, m := range messages {
+				if !yield(m, nil) {
+					return
+				}
+			}
+			if err != nil {
+				yield(core.Message{}, err)
+				return
+			}
+			if !follow || !wait(ctx, interval) {
+				break
+			}
+			messages, err = tail.Next()
+		}
+		for _, m := range tail.Flush() {
+			if !yield(m, nil) {
+				return
+			}
+		}
+	}
+}
+
+// wait sleeps for d and reports whether ctx is still live.
+func wait(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTail returns one batch of messages per call to Next.
+type fakeTail struct {
+	batches [][]core.Message
+	held    []core.Message
+	cancel  context.CancelFunc // called once the batches run out
+}
+
+func (f *fakeTail) Next() ([]core.Message, error) {
+	if len(f.batches) == 0 {
+		f.cancel()
+		return nil, nil
+	}
+	b := f.batches[0]
+	f.batches = f.batches[1:]
+	return b, nil
+}
+
+func (f *fakeTail) Flush() []core.Message    { return f.held }
+func (f *fakeTail) Header() *core.Transcript { return &core.Transcript{} }
+
+func textMessage(text string) core.Message {
+	return core.Message{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: text}}}
+}
+
+func collectTexts(t *testing.T, seq core.MessageSeq) []string {
+	t.Helper()
+	var texts []string
+	for m, err := range seq {
+		require.NoError(t, err)
+		texts = append(texts, m.Content[0].Text)
+	}
+	return texts
+}
+
+func TestTailMessages(t *testing.T) {
+	first := []core.Message{textMessage("one")}
+
+	t.Run("stops at end of file", func(t *testing.T) {
+		tail := &fakeTail{
+			batches: [][]core.Message{{textMessage("two")}},
+			held:    []core.Message{textMessage("held")},
+		}
+		seq := tailMessages(context.Background(), tail, first, false, time.Millisecond)
+		assert.Equal(t, []string{"one", "held"}, collectTexts(t, seq))
+	})
+
+	t.Run("follows until cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		tail := &fakeTail{
+			batches: [][]core.Message{{textMessage("two")}, nil, {textMessage("three")}},
+			held:    []core.Message{textMessage("held")},
+			cancel:  cancel,
+		}
+		seq := tailMessages(ctx, tail, first, true, time.Millisecond)
+		assert.Equal(t, []string{"one", "two", "three", "held"}, collectTexts(t, seq))
+	})
+}
@@ -12,7 +12,7 @@ func uninstallCmd() *cli.Command {
 	return &cli.Command{
 		Name:  "uninstall",
 		Usage: "Remove hooks and configuration installed by cg install",
-		Description: `Removes the Claude Code session hook, post-commit hook, and .gitignore entry.
+		Description: `Removes the Claude Code session hook, OpenCode plugin, post-commit hook, and .gitignore entry.
 Transcript data is preserved unless --purge is set.
 
 With --purge, also deletes the output directory and orphan branch.`,
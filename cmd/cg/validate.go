@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/urfave/cli/v3"
+)
+
+func validateCmd() *cli.Command {
+	return &cli.Command{
+		Name:      "validate",
+		Usage:     "Check JSON transcripts against the transcript schema",
+		ArgsUsage: "<file>...",
+		Description: `Validates each JSON transcript, such as the index.json and agent-{id}.json
+files written by "cg render --format json", against core/schema.json.
+Use - to read a transcript from stdin. Exits non-zero if any file is invalid.`,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			paths := cmd.Args().Slice()
+			if len(paths) == 0 {
+				return fmt.Errorf("at least one file is required")
+			}
+
+			invalid := 0
+			for _, path := range paths {
+				if err := validateFile(path); err != nil {
+					invalid++
+					fmt.Printf("%s: %v\n", path, err)
+					continue
+				}
+				fmt.Printf("%s: ok\n", path)
+			}
+			if invalid > 0 {
+				return fmt.Errorf("%d of %d files do not match the schema", invalid, len(paths))
+			}
+			return nil
+		},
+	}
+}
+
+// validateFile checks the JSON transcript at path, or on stdin for "-".
+func validateFile(path string) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	return core.ValidateJSON(data)
+}
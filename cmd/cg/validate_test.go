@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader/claude"
+	"github.com/sonnes/chitragupt/reader/codex"
+	"github.com/sonnes/chitragupt/reader/gemini"
+	"github.com/sonnes/chitragupt/reader/opencode"
+	jsonrender "github.com/sonnes/chitragupt/render/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJSONMatchesSchema renders every reader fixture to JSON and checks the
+// output against the published schema.
+func TestJSONMatchesSchema(t *testing.T) {
+	var transcripts []*core.Transcript
+	readFiles := func(read func(string) (*core.Transcript, error), pattern string) {
+		paths, err := filepath.Glob(pattern)
+		require.NoError(t, err)
+		require.NotEmpty(t, paths)
+		for _, path := range paths {
+			if strings.HasSuffix(path, "subagent_child.jsonl") {
+				continue // read through its parent
+			}
+			tr, err := read(path)
+			require.NoError(t, err, path)
+			transcripts = append(transcripts, tr)
+		}
+	}
+	readFiles((&claude.Reader{IncludeReadResults: true}).ReadFile, "../../reader/claude/testdata/*.jsonl")
+	readFiles((&codex.Reader{}).ReadFile, "../../reader/codex/testdata/*.jsonl")
+	readFiles((&gemini.Reader{}).ReadFile, "../../reader/gemini/testdata/*.json")
+
+	merged, err := (&claude.Reader{MergeResumed: true}).ReadFile("../../reader/claude/testdata/resume_second.jsonl")
+	require.NoError(t, err)
+	transcripts = append(transcripts, merged)
+
+	oc, err := (&opencode.Reader{Dir: "../../reader/opencode/testdata/storage"}).ReadAll()
+	require.NoError(t, err)
+	transcripts = append(transcripts, oc...)
+
+	for _, tr := range transcripts {
+		computeDiffStatsTree(tr)
+		var buf bytes.Buffer
+		require.NoError(t, jsonrender.New(true).Render(&buf, tr))
+		assert.NoError(t, core.ValidateJSON(buf.Bytes()), tr.SessionID)
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`{"session_id":"s1","agent":"claude","created_at":"2025-01-15T10:00:00Z","messages":[]}`), 0o644))
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`{"session_id":"s1","agent":"claude","messages":[]}`), 0o644))
+
+	assert.NoError(t, validateFile(valid))
+	assert.ErrorContains(t, validateFile(invalid), "missing property 'created_at'")
+	assert.Error(t, validateFile(filepath.Join(dir, "missing.json")))
+}
@@ -29,6 +29,11 @@ func (c *Compactor) Transform(t *core.Transcript) error {
 	for i := range t.Messages {
 		c.compactMessage(&t.Messages[i])
 	}
+	for _, b := range t.Branches {
+		for i := range b.Messages {
+			c.compactMessage(&b.Messages[i])
+		}
+	}
 	for _, sub := range t.SubAgents {
 		if err := c.Transform(sub); err != nil {
 			return err
@@ -78,12 +83,24 @@ func (c *Compactor) compactToolUse(b *core.ContentBlock) {
 	if !ok || m == nil {
 		return
 	}
-	switch strings.ToLower(b.Name) {
-	case "write":
+	switch core.ToolKindOf(b.Name) {
+	case core.ToolWrite:
 		summarizeMapField(m, "content")
-	case "edit":
+	case core.ToolEdit:
 		summarizeMapField(m, "old_string")
 		summarizeMapField(m, "new_string")
+	case core.ToolMultiEdit:
+		edits, _ := m["edits"].([]any)
+		for _, e := range edits {
+			if em, ok := e.(map[string]any); ok {
+				summarizeMapField(em, "old_string")
+				summarizeMapField(em, "new_string")
+			}
+		}
+	case core.ToolNotebookEdit:
+		summarizeMapField(m, "new_source")
+	case core.ToolApplyPatch:
+		summarizeMapField(m, "input")
 	}
 }
 
@@ -144,6 +144,19 @@ func TestCompactToolUseInputs(t *testing.T) {
 			},
 			keepFields: []string{"file_path"},
 		},
+		{
+			name:       "notebook new_source summarized",
+			toolName:   "NotebookEdit",
+			input:      map[string]any{"notebook_path": "/tmp/n.ipynb", "new_source": longContent},
+			wantFields: map[string]string{"new_source": "[new_source: 50 lines]"},
+			keepFields: []string{"notebook_path"},
+		},
+		{
+			name:       "apply_patch input summarized",
+			toolName:   "apply_patch",
+			input:      map[string]any{"input": longContent},
+			wantFields: map[string]string{"input": "[input: 50 lines]"},
+		},
 		{
 			name:       "bash command unchanged",
 			toolName:   "Bash",
@@ -187,6 +200,32 @@ func TestCompactToolUseInputs(t *testing.T) {
 	}
 }
 
+func TestCompactMultiEdit(t *testing.T) {
+	longContent := strings.Repeat("line\n", 50)
+	tr := &core.Transcript{
+		SessionID: "test",
+		Agent:     "claude",
+		CreatedAt: time.Now(),
+		Messages: []core.Message{{
+			Role: core.RoleAssistant,
+			Content: []core.ContentBlock{{
+				Type: core.BlockToolUse, Name: "MultiEdit",
+				Input: map[string]any{"file_path": "/tmp/f.go", "edits": []any{
+					map[string]any{"old_string": longContent, "new_string": "x"},
+				}},
+			}},
+		}},
+	}
+
+	require.NoError(t, New(Config{}).Transform(tr))
+
+	m := tr.Messages[0].Content[0].Input.(map[string]any)
+	edit := m["edits"].([]any)[0].(map[string]any)
+	assert.Equal(t, "[old_string: 50 lines]", edit["old_string"])
+	assert.Equal(t, "[new_string: 1 line]", edit["new_string"])
+	assert.Equal(t, "/tmp/f.go", m["file_path"])
+}
+
 func TestCompactKeepThinkingByDefault(t *testing.T) {
 	tr := &core.Transcript{
 		SessionID: "test",
@@ -285,3 +324,27 @@ func TestCompactErrorSession(t *testing.T) {
 		}
 	}
 }
+
+func TestCompactBranches(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "test",
+		Agent:     "claude",
+		CreatedAt: time.Now(),
+		Branches: []core.Branch{{
+			ID: "u3",
+			Messages: []core.Message{{
+				Role: core.RoleAssistant,
+				Content: []core.ContentBlock{
+					{Type: core.BlockThinking, Text: "deep thoughts"},
+					{Type: core.BlockToolResult, ToolUseID: "t1", Content: "a\nb"},
+				},
+			}},
+		}},
+	}
+
+	c := New(Config{StripThinking: true})
+	require.NoError(t, c.Transform(tr))
+	content := tr.Branches[0].Messages[0].Content
+	require.Len(t, content, 1)
+	assert.Equal(t, "[output: 2 lines]", content[0].Content)
+}
@@ -2,58 +2,210 @@ package core
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // ComputeDiffStats walks all tool_use blocks in the transcript and computes
-// aggregate line-level diff statistics. It must be called BEFORE compact
+// aggregate line-level diff statistics from Write, Edit, MultiEdit,
+// NotebookEdit and apply_patch calls. Replaced text is diffed line by line, so
+// unchanged lines are not counted. It must be called BEFORE compact
 // transformation, which mutates tool input strings.
 func ComputeDiffStats(t *Transcript) *DiffStats {
-	files := make(map[string]bool)
-	var added, removed int
+	stats, _ := ComputeChanges(t)
+	return stats
+}
+
+// ComputeDiffStatsSeq is ComputeDiffStats for a message stream.
+func ComputeDiffStatsSeq(messages MessageSeq) (*DiffStats, error) {
+	stats, _, err := ComputeChangesSeq(messages)
+	return stats, err
+}
+
+// ComputeChanges returns the transcript's DiffStats together with its file
+// ledger: one FileChange per file the session read or changed, in order of
+// first use. Like ComputeDiffStats, it must be called before compaction.
+func ComputeChanges(t *Transcript) (*DiffStats, []FileChange) {
+	var c diffCounter
+	for i := range t.Messages {
+		c.add(&t.Messages[i])
+	}
+	return c.result(), c.ledger()
+}
+
+// ComputeChangesSeq is ComputeChanges for a message stream.
+func ComputeChangesSeq(messages MessageSeq) (*DiffStats, []FileChange, error) {
+	var c diffCounter
+	for m, err := range messages {
+		if err != nil {
+			return nil, nil, err
+		}
+		c.add(&m)
+	}
+	return c.result(), c.ledger(), nil
+}
+
+// diffCounter accumulates diff statistics and the file ledger one message at
+// a time. It keeps the content of files the session wrote, so later edits and
+// rewrites of those files can be diffed against what they replace.
+type diffCounter struct {
+	files          map[string]*FileChange
+	order          []string          // paths in order of first use
+	content        map[string]string // file path → content, when known
+	added, removed int
+
+	// turn is the number of the current turn, counted the way GroupTurns
+	// splits messages, with events left out.
+	turn   int
+	inTurn bool
+}
 
-	for _, msg := range t.Messages {
-		for _, b := range msg.Content {
-			if b.Type != BlockToolUse {
-				continue
+func (c *diffCounter) add(msg *Message) {
+	if c.files == nil {
+		c.files = make(map[string]*FileChange)
+		c.content = make(map[string]string)
+	}
+	switch {
+	case isEvent(msg):
+		c.inTurn = false
+		return
+	case msg.Role == RoleUser && !isToolResultOnly(msg), !c.inTurn:
+		c.turn++
+		c.inTurn = true
+	}
+
+	for i := range msg.Content {
+		b := &msg.Content[i]
+		if b.Type != BlockToolUse {
+			continue
+		}
+
+		call := NewToolCall(b)
+		switch in := call.Input.(type) {
+		case *ReadInput:
+			c.touch(in.FilePath, call.Kind, 0, 0)
+		case *WriteInput:
+			var added, removed int
+			if old, ok := c.content[in.FilePath]; ok {
+				added, removed = lineDiff(old, in.Content)
+			} else {
+				added = countLines(in.Content)
 			}
-			m, ok := b.Input.(map[string]any)
-			if !ok || m == nil {
-				continue
+			c.touch(in.FilePath, call.Kind, added, removed)
+			c.remember(in.FilePath, in.Content)
+		case *EditInput:
+			if in.Sketch {
+				c.touch(in.FilePath, call.Kind, sketchLines(in.NewString), 0)
+				delete(c.content, in.FilePath)
+				break
 			}
-
-			switch strings.ToLower(b.Name) {
-			case "write":
-				if fp := stringVal(m, "file_path"); fp != "" {
-					files[fp] = true
-				}
-				if content := stringVal(m, "content"); content != "" {
-					added += countLines(content)
-				}
-			case "edit":
-				if fp := stringVal(m, "file_path"); fp != "" {
-					files[fp] = true
-				}
-				if old := stringVal(m, "old_string"); old != "" {
-					removed += countLines(old)
-				}
-				if ns := stringVal(m, "new_string"); ns != "" {
-					added += countLines(ns)
-				}
+			added, removed := c.edit(in.FilePath, EditSpan{OldString: in.OldString, NewString: in.NewString, ReplaceAll: in.ReplaceAll})
+			c.touch(in.FilePath, call.Kind, added, removed)
+		case *MultiEditInput:
+			var added, removed int
+			for _, e := range in.Edits {
+				a, r := c.edit(in.FilePath, e)
+				added, removed = added+a, removed+r
+			}
+			c.touch(in.FilePath, call.Kind, added, removed)
+		case *NotebookEditInput:
+			// The replaced cell's source is not recorded, so a replacement
+			// counts as added lines only.
+			var added int
+			if in.EditMode != "delete" {
+				added = countLines(in.NewSource)
+			}
+			c.touch(in.NotebookPath, call.Kind, added, 0)
+		case *ApplyPatchInput:
+			for _, f := range patchFiles(in.Patch) {
+				c.touch(f.path, call.Kind, f.added, f.removed)
+				delete(c.content, f.path)
 			}
 		}
 	}
+}
+
+// touch records a tool call of kind on path that added and removed lines.
+// Reads are recorded in the ledger but do not count as changes.
+func (c *diffCounter) touch(path string, kind ToolKind, added, removed int) {
+	c.added += added
+	c.removed += removed
+	if path == "" {
+		return
+	}
+
+	f, ok := c.files[path]
+	if !ok {
+		f = &FileChange{Path: path, FirstTurn: c.turn, ReadOnly: true}
+		c.files[path] = f
+		c.order = append(c.order, path)
+	}
+	if !slices.Contains(f.Operations, kind) {
+		f.Operations = append(f.Operations, kind)
+	}
+	f.Added += added
+	f.Removed += removed
+	f.LastTurn = c.turn
+	if kind != ToolRead {
+		f.ReadOnly = false
+	}
+}
+
+// remember records the content of path after a change.
+func (c *diffCounter) remember(path, content string) {
+	if path != "" {
+		c.content[path] = content
+	}
+}
 
-	if added == 0 && removed == 0 && len(files) == 0 {
+// edit returns the lines added and removed by one string replacement in
+// path. When the file's content is known, a replace_all edit counts every
+// occurrence it replaces.
+func (c *diffCounter) edit(path string, e EditSpan) (added, removed int) {
+	content, known := c.content[path]
+	if !known || e.OldString == "" || !strings.Contains(content, e.OldString) {
+		delete(c.content, path)
+		return lineDiff(e.OldString, e.NewString)
+	}
+
+	n := 1
+	if e.ReplaceAll {
+		n = strings.Count(content, e.OldString)
+	}
+	added, removed = lineDiff(e.OldString, e.NewString)
+	c.remember(path, strings.Replace(content, e.OldString, e.NewString, n))
+	return n * added, n * removed
+}
+
+func (c *diffCounter) result() *DiffStats {
+	changed := 0
+	for _, f := range c.files {
+		if !f.ReadOnly {
+			changed++
+		}
+	}
+	if c.added == 0 && c.removed == 0 && changed == 0 {
 		return nil
 	}
 
 	return &DiffStats{
-		Added:   added,
-		Removed: removed,
-		Changed: len(files),
+		Added:   c.added,
+		Removed: c.removed,
+		Changed: changed,
+	}
+}
+
+func (c *diffCounter) ledger() []FileChange {
+	if len(c.order) == 0 {
+		return nil
+	}
+	files := make([]FileChange, len(c.order))
+	for i, path := range c.order {
+		files[i] = *c.files[path]
 	}
+	return files
 }
 
 // RelativeTime formats a time.Time as a human-readable relative string.
@@ -77,18 +229,6 @@ func RelativeTime(t time.Time) string {
 	}
 }
 
-func stringVal(m map[string]any, key string) string {
-	v, ok := m[key]
-	if !ok {
-		return ""
-	}
-	s, ok := v.(string)
-	if !ok {
-		return ""
-	}
-	return s
-}
-
 // countLines returns the number of lines in s.
 // An empty string has 0 lines. A string with no newline has 1 line.
 func countLines(s string) int {
@@ -101,3 +241,150 @@ func countLines(s string) int {
 	}
 	return n
 }
+
+// sketchLines returns the number of lines in a Cursor edit sketch, not
+// counting the "... existing code ..." markers that stand in for unchanged
+// lines.
+func sketchLines(s string) int {
+	n := 0
+	for _, line := range splitLines(s) {
+		if !strings.Contains(line, "... existing code ...") {
+			n++
+		}
+	}
+	return n
+}
+
+// maxDiffCells bounds the work lineDiff does to find the longest common
+// subsequence. Larger changes count every differing line as replaced.
+const maxDiffCells = 4_000_000
+
+// lineDiff returns the number of lines added and removed by changing old to
+// new: the lines outside their longest common subsequence.
+func lineDiff(old, new string) (added, removed int) {
+	a, b := splitLines(old), splitLines(new)
+
+	// Common leading and trailing lines are unchanged.
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	if len(a) == 0 || len(b) == 0 || len(a)*len(b) > maxDiffCells {
+		return len(b), len(a)
+	}
+
+	// Length of the longest common subsequence, one row at a time.
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(cur[j], prev[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	lcs := prev[len(b)]
+	return len(b) - lcs, len(a) - lcs
+}
+
+// splitLines splits s into lines without their terminators. A trailing
+// newline does not start another line.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// fileDiff is the lines a patch adds to and removes from one file.
+type fileDiff struct {
+	path           string
+	added, removed int
+}
+
+// patchFiles returns the files a patch touches, in order, with the lines it
+// adds and removes in each. It reads Codex's "*** Begin Patch" format and
+// unified diffs.
+func patchFiles(patch string) []fileDiff {
+	var files []fileDiff
+	// Codex patches have no ---/+++ headers, so such lines are content.
+	codex := strings.HasPrefix(strings.TrimSpace(patch), "*** Begin Patch")
+	// Lines left in the current unified diff hunk, from its @@ header.
+	var oldLeft, newLeft int
+
+	file := func(path string) {
+		if len(files) == 0 || files[len(files)-1].path != path {
+			files = append(files, fileDiff{path: path})
+		}
+	}
+	count := func(added, removed int) {
+		if len(files) == 0 {
+			file("")
+		}
+		files[len(files)-1].added += added
+		files[len(files)-1].removed += removed
+	}
+
+	for line := range strings.Lines(patch) {
+		line = strings.TrimRight(line, "\r\n")
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				count(1, 0)
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				count(0, 1)
+				oldLeft--
+			case strings.HasPrefix(line, "\\"): // "\ No newline at end of file"
+			default:
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "*** "):
+			for _, prefix := range []string{"*** Add File: ", "*** Update File: ", "*** Delete File: ", "*** Move to: "} {
+				if path, ok := strings.CutPrefix(line, prefix); ok {
+					file(strings.TrimSpace(path))
+				}
+			}
+		case strings.HasPrefix(line, "@@ -"):
+			oldLeft, newLeft = hunkLengths(line)
+		case !codex && (strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ")):
+			path, _, _ := strings.Cut(strings.TrimSpace(line[4:]), "\t")
+			if path != "/dev/null" {
+				file(strings.TrimPrefix(strings.TrimPrefix(path, "a/"), "b/"))
+			}
+		case strings.HasPrefix(line, "+"):
+			count(1, 0)
+		case strings.HasPrefix(line, "-"):
+			count(0, 1)
+		}
+	}
+	return files
+}
+
+// hunkLengths parses the old and new line counts of a unified diff hunk
+// header like "@@ -1,3 +1,4 @@". A missing count means one line.
+func hunkLengths(header string) (oldLen, newLen int) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0, 0
+	}
+	count := func(r string) int {
+		_, n, ok := strings.Cut(r[1:], ",")
+		if !ok {
+			return 1
+		}
+		v, _ := strconv.Atoi(n)
+		return v
+	}
+	return count(fields[1]), count(fields[2])
+}
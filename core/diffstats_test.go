@@ -96,6 +96,91 @@ func TestComputeDiffStats(t *testing.T) {
 			}},
 			want: &DiffStats{Added: 2, Removed: 0, Changed: 1},
 		},
+		{
+			name: "edit counts only changed lines",
+			msgs: []Message{{
+				Role: RoleAssistant,
+				Content: []ContentBlock{{
+					Type: BlockToolUse, Name: "Edit",
+					Input: map[string]any{
+						"file_path":  "/a.go",
+						"old_string": "func a() {\n\treturn 1\n}\n",
+						"new_string": "func a() {\n\tlog()\n\treturn 2\n}\n",
+					},
+				}},
+			}},
+			want: &DiffStats{Added: 2, Removed: 1, Changed: 1},
+		},
+		{
+			name: "rewrite of a written file is diffed",
+			msgs: []Message{{
+				Role: RoleAssistant,
+				Content: []ContentBlock{
+					{Type: BlockToolUse, Name: "Write", Input: map[string]any{"file_path": "/a.go", "content": "a\nb\nc\n"}},
+					{Type: BlockToolUse, Name: "Write", Input: map[string]any{"file_path": "/a.go", "content": "a\nB\nc\nd\n"}},
+				},
+			}},
+			want: &DiffStats{Added: 5, Removed: 1, Changed: 1},
+		},
+		{
+			name: "replace_all counts each occurrence in a known file",
+			msgs: []Message{{
+				Role: RoleAssistant,
+				Content: []ContentBlock{
+					{Type: BlockToolUse, Name: "Write", Input: map[string]any{"file_path": "/a.go", "content": "x\ny\nx\nx\n"}},
+					{Type: BlockToolUse, Name: "Edit", Input: map[string]any{"file_path": "/a.go", "old_string": "x", "new_string": "z", "replace_all": true}},
+					{Type: BlockToolUse, Name: "Edit", Input: map[string]any{"file_path": "/a.go", "old_string": "z\ny", "new_string": "y"}},
+				},
+			}},
+			want: &DiffStats{Added: 7, Removed: 4, Changed: 1},
+		},
+		{
+			name: "multiedit counts every edit",
+			msgs: []Message{{
+				Role: RoleAssistant,
+				Content: []ContentBlock{{
+					Type: BlockToolUse, Name: "MultiEdit",
+					Input: map[string]any{"file_path": "/a.go", "edits": []any{
+						map[string]any{"old_string": "a\n", "new_string": "b\nc\n"},
+						map[string]any{"old_string": "d\ne\n", "new_string": ""},
+					}},
+				}},
+			}},
+			want: &DiffStats{Added: 2, Removed: 3, Changed: 1},
+		},
+		{
+			name: "notebook edits",
+			msgs: []Message{{
+				Role: RoleAssistant,
+				Content: []ContentBlock{
+					{Type: BlockToolUse, Name: "NotebookEdit", Input: map[string]any{"notebook_path": "/n.ipynb", "new_source": "import os\nprint(1)", "edit_mode": "insert"}},
+					{Type: BlockToolUse, Name: "NotebookEdit", Input: map[string]any{"notebook_path": "/n.ipynb", "new_source": "", "edit_mode": "delete"}},
+				},
+			}},
+			want: &DiffStats{Added: 2, Removed: 0, Changed: 1},
+		},
+		{
+			name: "codex apply_patch",
+			msgs: []Message{{
+				Role: RoleAssistant,
+				Content: []ContentBlock{{
+					Type: BlockToolUse, Name: "apply_patch",
+					Input: map[string]any{"input": "*** Begin Patch\n*** Update File: main.go\n@@\n-old\n+new\n+more\n*** Add File: new.go\n+package main\n*** End Patch"},
+				}},
+			}},
+			want: &DiffStats{Added: 3, Removed: 1, Changed: 2},
+		},
+		{
+			name: "unified diff patch",
+			msgs: []Message{{
+				Role: RoleAssistant,
+				Content: []ContentBlock{{
+					Type: BlockToolUse, Name: "apply_patch",
+					Input: map[string]any{"input": "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n a\n--- b\n+b\n c\n--- a/other.go\n+++ b/other.go\n@@ -1 +1,2 @@\n x\n+y\n"},
+				}},
+			}},
+			want: &DiffStats{Added: 2, Removed: 1, Changed: 2},
+		},
 	}
 
 	for _, tt := range tests {
@@ -119,6 +204,153 @@ func TestComputeDiffStats(t *testing.T) {
 	}
 }
 
+func TestComputeChangesLedger(t *testing.T) {
+	msgs := []Message{
+		{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "fix it"}}},
+		{Role: RoleAssistant, Content: []ContentBlock{
+			{Type: BlockToolUse, ToolUseID: "t1", Name: "Read", Input: map[string]any{"file_path": "/w/main.go"}},
+			{Type: BlockToolUse, ToolUseID: "t2", Name: "Read", Input: map[string]any{"file_path": "/w/go.mod"}},
+		}},
+		{Role: RoleUser, Content: []ContentBlock{{Type: BlockToolResult, ToolUseID: "t1"}, {Type: BlockToolResult, ToolUseID: "t2"}}},
+		{Role: RoleAssistant, Content: []ContentBlock{
+			{Type: BlockToolUse, ToolUseID: "t3", Name: "Edit", Input: map[string]any{"file_path": "/w/main.go", "old_string": "a\n", "new_string": "b\n"}},
+		}},
+		{Role: RoleSystem, Content: []ContentBlock{{Type: BlockEvent, Event: &Event{Kind: EventCompaction}}}},
+		{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "and the patch"}}},
+		{Role: RoleAssistant, Content: []ContentBlock{
+			{Type: BlockToolUse, ToolUseID: "t4", Name: "apply_patch", Input: map[string]any{"input": "*** Begin Patch\n*** Update File: /w/main.go\n@@\n+c\n*** Add File: /w/new.go\n+x\n+y\n*** End Patch"}},
+		}},
+	}
+
+	stats, files := ComputeChanges(&Transcript{Messages: msgs})
+	assert.Equal(t, &DiffStats{Added: 4, Removed: 1, Changed: 2}, stats)
+	assert.Equal(t, []FileChange{
+		{Path: "/w/main.go", Operations: []ToolKind{ToolRead, ToolEdit, ToolApplyPatch}, Added: 2, Removed: 1, FirstTurn: 1, LastTurn: 2},
+		{Path: "/w/go.mod", Operations: []ToolKind{ToolRead}, FirstTurn: 1, LastTurn: 1, ReadOnly: true},
+		{Path: "/w/new.go", Operations: []ToolKind{ToolApplyPatch}, Added: 2, FirstTurn: 2, LastTurn: 2},
+	}, files)
+
+	seqStats, seqFiles, err := ComputeChangesSeq(Messages(msgs))
+	require.NoError(t, err)
+	assert.Equal(t, stats, seqStats)
+	assert.Equal(t, files, seqFiles)
+}
+
+func TestComputeChangesAgentTools(t *testing.T) {
+	tests := []struct {
+		name  string
+		uses  []ContentBlock
+		stats *DiffStats
+		files []FileChange
+	}{
+		{
+			name: "opencode",
+			uses: []ContentBlock{
+				{Name: "read", Input: map[string]any{"filePath": "/w/main.go"}},
+				{Name: "edit", Input: map[string]any{"filePath": "/w/main.go", "oldString": "a\n", "newString": "b\nc\n"}},
+				{Name: "write", Input: map[string]any{"filePath": "/w/new.go", "content": "x\n"}},
+			},
+			stats: &DiffStats{Added: 3, Removed: 1, Changed: 2},
+			files: []FileChange{
+				{Path: "/w/main.go", Operations: []ToolKind{ToolRead, ToolEdit}, Added: 2, Removed: 1, FirstTurn: 1, LastTurn: 1},
+				{Path: "/w/new.go", Operations: []ToolKind{ToolWrite}, Added: 1, FirstTurn: 1, LastTurn: 1},
+			},
+		},
+		{
+			name: "gemini",
+			uses: []ContentBlock{
+				{Name: "read_file", Input: map[string]any{"absolute_path": "/w/main.go"}},
+				{Name: "replace", Input: map[string]any{"file_path": "/w/main.go", "old_string": "a\n", "new_string": "b\n"}},
+				{Name: "write_file", Input: map[string]any{"file_path": "/w/new.go", "content": "x\ny\n"}},
+			},
+			stats: &DiffStats{Added: 3, Removed: 1, Changed: 2},
+			files: []FileChange{
+				{Path: "/w/main.go", Operations: []ToolKind{ToolRead, ToolEdit}, Added: 1, Removed: 1, FirstTurn: 1, LastTurn: 1},
+				{Path: "/w/new.go", Operations: []ToolKind{ToolWrite}, Added: 2, FirstTurn: 1, LastTurn: 1},
+			},
+		},
+		{
+			name: "cursor",
+			uses: []ContentBlock{
+				{Name: "read_file", Input: map[string]any{"target_file": "main.go"}},
+				{Name: "edit_file", Input: map[string]any{"target_file": "main.go", "code_edit": "// ... existing code ...\nfunc b() {}\n// ... existing code ...\n"}},
+				{Name: "search_replace", Input: map[string]any{"file_path": "util.go", "old_string": "a\n", "new_string": "b\n"}},
+			},
+			stats: &DiffStats{Added: 2, Removed: 1, Changed: 2},
+			files: []FileChange{
+				{Path: "main.go", Operations: []ToolKind{ToolRead, ToolEdit}, Added: 1, FirstTurn: 1, LastTurn: 1},
+				{Path: "util.go", Operations: []ToolKind{ToolEdit}, Added: 1, Removed: 1, FirstTurn: 1, LastTurn: 1},
+			},
+		},
+		{
+			name: "codex",
+			uses: []ContentBlock{
+				{Name: "shell", Input: map[string]any{"command": []any{"cat", "main.go"}}},
+				{Name: "apply_patch", Input: map[string]any{"input": "*** Begin Patch\n*** Update File: main.go\n@@\n-a\n+b\n*** End Patch"}},
+			},
+			stats: &DiffStats{Added: 1, Removed: 1, Changed: 1},
+			files: []FileChange{
+				{Path: "main.go", Operations: []ToolKind{ToolApplyPatch}, Added: 1, Removed: 1, FirstTurn: 1, LastTurn: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.uses {
+				tt.uses[i].Type = BlockToolUse
+			}
+			msgs := []Message{
+				{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "go"}}},
+				{Role: RoleAssistant, Content: tt.uses},
+			}
+			stats, files := ComputeChanges(&Transcript{Messages: msgs})
+			assert.Equal(t, tt.stats, stats)
+			assert.Equal(t, tt.files, files)
+		})
+	}
+}
+
+func TestComputeChangesReadOnly(t *testing.T) {
+	msgs := []Message{{Role: RoleAssistant, Content: []ContentBlock{
+		{Type: BlockToolUse, Name: "Read", Input: map[string]any{"file_path": "/w/main.go"}},
+	}}}
+
+	stats, files := ComputeChanges(&Transcript{Messages: msgs})
+	assert.Nil(t, stats)
+	require.Len(t, files, 1)
+	assert.True(t, files[0].ReadOnly)
+}
+
+func TestFileChangeRelPath(t *testing.T) {
+	f := FileChange{Path: "/w/pkg/main.go"}
+	assert.Equal(t, "pkg/main.go", f.RelPath("/w"))
+	assert.Equal(t, "pkg/main.go", f.RelPath("/w/"))
+	assert.Equal(t, "/w/pkg/main.go", f.RelPath("/work"))
+	assert.Equal(t, "/w/pkg/main.go", f.RelPath(""))
+	assert.Equal(t, "main.go", FileChange{Path: "main.go"}.RelPath("/w"))
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		old, new       string
+		added, removed int
+	}{
+		{"", "", 0, 0},
+		{"", "a\nb\n", 2, 0},
+		{"a\nb\n", "", 0, 2},
+		{"a\nb\nc\n", "a\nb\nc\n", 0, 0},
+		{"a\nb\nc\n", "a\nx\nc\n", 1, 1},
+		{"a\nb\nc\nd\n", "b\nd\ne\n", 1, 2},
+		{"x", "x\n", 0, 0},
+	}
+	for _, tt := range tests {
+		added, removed := lineDiff(tt.old, tt.new)
+		assert.Equal(t, tt.added, added, "added: %q → %q", tt.old, tt.new)
+		assert.Equal(t, tt.removed, removed, "removed: %q → %q", tt.old, tt.new)
+	}
+}
+
 func TestRelativeTime(t *testing.T) {
 	tests := []struct {
 		name string
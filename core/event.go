@@ -0,0 +1,59 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Label describes e for text output, e.g. "context compacted (auto, 155,123
+// tokens)".
+func (e *Event) Label() string {
+	if e == nil {
+		return "event"
+	}
+	var label string
+	switch e.Kind {
+	case EventCompaction:
+		label = "context compacted"
+	case EventResume:
+		label = "session resumed"
+	default:
+		label = string(e.Kind)
+	}
+	var details []string
+	if e.SessionID != "" {
+		details = append(details, e.SessionID)
+	}
+	if e.Trigger != "" {
+		details = append(details, e.Trigger)
+	}
+	if e.PreTokens > 0 {
+		details = append(details, groupDigits(e.PreTokens)+" tokens")
+	}
+	if len(details) > 0 {
+		label += " (" + strings.Join(details, ", ") + ")"
+	}
+	return label
+}
+
+// EventOf returns the event carried by msg, or nil.
+func EventOf(msg *Message) *Event {
+	if msg == nil {
+		return nil
+	}
+	for _, b := range msg.Content {
+		if b.Type == BlockEvent && b.Event != nil {
+			return b.Event
+		}
+	}
+	return nil
+}
+
+// groupDigits formats n with comma thousands separators.
+func groupDigits(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0 && s[i-1] != '-'; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
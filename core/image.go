@@ -0,0 +1,51 @@
+package core
+
+import (
+	"encoding/base64"
+	"image"
+	_ "image/gif"  // register GIF for DecodeConfig
+	_ "image/jpeg" // register JPEG for DecodeConfig
+	_ "image/png"  // register PNG for DecodeConfig
+	"strconv"
+	"strings"
+)
+
+// ImageSize returns the pixel dimensions of base64-encoded image data. Only
+// the image header is decoded. It returns zeros for unsupported formats or
+// invalid data.
+func ImageSize(data string) (width, height int) {
+	dec := base64.NewDecoder(base64.StdEncoding, strings.NewReader(data))
+	cfg, _, err := image.DecodeConfig(dec)
+	if err != nil {
+		return 0, 0
+	}
+	return cfg.Width, cfg.Height
+}
+
+// DataURI returns img as a data: URI for inline embedding, or "" when the
+// image has no inline data.
+func (img *Image) DataURI() string {
+	if img == nil || img.Data == "" {
+		return ""
+	}
+	mediaType := img.MediaType
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	return "data:" + mediaType + ";base64," + img.Data
+}
+
+// Label describes img for text output, e.g. "image/png 1280×720".
+func (img *Image) Label() string {
+	if img == nil {
+		return "image"
+	}
+	label := img.MediaType
+	if label == "" {
+		label = "image"
+	}
+	if img.Width > 0 && img.Height > 0 {
+		label += " " + strconv.Itoa(img.Width) + "×" + strconv.Itoa(img.Height)
+	}
+	return label
+}
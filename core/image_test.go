@@ -0,0 +1,29 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageSize(t *testing.T) {
+	png := "iVBORw0KGgoAAAANSUhEUgAAAAQAAAADCAAAAACRn/EaAAAAHElEQVR4nAAPAPD/AgAAAAACAAAAAAIAAAAAAwAASwAHL9VvuAAAAABJRU5ErkJggg=="
+	w, h := ImageSize(png)
+	assert.Equal(t, 4, w)
+	assert.Equal(t, 3, h)
+
+	w, h = ImageSize("not base64!")
+	assert.Zero(t, w)
+	assert.Zero(t, h)
+}
+
+func TestImageLabel(t *testing.T) {
+	assert.Equal(t, "image/png 4×3", (&Image{MediaType: "image/png", Width: 4, Height: 3}).Label())
+	assert.Equal(t, "image", (&Image{URL: "a.png"}).Label())
+	assert.Equal(t, "image", (*Image)(nil).Label())
+}
+
+func TestImageDataURI(t *testing.T) {
+	assert.Equal(t, "data:image/png;base64,abc", (&Image{MediaType: "image/png", Data: "abc"}).DataURI())
+	assert.Empty(t, (&Image{URL: "a.png"}).DataURI())
+}
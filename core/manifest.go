@@ -7,14 +7,17 @@ import "time"
 // that the index page needs, without carrying the full message list.
 type ManifestEntry struct {
 	SessionID    string     `json:"session_id"`
+	SessionIDs   []string   `json:"session_ids,omitempty"` // every session merged into this entry
 	Title        string     `json:"title,omitempty"`
 	Agent        string     `json:"agent"`
 	Author       string     `json:"author,omitempty"`
 	Model        string     `json:"model,omitempty"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
-	Usage        *Usage     `json:"usage,omitempty"`
+	Usage        *Usage     `json:"usage,omitempty"`       // the session's own messages
+	TotalUsage   *Usage     `json:"total_usage,omitempty"` // including sub-agents; omitted when they add nothing
 	DiffStats    *DiffStats `json:"diff_stats,omitempty"`
+	Files        []string   `json:"files,omitempty"` // changed files, relative to the session's directory
 	MessageCount int        `json:"message_count"`
 	Href         string     `json:"href"`
 }
@@ -24,6 +27,7 @@ type ManifestEntry struct {
 func NewManifestEntry(t *Transcript, href string) ManifestEntry {
 	return ManifestEntry{
 		SessionID:    t.SessionID,
+		SessionIDs:   t.SessionIDs,
 		Title:        t.Title,
 		Agent:        t.Agent,
 		Author:       t.Author,
@@ -31,8 +35,32 @@ func NewManifestEntry(t *Transcript, href string) ManifestEntry {
 		CreatedAt:    t.CreatedAt,
 		UpdatedAt:    t.UpdatedAt,
 		Usage:        t.Usage,
+		TotalUsage:   subAgentTotal(t),
 		DiffStats:    t.DiffStats,
+		Files:        changedFiles(t),
 		MessageCount: len(t.Messages),
 		Href:         href,
 	}
 }
+
+// changedFiles returns the paths of the files t changed, relative to its
+// working directory.
+func changedFiles(t *Transcript) []string {
+	var files []string
+	for _, f := range t.Files {
+		if !f.ReadOnly {
+			files = append(files, f.RelPath(t.Dir))
+		}
+	}
+	return files
+}
+
+// subAgentTotal returns the usage of t including its sub-agents, or nil when
+// the sub-agents record no usage of their own.
+func subAgentTotal(t *Transcript) *Usage {
+	total := t.TotalUsage()
+	if total == nil || t.Usage != nil && *total == *t.Usage {
+		return nil
+	}
+	return total
+}
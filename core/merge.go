@@ -0,0 +1,211 @@
+package core
+
+import (
+	"sort"
+	"time"
+)
+
+// MergeResumed stitches sessions that resumed one another into single
+// transcripts. Transcripts that are not part of a chain are returned as they
+// are; the result keeps the order of each chain's first session in ts.
+func MergeResumed(ts []*Transcript) []*Transcript {
+	var merged []*Transcript
+	for _, chain := range ResumeChains(ts) {
+		if len(chain) == 1 {
+			merged = append(merged, chain[0])
+			continue
+		}
+		merged = append(merged, MergeSessions(chain))
+	}
+	return merged
+}
+
+// ResumeChains groups transcripts into chains of sessions that continue one
+// another, each ordered oldest first. A session continues the earlier one
+// that first carried its ContinuesFrom message or, failing that, the last
+// message it replays: resumed sessions replay the conversation so far. When
+// two sessions resume the same one, the earlier continues the chain and the
+// later starts a chain of its own. Chains are returned in the order of their
+// first session in ts.
+func ResumeChains(ts []*Transcript) [][]*Transcript {
+	order := make([]int, len(ts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return sessionBefore(ts[order[a]], ts[order[b]])
+	})
+
+	// prev and next link each session to the one it continues and the one
+	// continuing it, or -1.
+	prev := make([]int, len(ts))
+	next := make([]int, len(ts))
+	for i := range ts {
+		prev[i], next[i] = -1, -1
+	}
+	// origin maps a message UUID to the session that first carried it.
+	origin := make(map[string]int)
+	for _, i := range order {
+		t := ts[i]
+		if j, ok := resumedFrom(t, origin); ok && j != i && next[j] < 0 {
+			prev[i], next[j] = j, i
+		}
+		for _, m := range t.Messages {
+			if _, ok := origin[m.UUID]; m.UUID != "" && !ok {
+				origin[m.UUID] = i
+			}
+		}
+	}
+
+	head := func(i int) int {
+		for prev[i] >= 0 {
+			i = prev[i]
+		}
+		return i
+	}
+	seen := make(map[int]bool)
+	var chains [][]*Transcript
+	for i := range ts {
+		h := head(i)
+		if seen[h] {
+			continue
+		}
+		seen[h] = true
+		var chain []*Transcript
+		for j := h; j >= 0; j = next[j] {
+			chain = append(chain, ts[j])
+		}
+		chains = append(chains, chain)
+	}
+	return chains
+}
+
+// resumedFrom returns the session t resumes, looked up in origin: the one
+// that first carried t's ContinuesFrom message or, failing that, the last
+// message t replays.
+func resumedFrom(t *Transcript, origin map[string]int) (int, bool) {
+	if j, ok := origin[t.ContinuesFrom]; ok && t.ContinuesFrom != "" {
+		return j, true
+	}
+	from, found := -1, false
+	for _, m := range t.Messages {
+		if j, ok := origin[m.UUID]; ok && m.UUID != "" {
+			from, found = j, true
+		}
+	}
+	return from, found
+}
+
+// sessionBefore orders sessions by start time. A resumed session replays the
+// messages, and so the start time, of the one it continues; ties are broken by
+// the time of last activity.
+func sessionBefore(a, b *Transcript) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return lastActivity(a).Before(lastActivity(b))
+}
+
+func lastActivity(t *Transcript) time.Time {
+	if t.UpdatedAt != nil {
+		return *t.UpdatedAt
+	}
+	return t.CreatedAt
+}
+
+// MergeSessions merges a chain of sessions, oldest first, into one
+// transcript. Messages a session replays from earlier ones are dropped, and a
+// resume event marks where each later session begins. The merged transcript
+// takes its identity from the first session and lists every session in
+// SessionIDs.
+func MergeSessions(chain []*Transcript) *Transcript {
+	first, last := chain[0], chain[len(chain)-1]
+	merged := *first
+	merged.SessionIDs = nil
+	merged.ContinuesFrom = ""
+	merged.Messages = nil
+	merged.SubAgents = nil
+	merged.Branches = nil
+	merged.DiffStats = nil
+	merged.Files = nil
+	merged.Usage = nil
+
+	if updated := lastActivity(last); !updated.Equal(first.CreatedAt) {
+		merged.UpdatedAt = &updated
+	}
+
+	seen := make(map[string]bool)
+	seenSub := make(map[string]bool)
+	var usage Usage
+	var hasUsage bool
+	for i, t := range chain {
+		merged.SessionIDs = append(merged.SessionIDs, t.SessionID)
+		if merged.Model == "" {
+			merged.Model = t.Model
+		}
+		if merged.Title == "" {
+			merged.Title = t.Title
+		}
+
+		marked := i == 0
+		for _, m := range t.Messages {
+			if m.UUID != "" {
+				if seen[m.UUID] {
+					continue
+				}
+				seen[m.UUID] = true
+			}
+			if !marked {
+				// The boundary sits before the session's first new message.
+				marked = true
+				merged.Messages = append(merged.Messages, resumeEvent(t, m.Timestamp))
+			}
+			if m.Usage != nil {
+				usage.Add(*m.Usage)
+				hasUsage = true
+			}
+			merged.Messages = append(merged.Messages, m)
+		}
+
+		for _, sub := range t.SubAgents {
+			if !seenSub[sub.SessionID] {
+				seenSub[sub.SessionID] = true
+				merged.SubAgents = append(merged.SubAgents, sub)
+			}
+		}
+		merged.Branches = append(merged.Branches, t.Branches...)
+	}
+
+	// Replayed messages are counted by every session that carries them, so
+	// usage is recomputed from the merged messages when they carry it.
+	if hasUsage {
+		merged.Usage = &usage
+	} else {
+		for _, t := range chain {
+			if t.Usage != nil {
+				usage.Add(*t.Usage)
+				hasUsage = true
+			}
+		}
+		if hasUsage {
+			merged.Usage = &usage
+		}
+	}
+	return &merged
+}
+
+// resumeEvent returns the system message marking where session t begins.
+func resumeEvent(t *Transcript, ts *time.Time) Message {
+	if ts == nil {
+		created := t.CreatedAt
+		ts = &created
+	}
+	return Message{
+		Role:      RoleSystem,
+		Timestamp: ts,
+		Content: []ContentBlock{{Type: BlockEvent, Event: &Event{
+			Kind:      EventResume,
+			SessionID: t.SessionID,
+		}}},
+	}
+}
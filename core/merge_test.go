@@ -0,0 +1,117 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mergeFixture() (a, b, c, other *Transcript) {
+	t0 := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	msg := func(uuid string, role Role, text string, usage *Usage) Message {
+		return Message{UUID: uuid, Role: role, Usage: usage, Content: []ContentBlock{{Type: BlockText, Text: text}}}
+	}
+	a = &Transcript{
+		SessionID: "a", Agent: "claude", Title: "Add login", CreatedAt: t0,
+		Usage: &Usage{InputTokens: 10},
+		Messages: []Message{
+			msg("u1", RoleUser, "Add login", nil),
+			msg("a1", RoleAssistant, "Added.", &Usage{InputTokens: 10}),
+		},
+	}
+	// b replays a and continues it.
+	b = &Transcript{
+		SessionID: "b", Agent: "claude", Title: "Add login", CreatedAt: t0.Add(time.Hour),
+		Usage: &Usage{InputTokens: 30},
+		Messages: []Message{
+			msg("u1", RoleUser, "Add login", nil),
+			msg("a1", RoleAssistant, "Added.", &Usage{InputTokens: 10}),
+			msg("u2", RoleUser, "Add tests", nil),
+			msg("a2", RoleAssistant, "Tested.", &Usage{InputTokens: 20}),
+		},
+	}
+	// c continues b from its last message without replaying it.
+	c = &Transcript{
+		SessionID: "c", Agent: "claude", CreatedAt: t0.Add(2 * time.Hour), ContinuesFrom: "a2",
+		Messages: []Message{
+			msg("u3", RoleUser, "Ship it", nil),
+		},
+	}
+	other = &Transcript{
+		SessionID: "x", Agent: "claude", CreatedAt: t0.Add(30 * time.Minute),
+		Messages: []Message{msg("ux", RoleUser, "Unrelated", nil)},
+	}
+	return a, b, c, other
+}
+
+func TestResumeChains(t *testing.T) {
+	a, b, c, other := mergeFixture()
+
+	chains := ResumeChains([]*Transcript{c, other, a, b})
+	require.Len(t, chains, 2)
+	assert.Equal(t, []*Transcript{a, b, c}, chains[0])
+	assert.Equal(t, []*Transcript{other}, chains[1])
+}
+
+func TestResumeChainsFork(t *testing.T) {
+	a, b, c, _ := mergeFixture()
+	// d resumes a as well, after b did: it replays a's messages only.
+	d := &Transcript{
+		SessionID: "d", Agent: "claude", CreatedAt: a.CreatedAt.Add(3 * time.Hour),
+		Messages: []Message{
+			{UUID: "u1", Role: RoleUser},
+			{UUID: "a1", Role: RoleAssistant},
+			{UUID: "u4", Role: RoleUser},
+		},
+	}
+	// e continues d.
+	e := &Transcript{
+		SessionID: "e", Agent: "claude", CreatedAt: a.CreatedAt.Add(4 * time.Hour), ContinuesFrom: "u4",
+		Messages: []Message{{UUID: "u5", Role: RoleUser}},
+	}
+
+	chains := ResumeChains([]*Transcript{e, d, c, b, a})
+	require.Len(t, chains, 2)
+	assert.Equal(t, []*Transcript{d, e}, chains[0])
+	assert.Equal(t, []*Transcript{a, b, c}, chains[1])
+}
+
+func TestMergeSessions(t *testing.T) {
+	a, b, c, _ := mergeFixture()
+
+	merged := MergeSessions([]*Transcript{a, b, c})
+	assert.Equal(t, "a", merged.SessionID)
+	assert.Equal(t, []string{"a", "b", "c"}, merged.SessionIDs)
+	assert.Equal(t, "Add login", merged.Title)
+	require.NotNil(t, merged.UpdatedAt)
+	assert.Equal(t, c.CreatedAt, *merged.UpdatedAt)
+	assert.Equal(t, &Usage{InputTokens: 30}, merged.Usage)
+
+	var uuids []string
+	var resumed []string
+	for _, m := range merged.Messages {
+		if ev := EventOf(&m); ev != nil {
+			assert.Equal(t, EventResume, ev.Kind)
+			resumed = append(resumed, ev.SessionID)
+			continue
+		}
+		uuids = append(uuids, m.UUID)
+	}
+	assert.Equal(t, []string{"u1", "a1", "u2", "a2", "u3"}, uuids)
+	assert.Equal(t, []string{"b", "c"}, resumed)
+
+	// The inputs are left untouched.
+	assert.Len(t, a.Messages, 2)
+	assert.Nil(t, a.SessionIDs)
+}
+
+func TestMergeResumed(t *testing.T) {
+	a, b, c, other := mergeFixture()
+
+	got := MergeResumed([]*Transcript{other, a, b, c})
+	require.Len(t, got, 2)
+	assert.Same(t, other, got[0])
+	assert.Equal(t, []string{"a", "b", "c"}, got[1].SessionIDs)
+}
@@ -0,0 +1,145 @@
+// Package migrate upgrades transcript and manifest documents written by
+// earlier versions of cg to the current core.SchemaVersion.
+//
+// Each format has a list of steps; step i upgrades a decoded document from
+// version i to version i+1. Documents without a schema_version predate
+// versioning and are version 0. To change a format, bump core.SchemaVersion
+// and append a step to both lists.
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sonnes/chitragupt/core"
+)
+
+// step upgrades a decoded document by one version in place.
+type step func(doc map[string]any)
+
+var transcriptSteps = []step{
+	transcriptV1,
+}
+
+var manifestSteps = []step{
+	func(map[string]any) {}, // v1 only adds schema_version
+}
+
+// Transcript upgrades a transcript JSON document and decodes it.
+func Transcript(data []byte) (*core.Transcript, error) {
+	var t core.Transcript
+	if err := upgrade(data, transcriptSteps, &t); err != nil {
+		return nil, fmt.Errorf("transcript: %w", err)
+	}
+	return &t, nil
+}
+
+// Manifest upgrades a manifest JSON document and decodes it into v, which is
+// typically a *manifest.Manifest.
+func Manifest(data []byte, v any) error {
+	if err := upgrade(data, manifestSteps, v); err != nil {
+		return fmt.Errorf("manifest: %w", err)
+	}
+	return nil
+}
+
+// upgrade runs the steps from the document's version to the current one,
+// then decodes the result into v. Current documents are decoded directly.
+func upgrade(data []byte, steps []step, v any) error {
+	var head struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	version := head.SchemaVersion
+	switch {
+	case version > core.SchemaVersion:
+		return fmt.Errorf("schema version %d is newer than supported (%d)", version, core.SchemaVersion)
+	case version == core.SchemaVersion:
+		return json.Unmarshal(data, v)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for ; version < core.SchemaVersion; version++ {
+		steps[version](doc)
+	}
+	doc["schema_version"] = core.SchemaVersion
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(upgraded, v)
+}
+
+// compactSummaryPrefix starts the summary Claude Code writes after
+// compacting a conversation.
+const compactSummaryPrefix = "This session is being continued from a previous conversation"
+
+// transcriptV1 turns compaction summaries, which version 0 recorded as user
+// prompts, into compaction events.
+func transcriptV1(doc map[string]any) {
+	if doc["agent"] == "claude" {
+		eachMessageList(doc, func(messages []any) {
+			for _, m := range messages {
+				if msg, ok := m.(map[string]any); ok {
+					summaryToEvent(msg)
+				}
+			}
+		})
+	}
+	if subs, ok := doc["sub_agents"].([]any); ok {
+		for _, sub := range subs {
+			if s, ok := sub.(map[string]any); ok {
+				transcriptV1(s)
+			}
+		}
+	}
+}
+
+// eachMessageList calls fn with the messages of doc and of each branch.
+func eachMessageList(doc map[string]any, fn func([]any)) {
+	if messages, ok := doc["messages"].([]any); ok {
+		fn(messages)
+	}
+	if branches, ok := doc["branches"].([]any); ok {
+		for _, b := range branches {
+			if branch, ok := b.(map[string]any); ok {
+				if messages, ok := branch["messages"].([]any); ok {
+					fn(messages)
+				}
+			}
+		}
+	}
+}
+
+// summaryToEvent rewrites msg as a compaction event if it is a user message
+// holding only a compaction summary.
+func summaryToEvent(msg map[string]any) {
+	content, ok := msg["content"].([]any)
+	if msg["role"] != string(core.RoleUser) || !ok || len(content) != 1 {
+		return
+	}
+	block, ok := content[0].(map[string]any)
+	if !ok || block["type"] != string(core.BlockText) {
+		return
+	}
+	text, _ := block["text"].(string)
+	if !strings.HasPrefix(text, compactSummaryPrefix) {
+		return
+	}
+
+	msg["role"] = string(core.RoleSystem)
+	msg["content"] = []any{map[string]any{
+		"type": string(core.BlockEvent),
+		"event": map[string]any{
+			"kind":    string(core.EventCompaction),
+			"summary": text,
+		},
+	}}
+}
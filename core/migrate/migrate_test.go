@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const summary = "This session is being continued from a previous conversation that ran out of context."
+
+func TestTranscriptV0(t *testing.T) {
+	data := `{
+		"session_id": "s1",
+		"agent": "claude",
+		"created_at": "2026-02-15T10:00:00Z",
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "` + summary + `"}]},
+			{"role": "user", "content": [{"type": "text", "text": "keep going"}]}
+		],
+		"branches": [
+			{"messages": [{"role": "user", "content": [{"type": "text", "text": "` + summary + `"}]}]}
+		],
+		"sub_agents": [
+			{"session_id": "a1", "agent": "claude", "created_at": "2026-02-15T10:00:00Z", "messages": [
+				{"role": "user", "content": [{"type": "text", "text": "` + summary + `"}]}
+			]}
+		]
+	}`
+
+	tr, err := Transcript([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, core.SchemaVersion, tr.SchemaVersion)
+
+	event := func(m core.Message) {
+		t.Helper()
+		assert.Equal(t, core.RoleSystem, m.Role)
+		require.Len(t, m.Content, 1)
+		require.Equal(t, core.BlockEvent, m.Content[0].Type)
+		assert.Equal(t, core.EventCompaction, m.Content[0].Event.Kind)
+		assert.Equal(t, summary, m.Content[0].Event.Summary)
+	}
+
+	require.Len(t, tr.Messages, 2)
+	event(tr.Messages[0])
+	assert.Equal(t, core.RoleUser, tr.Messages[1].Role)
+	assert.Equal(t, "keep going", tr.Messages[1].Content[0].Text)
+
+	require.Len(t, tr.Branches, 1)
+	event(tr.Branches[0].Messages[0])
+	require.Len(t, tr.SubAgents, 1)
+	event(tr.SubAgents[0].Messages[0])
+}
+
+func TestTranscriptV0OtherAgents(t *testing.T) {
+	data := `{"session_id": "s1", "agent": "codex", "created_at": "2026-02-15T10:00:00Z", "messages": [
+		{"role": "user", "content": [{"type": "text", "text": "` + summary + `"}]}
+	]}`
+
+	tr, err := Transcript([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, core.RoleUser, tr.Messages[0].Role)
+	assert.Equal(t, summary, tr.Messages[0].Content[0].Text)
+}
+
+func TestTranscriptCurrent(t *testing.T) {
+	// Current documents are decoded as they are, summaries included.
+	data := fmt.Sprintf(`{"schema_version": %d, "session_id": "s1", "agent": "claude", "created_at": "2026-02-15T10:00:00Z", "messages": [
+		{"role": "user", "content": [{"type": "text", "text": "%s"}]}
+	]}`, core.SchemaVersion, summary)
+
+	tr, err := Transcript([]byte(data))
+	require.NoError(t, err)
+	assert.Equal(t, core.SchemaVersion, tr.SchemaVersion)
+	assert.Equal(t, core.RoleUser, tr.Messages[0].Role)
+}
+
+func TestTranscriptNewer(t *testing.T) {
+	data := fmt.Sprintf(`{"schema_version": %d, "session_id": "s1", "agent": "claude"}`, core.SchemaVersion+1)
+
+	_, err := Transcript([]byte(data))
+	assert.ErrorContains(t, err, "is newer than supported")
+}
+
+func TestTranscriptInvalid(t *testing.T) {
+	_, err := Transcript([]byte(`{"session_id": `))
+	assert.ErrorContains(t, err, "transcript:")
+}
+
+func TestManifest(t *testing.T) {
+	var m struct {
+		SchemaVersion int                  `json:"schema_version"`
+		Entries       []core.ManifestEntry `json:"entries"`
+	}
+	data := `{"entries": [{"session_id": "abc", "agent": "claude", "created_at": "2026-02-15T10:00:00Z", "href": "claude/abc/index.html"}]}`
+
+	require.NoError(t, Manifest([]byte(data), &m))
+	assert.Equal(t, core.SchemaVersion, m.SchemaVersion)
+	require.Len(t, m.Entries, 1)
+	assert.Equal(t, "abc", m.Entries[0].SessionID)
+}
+
+func TestStepsCoverEveryVersion(t *testing.T) {
+	assert.Len(t, transcriptSteps, core.SchemaVersion)
+	assert.Len(t, manifestSteps, core.SchemaVersion)
+}
@@ -0,0 +1,311 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PatchOptions controls Patches.
+type PatchOptions struct {
+	// SkipFailed leaves out tool calls whose tool_result is an error.
+	SkipFailed bool
+
+	// Base, when set, returns the content of a file before the session, given
+	// its path as the tool calls recorded it. ok is false for files that did
+	// not exist or could not be read.
+	Base func(path string) (content string, ok bool)
+}
+
+// FilePatch is the unified diff of the changes a session made to one file.
+type FilePatch struct {
+	Path string // relative to the transcript's Dir when inside it
+	Diff string // unified diff, including the ---/+++ header
+}
+
+// Patches replays the Write, Edit and MultiEdit calls of t in order,
+// including those of sub-agents at the point they were started, and returns
+// a unified diff per file in order of first change. Files whose content ends
+// up unchanged are left out.
+//
+// A file's earlier content comes from opts.Base. When it is unknown, a file
+// the session wrote is shown as created, and each edit as a hunk of the
+// replaced text whose line numbers are only a hint. patch and
+// git apply --unidiff-zero locate such hunks by their content, which only
+// works for edits of whole lines. Cursor edit_file sketches do not record the
+// text they replace and are left out.
+func Patches(t *Transcript, opts PatchOptions) []FilePatch {
+	p := patcher{opts: opts, files: make(map[string]*replayFile)}
+	p.replay(t)
+
+	var patches []FilePatch
+	for _, path := range p.order {
+		f := p.files[path]
+		rel := FileChange{Path: path}.RelPath(t.Dir)
+		if diff := f.diff(rel); diff != "" {
+			patches = append(patches, FilePatch{Path: rel, Diff: diff})
+		}
+	}
+	return patches
+}
+
+// patcher replays file changes across a transcript and its sub-agents.
+type patcher struct {
+	opts  PatchOptions
+	files map[string]*replayFile
+	order []string
+}
+
+func (p *patcher) replay(t *Transcript) {
+	for _, c := range ToolCalls(t.Messages) {
+		if p.opts.SkipFailed && c.Result != nil && c.Result.IsError {
+			continue
+		}
+		switch in := c.Input.(type) {
+		case *WriteInput:
+			p.file(in.FilePath).write(in.Content)
+		case *EditInput:
+			if in.Sketch {
+				break
+			}
+			p.file(in.FilePath).edit(EditSpan{OldString: in.OldString, NewString: in.NewString, ReplaceAll: in.ReplaceAll})
+		case *MultiEditInput:
+			f := p.file(in.FilePath)
+			for _, e := range in.Edits {
+				f.edit(e)
+			}
+		}
+		if ref := c.Use.SubAgentRef; ref != nil {
+			for _, sub := range t.SubAgents {
+				if sub.SessionID == ref.AgentID {
+					p.replay(sub)
+				}
+			}
+		}
+	}
+}
+
+// file returns the replay state of path, loading its base on first use.
+func (p *patcher) file(path string) *replayFile {
+	if f, ok := p.files[path]; ok {
+		return f
+	}
+	f := &replayFile{}
+	if p.opts.Base != nil {
+		f.base, f.known = p.opts.Base(path)
+		f.existed = f.known
+		f.content = f.base
+	}
+	p.files[path] = f
+	p.order = append(p.order, path)
+	return f
+}
+
+// replayFile is the state of one file while replaying. While its content is
+// known, changes are applied to it; otherwise edits are kept as hunks.
+type replayFile struct {
+	base    string
+	existed bool // base is the content of an existing file
+	content string
+	known   bool
+	hunks   []string // edits to unknown content
+}
+
+func (f *replayFile) write(content string) {
+	if !f.known {
+		// The earlier content is unknown, and replaced as a whole.
+		f.hunks = nil
+		f.known = true
+	}
+	f.content = content
+}
+
+func (f *replayFile) edit(e EditSpan) {
+	if !f.known {
+		if e.OldString == "" {
+			f.write(e.NewString) // an empty old_string creates the file
+			return
+		}
+		a, b := splitKeepLines(e.OldString), splitKeepLines(e.NewString)
+		f.hunks = append(f.hunks, formatHunks(diffLines(a, b), len(a)+len(b)))
+		return
+	}
+	if e.OldString == "" || !strings.Contains(f.content, e.OldString) {
+		return // the call failed, or changed the file in a way we cannot see
+	}
+	n := 1
+	if e.ReplaceAll {
+		n = -1
+	}
+	f.content = strings.Replace(f.content, e.OldString, e.NewString, n)
+}
+
+// diff returns the unified diff of f, with path in its header.
+func (f *replayFile) diff(path string) string {
+	var body string
+	switch {
+	case len(f.hunks) > 0:
+		body = strings.Join(f.hunks, "")
+	case f.known:
+		body = formatHunks(diffLines(splitKeepLines(f.base), splitKeepLines(f.content)), 3)
+	}
+	if body == "" {
+		return ""
+	}
+
+	from, to := "a/"+path, "b/"+path
+	if filepath.IsAbs(path) {
+		from, to = path, path
+	}
+	if f.known && !f.existed {
+		from = "/dev/null"
+	}
+	return fmt.Sprintf("--- %s\n+++ %s\n%s", from, to, body)
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	line string // with its line terminator, if any
+}
+
+// diffLines returns an edit script turning a into b, built from their
+// longest common subsequence. Inputs larger than maxDiffCells are diffed as
+// one replaced block after trimming common leading and trailing lines.
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffOp{' ', a[prefix]})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if len(ma)*len(mb) > maxDiffCells {
+		for _, l := range ma {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range mb {
+			ops = append(ops, diffOp{'+', l})
+		}
+	} else {
+		// lcs[i][j] is the LCS length of ma[i:] and mb[j:].
+		lcs := make([][]int32, len(ma)+1)
+		for i := range lcs {
+			lcs[i] = make([]int32, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(ma) || j < len(mb) {
+			switch {
+			case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+				ops = append(ops, diffOp{' ', ma[i]})
+				i++
+				j++
+			case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+				ops = append(ops, diffOp{'-', ma[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', mb[j]})
+				j++
+			}
+		}
+	}
+
+	for _, l := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// formatHunks formats an edit script as unified diff hunks with up to
+// context unchanged lines around each change.
+func formatHunks(ops []diffOp, context int) string {
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk until a run of more than 2*context kept lines.
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			} else if i-last > 2*context {
+				break
+			}
+		}
+		from := max(start, first-context)
+		to := min(len(ops), last+1+context)
+
+		// Line numbers of the hunk's first line in each file.
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		var oldLen, newLen int
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		if oldLen == 0 {
+			oldLine--
+		}
+		if newLen == 0 {
+			newLine--
+		}
+
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldLine, oldLen), hunkRange(newLine, newLen))
+		for _, op := range ops[from:to] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// hunkRange formats the start and length of one side of a hunk header.
+func hunkRange(start, n int) string {
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// splitKeepLines splits s into lines, each keeping its "\n" terminator, so
+// that a last line without one differs from the same line with one.
+func splitKeepLines(s string) []string {
+	return slices.Collect(strings.Lines(s))
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func patchTranscript(blocks ...ContentBlock) *Transcript {
+	return &Transcript{
+		SessionID: "s1",
+		Dir:       "/w",
+		Messages: []Message{
+			{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "go"}}},
+			{Role: RoleAssistant, Content: blocks},
+		},
+	}
+}
+
+func TestPatchesWithBase(t *testing.T) {
+	tr := patchTranscript(
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t1", Name: "Edit", Input: map[string]any{
+			"file_path": "/w/main.go", "old_string": "return 1", "new_string": "return 2",
+		}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t2", Name: "MultiEdit", Input: map[string]any{
+			"file_path": "/w/main.go", "edits": []any{
+				map[string]any{"old_string": "x", "new_string": "y", "replace_all": true},
+			},
+		}},
+	)
+	base := "package main\n\nfunc a() int {\n\treturn 1\n}\n\nvar x = 1\nvar b = 2\nvar c = 3\nvar d = 4\nvar e = 5\nvar f = 6\nvar g = 7\nvar h = 8\nvar x2 = 9\n"
+	opts := PatchOptions{Base: func(path string) (string, bool) {
+		return base, path == "/w/main.go"
+	}}
+
+	patches := Patches(tr, opts)
+	require.Len(t, patches, 1)
+	assert.Equal(t, "main.go", patches[0].Path)
+	assert.Equal(t, "--- a/main.go\n+++ b/main.go\n"+
+		"@@ -1,10 +1,10 @@\n"+
+		" package main\n \n func a() int {\n-\treturn 1\n+\treturn 2\n }\n \n-var x = 1\n+var y = 1\n var b = 2\n var c = 3\n var d = 4\n"+
+		"@@ -12,4 +12,4 @@\n"+
+		" var f = 6\n var g = 7\n var h = 8\n-var x2 = 9\n+var y2 = 9\n",
+		patches[0].Diff)
+}
+
+func TestPatchesNewFile(t *testing.T) {
+	tr := patchTranscript(
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t1", Name: "Write", Input: map[string]any{"file_path": "/w/new.go", "content": "a\nb\n"}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t2", Name: "Edit", Input: map[string]any{"file_path": "/w/new.go", "old_string": "b", "new_string": "c"}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t3", Name: "Write", Input: map[string]any{"file_path": "/tmp/out.txt", "content": "no newline"}},
+	)
+
+	patches := Patches(tr, PatchOptions{})
+	require.Len(t, patches, 2)
+	assert.Equal(t, "--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,2 @@\n+a\n+c\n", patches[0].Diff)
+	assert.Equal(t, "/tmp/out.txt", patches[1].Path)
+	assert.Equal(t, "--- /dev/null\n+++ /tmp/out.txt\n@@ -0,0 +1 @@\n+no newline\n\\ No newline at end of file\n", patches[1].Diff)
+}
+
+func TestPatchesUnknownBase(t *testing.T) {
+	tr := patchTranscript(
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t1", Name: "Edit", Input: map[string]any{
+			"file_path": "/w/main.go", "old_string": "func a() {\n\treturn 1\n}\n", "new_string": "func a() {\n\treturn 2\n}\n",
+		}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t2", Name: "Edit", Input: map[string]any{
+			"file_path": "/w/main.go", "old_string": "var x = 1\n", "new_string": "",
+		}},
+	)
+
+	patches := Patches(tr, PatchOptions{})
+	require.Len(t, patches, 1)
+	assert.Equal(t, "--- a/main.go\n+++ b/main.go\n"+
+		"@@ -1,3 +1,3 @@\n func a() {\n-\treturn 1\n+\treturn 2\n }\n"+
+		"@@ -1 +0,0 @@\n-var x = 1\n",
+		patches[0].Diff)
+}
+
+func TestPatchesAgentTools(t *testing.T) {
+	tr := patchTranscript(
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t1", Name: "write", Input: map[string]any{"filePath": "/w/a.go", "content": "a\nb\n"}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t2", Name: "edit", Input: map[string]any{"filePath": "/w/a.go", "oldString": "b", "newString": "c"}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t3", Name: "write_file", Input: map[string]any{"file_path": "/w/b.go", "content": "x\n"}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t4", Name: "edit_file", Input: map[string]any{"target_file": "/w/c.go", "code_edit": "// ... existing code ...\ny\n"}},
+	)
+
+	patches := Patches(tr, PatchOptions{})
+	require.Len(t, patches, 2)
+	assert.Equal(t, "--- /dev/null\n+++ b/a.go\n@@ -0,0 +1,2 @@\n+a\n+c\n", patches[0].Diff)
+	assert.Equal(t, "b.go", patches[1].Path)
+}
+
+func TestPatchesSkipFailed(t *testing.T) {
+	tr := patchTranscript(
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t1", Name: "Write", Input: map[string]any{"file_path": "/w/a.txt", "content": "a\n"}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t2", Name: "Write", Input: map[string]any{"file_path": "/w/b.txt", "content": "b\n"}},
+	)
+	tr.Messages = append(tr.Messages, Message{Role: RoleUser, Content: []ContentBlock{
+		{Type: BlockToolResult, ToolUseID: "t1", Content: "ok"},
+		{Type: BlockToolResult, ToolUseID: "t2", Content: "permission denied", IsError: true},
+	}})
+
+	assert.Len(t, Patches(tr, PatchOptions{}), 2)
+
+	patches := Patches(tr, PatchOptions{SkipFailed: true})
+	require.Len(t, patches, 1)
+	assert.Equal(t, "a.txt", patches[0].Path)
+}
+
+func TestPatchesSubAgents(t *testing.T) {
+	task := ContentBlock{Type: BlockToolUse, ToolUseID: "t1", Name: "Task", Input: map[string]any{"description": "x"},
+		SubAgentRef: &SubAgentRef{AgentID: "a1"}}
+	tr := patchTranscript(
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t0", Name: "Write", Input: map[string]any{"file_path": "/w/a.txt", "content": "1\n"}},
+		task,
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t2", Name: "Edit", Input: map[string]any{"file_path": "/w/a.txt", "old_string": "2", "new_string": "3"}},
+	)
+	tr.SubAgents = []*Transcript{{SessionID: "a1", Messages: []Message{{Role: RoleAssistant, Content: []ContentBlock{
+		{Type: BlockToolUse, ToolUseID: "s1", Name: "Edit", Input: map[string]any{"file_path": "/w/a.txt", "old_string": "1", "new_string": "2"}},
+	}}}}}
+
+	patches := Patches(tr, PatchOptions{})
+	require.Len(t, patches, 1)
+	assert.Equal(t, "--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1 @@\n+3\n", patches[0].Diff)
+}
+
+func TestPatchesUnchanged(t *testing.T) {
+	tr := patchTranscript(
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t1", Name: "Edit", Input: map[string]any{"file_path": "/w/a.txt", "old_string": "a", "new_string": "b"}},
+		ContentBlock{Type: BlockToolUse, ToolUseID: "t2", Name: "Edit", Input: map[string]any{"file_path": "/w/a.txt", "old_string": "b", "new_string": "a"}},
+	)
+	opts := PatchOptions{Base: func(string) (string, bool) { return "a\n", true }}
+	assert.Empty(t, Patches(tr, opts))
+}
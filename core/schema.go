@@ -0,0 +1,51 @@
+package core
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// SchemaJSON is the JSON Schema for the transcript format, as published in
+// core/schema.json.
+//
+//go:embed schema.json
+var SchemaJSON []byte
+
+// SchemaID is the $id of the transcript schema.
+const SchemaID = "https://github.com/sonnes/chitragupt/transcript"
+
+// SchemaVersion is the version of the transcript and manifest formats written
+// by this build, stored in their schema_version field. Documents without one
+// predate versioning and are version 0; core/migrate upgrades them on load.
+const SchemaVersion = 1
+
+var compileSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(SchemaJSON))
+	if err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(SchemaID, doc); err != nil {
+		return nil, fmt.Errorf("load schema: %w", err)
+	}
+	return c.Compile(SchemaID)
+})
+
+// ValidateJSON checks that data is a JSON transcript matching the schema. The
+// returned error lists every violation with its location in the document.
+func ValidateJSON(data []byte) error {
+	sch, err := compileSchema()
+	if err != nil {
+		return err
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("parse transcript: %w", err)
+	}
+	return sch.Validate(doc)
+}
@@ -0,0 +1,52 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJSON(t *testing.T) {
+	ts := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	sub := &Transcript{
+		SessionID: "agent-1", ParentSessionID: "s1", Agent: "claude", CreatedAt: ts,
+		Messages: []Message{{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "find tests"}}}},
+	}
+	tr := &Transcript{
+		SessionID: "s1",
+		Agent:     "claude",
+		Author:    "Ada",
+		CreatedAt: ts,
+		UpdatedAt: &ts,
+		Usage:     &Usage{InputTokens: 10, OutputTokens: 5},
+		DiffStats: &DiffStats{Added: 3, Removed: 1, Changed: 1},
+		Messages: []Message{
+			{UUID: "u1", Role: RoleUser, Timestamp: &ts, Content: []ContentBlock{
+				{Type: BlockText, Format: FormatPlain, Text: "Fix it"},
+				{Type: BlockImage, Image: &Image{MediaType: "image/png", Data: "iVBORw0KGgo="}},
+			}},
+			{UUID: "a1", ParentUUID: "u1", Role: RoleAssistant, Model: "claude-sonnet-4", Content: []ContentBlock{
+				{Type: BlockThinking, Text: "Look first."},
+				{Type: BlockToolUse, ToolUseID: "t1", Name: "Task", Input: map[string]any{"description": "find tests"},
+					SubAgentRef: &SubAgentRef{AgentID: "agent-1"}},
+				{Type: BlockToolResult, ToolUseID: "t1", Content: "done"},
+			}},
+			{Role: RoleSystem, Content: []ContentBlock{{Type: BlockEvent, Event: &Event{Kind: EventCompaction, Trigger: "auto"}}}},
+		},
+		SubAgents: []*Transcript{sub},
+	}
+	data, err := json.Marshal(tr)
+	require.NoError(t, err)
+	assert.NoError(t, ValidateJSON(data))
+
+	err = ValidateJSON([]byte(`{"session_id":"s1","agent":"unknown","created_at":"yesterday","messages":[{"role":"user","content":[]}],"extra":true}`))
+	require.Error(t, err)
+	for _, want := range []string{"/agent", "/created_at", "'extra' not allowed"} {
+		assert.ErrorContains(t, err, want)
+	}
+
+	assert.ErrorContains(t, ValidateJSON([]byte(`{`)), "parse transcript")
+}
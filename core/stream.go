@@ -0,0 +1,46 @@
+package core
+
+import "iter"
+
+// MessageSeq is a stream of messages in conversation order. A non-nil error
+// ends the stream.
+type MessageSeq = iter.Seq2[Message, error]
+
+// Messages returns a MessageSeq over an in-memory message list, for passing a
+// fully read transcript to streaming APIs.
+func Messages(messages []Message) MessageSeq {
+	return func(yield func(Message, error) bool) {
+		for _, m := range messages {
+			if !yield(m, nil) {
+				return
+			}
+		}
+	}
+}
+
+// TransformStream applies transformers to each message as it passes through
+// the stream. Each message is wrapped in a single-message transcript, so only
+// message-level changes take effect; apply the transformers to the header
+// separately. Whatever messages the transformers leave in the transcript are
+// yielded in its place, so dropped messages are skipped.
+func TransformStream(messages MessageSeq, transformers ...Transformer) MessageSeq {
+	return func(yield func(Message, error) bool) {
+		for m, err := range messages {
+			if err != nil {
+				yield(Message{}, err)
+				return
+			}
+			t := &Transcript{Messages: []Message{m}}
+			if err := Chain(t, transformers...); err != nil {
+				yield(Message{}, err)
+				return
+			}
+			// A transformer may drop the message or split it into several.
+			for _, m := range t.Messages {
+				if !yield(m, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamFixture() []Message {
+	return []Message{
+		{Role: RoleAssistant, Content: []ContentBlock{{Type: BlockText, Text: "preamble"}}},
+		{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "first"}}},
+		{Role: RoleAssistant, Content: []ContentBlock{
+			{Type: BlockToolUse, ToolUseID: "t1", Name: "Edit", Input: map[string]any{
+				"file_path": "a.go", "old_string": "x", "new_string": "y\nz",
+			}},
+		}},
+		{Role: RoleUser, Content: []ContentBlock{{Type: BlockToolResult, ToolUseID: "t1", Content: "ok"}}},
+		{Role: RoleAssistant, Content: []ContentBlock{{Type: BlockText, Text: "done"}}},
+		{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "second"}}},
+	}
+}
+
+type upperTransformer struct{}
+
+func (upperTransformer) Transform(t *Transcript) error {
+	for i := range t.Messages {
+		for j := range t.Messages[i].Content {
+			b := &t.Messages[i].Content[j]
+			b.Text = strings.ToUpper(b.Text)
+		}
+	}
+	return nil
+}
+
+// dropTransformer removes messages that contain no text.
+type dropTransformer struct{}
+
+func (dropTransformer) Transform(t *Transcript) error {
+	var kept []Message
+	for _, m := range t.Messages {
+		if m.Content[0].Type == BlockText {
+			kept = append(kept, m)
+		}
+	}
+	t.Messages = kept
+	return nil
+}
+
+type failTransformer struct{}
+
+func (failTransformer) Transform(*Transcript) error { return errors.New("boom") }
+
+func TestStreamTurns(t *testing.T) {
+	messages := streamFixture()
+
+	var streamed []Turn
+	for turn, err := range StreamTurns(Messages(messages)) {
+		require.NoError(t, err)
+		streamed = append(streamed, turn)
+	}
+
+	assert.Equal(t, GroupTurns(messages), streamed)
+}
+
+func TestStreamTurnsError(t *testing.T) {
+	seq := func(yield func(Message, error) bool) {
+		if !yield(Message{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "hi"}}}, nil) {
+			return
+		}
+		yield(Message{}, errors.New("read failed"))
+	}
+
+	var turns int
+	var gotErr error
+	for _, err := range StreamTurns(seq) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		turns++
+	}
+	assert.Equal(t, 0, turns)
+	assert.EqualError(t, gotErr, "read failed")
+}
+
+func TestTransformStream(t *testing.T) {
+	var texts []string
+	for m, err := range TransformStream(Messages(streamFixture()[:2]), upperTransformer{}) {
+		require.NoError(t, err)
+		texts = append(texts, m.Content[0].Text)
+	}
+	assert.Equal(t, []string{"PREAMBLE", "FIRST"}, texts)
+
+	for _, err := range TransformStream(Messages(streamFixture()), failTransformer{}) {
+		assert.EqualError(t, err, "boom")
+	}
+
+	// Messages a transformer drops are skipped.
+	texts = nil
+	for m, err := range TransformStream(Messages(streamFixture()), dropTransformer{}, upperTransformer{}) {
+		require.NoError(t, err)
+		texts = append(texts, m.Content[0].Text)
+	}
+	assert.Equal(t, []string{"PREAMBLE", "FIRST", "DONE", "SECOND"}, texts)
+}
+
+func TestComputeDiffStatsSeq(t *testing.T) {
+	messages := streamFixture()
+
+	got, err := ComputeDiffStatsSeq(Messages(messages))
+	require.NoError(t, err)
+	assert.Equal(t, ComputeDiffStats(&Transcript{Messages: messages}), got)
+	assert.Equal(t, &DiffStats{Added: 2, Removed: 1, Changed: 1}, got)
+}
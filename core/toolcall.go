@@ -0,0 +1,402 @@
+package core
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// ToolKind identifies a well-known tool independently of how an agent names
+// it.
+type ToolKind string
+
+const (
+	ToolUnknown      ToolKind = ""
+	ToolBash         ToolKind = "bash" // Claude Code Bash, Codex shell
+	ToolRead         ToolKind = "read"
+	ToolWrite        ToolKind = "write"
+	ToolEdit         ToolKind = "edit"
+	ToolMultiEdit    ToolKind = "multiedit"
+	ToolGlob         ToolKind = "glob"
+	ToolGrep         ToolKind = "grep"
+	ToolTask         ToolKind = "task"
+	ToolTodoWrite    ToolKind = "todowrite"
+	ToolWebFetch     ToolKind = "webfetch"
+	ToolNotebookEdit ToolKind = "notebookedit"
+	ToolApplyPatch   ToolKind = "apply_patch" // Codex
+)
+
+// ToolKindOf returns the kind of the tool called name, which may be the name
+// Claude Code, OpenCode, Codex, Gemini CLI or Cursor gives it. Names are
+// matched case-insensitively.
+func ToolKindOf(name string) ToolKind {
+	switch k := ToolKind(strings.ToLower(name)); k {
+	case "shell", "run_shell_command", "run_terminal_cmd":
+		return ToolBash
+	case "read_file":
+		return ToolRead
+	case "write_file":
+		return ToolWrite
+	case "replace", "search_replace", "edit_file":
+		return ToolEdit
+	case "search_file_content", "grep_search":
+		return ToolGrep
+	case "web_fetch":
+		return ToolWebFetch
+	case "patch": // OpenCode
+		return ToolApplyPatch
+	case ToolBash, ToolRead, ToolWrite, ToolEdit, ToolMultiEdit, ToolGlob, ToolGrep,
+		ToolTask, ToolTodoWrite, ToolWebFetch, ToolNotebookEdit, ToolApplyPatch:
+		return k
+	}
+	return ToolUnknown
+}
+
+// ToolInput is the decoded input of a well-known tool: one of *BashInput,
+// *ReadInput, *WriteInput, *EditInput, *MultiEditInput, *GlobInput,
+// *GrepInput, *TaskInput, *TodoWriteInput, *WebFetchInput,
+// *NotebookEditInput or *ApplyPatchInput.
+type ToolInput interface {
+	// Summary returns the most relevant argument, such as the command or
+	// file path, or "" if there is none.
+	Summary() string
+}
+
+// BashInput runs a shell command.
+type BashInput struct {
+	Command     string
+	Description string
+	Workdir     string // Codex only
+}
+
+// ReadInput reads a file, optionally a range of its lines.
+type ReadInput struct {
+	FilePath string `json:"file_path"`
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+}
+
+// WriteInput creates or overwrites a file.
+type WriteInput struct {
+	FilePath string `json:"file_path"`
+	Content  string `json:"content"`
+}
+
+// EditInput replaces text in a file.
+type EditInput struct {
+	FilePath   string `json:"file_path"`
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all"`
+
+	// Sketch is set for Cursor edit_file calls. Their NewString is the
+	// changed region with "// ... existing code ..." markers standing in for
+	// unchanged lines, and OldString is empty.
+	Sketch bool `json:"-"`
+}
+
+// MultiEditInput applies several edits to one file in order.
+type MultiEditInput struct {
+	FilePath string     `json:"file_path"`
+	Edits    []EditSpan `json:"edits"`
+}
+
+// EditSpan is one edit of a MultiEdit call.
+type EditSpan struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all"`
+}
+
+// GlobInput finds files by name.
+type GlobInput struct {
+	Pattern string `json:"pattern"`
+	Path    string `json:"path"`
+}
+
+// GrepInput searches file contents.
+type GrepInput struct {
+	Pattern    string `json:"pattern"`
+	Path       string `json:"path"`
+	Glob       string `json:"glob"`
+	OutputMode string `json:"output_mode"`
+}
+
+// TaskInput starts a sub-agent.
+type TaskInput struct {
+	Description  string `json:"description"`
+	Prompt       string `json:"prompt"`
+	SubagentType string `json:"subagent_type"`
+}
+
+// TodoWriteInput replaces the agent's todo list.
+type TodoWriteInput struct {
+	Todos []Todo `json:"todos"`
+}
+
+// Todo is one item of a todo list.
+type Todo struct {
+	Content    string `json:"content"`
+	Status     string `json:"status"` // pending, in_progress or completed
+	ActiveForm string `json:"activeForm"`
+}
+
+// WebFetchInput fetches a URL and asks a question about it.
+type WebFetchInput struct {
+	URL    string `json:"url"`
+	Prompt string `json:"prompt"`
+}
+
+// NotebookEditInput edits a Jupyter notebook cell.
+type NotebookEditInput struct {
+	NotebookPath string `json:"notebook_path"`
+	CellID       string `json:"cell_id"`
+	NewSource    string `json:"new_source"`
+	CellType     string `json:"cell_type"`
+	EditMode     string `json:"edit_mode"` // replace, insert or delete
+}
+
+// ApplyPatchInput applies a patch in Codex's "*** Begin Patch" format or
+// as a unified diff.
+type ApplyPatchInput struct {
+	Patch string
+}
+
+func (in *BashInput) Summary() string         { return in.Command }
+func (in *ReadInput) Summary() string         { return in.FilePath }
+func (in *WriteInput) Summary() string        { return in.FilePath }
+func (in *EditInput) Summary() string         { return in.FilePath }
+func (in *MultiEditInput) Summary() string    { return in.FilePath }
+func (in *GlobInput) Summary() string         { return in.Pattern }
+func (in *GrepInput) Summary() string         { return in.Pattern }
+func (in *TaskInput) Summary() string         { return in.Description }
+func (in *TodoWriteInput) Summary() string    { return "" }
+func (in *WebFetchInput) Summary() string     { return in.URL }
+func (in *NotebookEditInput) Summary() string { return in.NotebookPath }
+func (in *ApplyPatchInput) Summary() string   { return strings.Join(in.Files(), ", ") }
+
+// Files returns the paths the patch adds, updates, deletes or moves to, in
+// order of appearance.
+func (in *ApplyPatchInput) Files() []string {
+	var files []string
+	for _, f := range patchFiles(in.Patch) {
+		if f.path != "" && !slices.Contains(files, f.path) {
+			files = append(files, f.path)
+		}
+	}
+	return files
+}
+
+// ToolCall is a tool_use block paired with the tool_result that answers it.
+type ToolCall struct {
+	Use    *ContentBlock
+	Result *ContentBlock // nil if no result was recorded
+	Kind   ToolKind
+
+	// Input is the decoded input of a well-known tool. It is nil for other
+	// tools and for inputs that do not match the tool's parameters.
+	Input ToolInput
+}
+
+// NewToolCall decodes the tool_use block use, without a result.
+func NewToolCall(use *ContentBlock) ToolCall {
+	c := ToolCall{Use: use, Kind: ToolKindOf(use.Name)}
+	c.Input = decodeToolInput(c.Kind, use.Input)
+	return c
+}
+
+// ToolCalls returns the tool calls in messages, in order, each paired with
+// the first tool_result that carries its ToolUseID. Blocks are referenced, not
+// copied.
+func ToolCalls(messages []Message) []ToolCall {
+	var calls []ToolCall
+	pending := make(map[string]int) // ToolUseID → index in calls
+	for i := range messages {
+		for j := range messages[i].Content {
+			b := &messages[i].Content[j]
+			switch b.Type {
+			case BlockToolUse:
+				if b.ToolUseID != "" {
+					pending[b.ToolUseID] = len(calls)
+				}
+				calls = append(calls, NewToolCall(b))
+			case BlockToolResult:
+				if k, ok := pending[b.ToolUseID]; ok {
+					calls[k].Result = b
+					delete(pending, b.ToolUseID)
+				}
+			}
+		}
+	}
+	return calls
+}
+
+// Summary returns the most relevant argument of the call. Inputs of unknown
+// tools are searched for commonly used keys such as "command" or "path".
+func (c ToolCall) Summary() string {
+	if c.Input != nil {
+		return c.Input.Summary()
+	}
+	m, ok := c.Use.Input.(map[string]any)
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"command", "file_path", "filePath", "target_file", "path", "pattern", "query", "url", "description"} {
+		if v, ok := m[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// decodeToolInput decodes input for a tool of kind k, returning nil if k is
+// unknown or input does not fit.
+func decodeToolInput(k ToolKind, input any) ToolInput {
+	raw, ok := input.(map[string]any)
+	if !ok {
+		return nil
+	}
+	input = canonicalInput(raw)
+	var v ToolInput
+	switch k {
+	case ToolBash:
+		return decodeBash(input)
+	case ToolApplyPatch:
+		m := input.(map[string]any)
+		patch, ok := m["input"].(string)
+		if !ok {
+			return nil
+		}
+		return &ApplyPatchInput{Patch: patch}
+	case ToolRead:
+		v = new(ReadInput)
+	case ToolWrite:
+		v = new(WriteInput)
+	case ToolEdit:
+		v = new(EditInput)
+	case ToolMultiEdit:
+		v = new(MultiEditInput)
+	case ToolGlob:
+		v = new(GlobInput)
+	case ToolGrep:
+		v = new(GrepInput)
+	case ToolTask:
+		v = new(TaskInput)
+	case ToolTodoWrite:
+		v = new(TodoWriteInput)
+	case ToolWebFetch:
+		v = new(WebFetchInput)
+	case ToolNotebookEdit:
+		v = new(NotebookEditInput)
+	default:
+		return nil
+	}
+	if !remarshal(input, v) {
+		return nil
+	}
+	if e, ok := v.(*EditInput); ok {
+		_, e.Sketch = raw["code_edit"]
+	}
+	return v
+}
+
+// inputAliases maps the parameter names other agents give their tools to the
+// Claude Code names the ToolInput types decode.
+var inputAliases = map[string]string{
+	// OpenCode
+	"filePath":   "file_path",
+	"oldString":  "old_string",
+	"newString":  "new_string",
+	"replaceAll": "replace_all",
+	"patchText":  "input",
+
+	// Gemini CLI
+	"absolute_path": "file_path",
+	"directory":     "workdir",
+	"include":       "glob",
+
+	// Cursor
+	"target_file":     "file_path",
+	"contents":        "content",
+	"code_edit":       "new_string",
+	"query":           "pattern",
+	"include_pattern": "glob",
+	"explanation":     "description",
+}
+
+// canonicalInput returns a copy of input, and of the edits it holds, with
+// aliased parameters renamed. A parameter that is already present under its
+// canonical name is kept.
+func canonicalInput(input map[string]any) map[string]any {
+	out := make(map[string]any, len(input))
+	for key, v := range input {
+		if edits, ok := v.([]any); ok && key == "edits" {
+			spans := make([]any, len(edits))
+			for i, e := range edits {
+				if m, ok := e.(map[string]any); ok {
+					e = canonicalInput(m)
+				}
+				spans[i] = e
+			}
+			v = spans
+		}
+		if alias, ok := inputAliases[key]; ok {
+			if _, taken := input[alias]; taken {
+				continue
+			}
+			key = alias
+		}
+		out[key] = v
+	}
+	return out
+}
+
+// decodeBash decodes Claude Code's Bash input, whose command is a string, and
+// Codex's shell input, whose command is an argv list such as
+// ["bash", "-lc", "ls"].
+func decodeBash(input any) ToolInput {
+	var raw struct {
+		Command          json.RawMessage `json:"command"`
+		Description      string          `json:"description"`
+		Workdir          string          `json:"workdir"`
+		WorkingDirectory string          `json:"working_directory"` // Codex local_shell_call
+	}
+	if !remarshal(input, &raw) {
+		return nil
+	}
+	in := &BashInput{Description: raw.Description, Workdir: raw.Workdir}
+	if in.Workdir == "" {
+		in.Workdir = raw.WorkingDirectory
+	}
+
+	var argv []string
+	switch {
+	case json.Unmarshal(raw.Command, &in.Command) == nil:
+	case json.Unmarshal(raw.Command, &argv) == nil:
+		in.Command = commandLine(argv)
+	default:
+		return nil
+	}
+	return in
+}
+
+// commandLine returns the script of a shell invocation like
+// ["bash", "-lc", script], or argv joined by spaces otherwise.
+func commandLine(argv []string) string {
+	if len(argv) == 3 && (argv[1] == "-c" || argv[1] == "-lc") {
+		switch argv[0] {
+		case "bash", "sh", "zsh", "/bin/bash", "/bin/sh", "/bin/zsh":
+			return argv[2]
+		}
+	}
+	return strings.Join(argv, " ")
+}
+
+// remarshal converts a decoded JSON value into v through its JSON encoding.
+func remarshal(in, v any) bool {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
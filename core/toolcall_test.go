@@ -0,0 +1,157 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolKindOf(t *testing.T) {
+	assert.Equal(t, ToolBash, ToolKindOf("Bash"))
+	assert.Equal(t, ToolBash, ToolKindOf("shell"))
+	assert.Equal(t, ToolMultiEdit, ToolKindOf("MultiEdit"))
+	assert.Equal(t, ToolNotebookEdit, ToolKindOf("NotebookEdit"))
+	assert.Equal(t, ToolApplyPatch, ToolKindOf("apply_patch"))
+	assert.Equal(t, ToolApplyPatch, ToolKindOf("patch"))
+	assert.Equal(t, ToolWrite, ToolKindOf("write_file"))
+	assert.Equal(t, ToolEdit, ToolKindOf("replace"))
+	assert.Equal(t, ToolBash, ToolKindOf("run_shell_command"))
+	assert.Equal(t, ToolEdit, ToolKindOf("edit_file"))
+	assert.Equal(t, ToolEdit, ToolKindOf("search_replace"))
+	assert.Equal(t, ToolBash, ToolKindOf("run_terminal_cmd"))
+	assert.Equal(t, ToolUnknown, ToolKindOf("WebSearch"))
+}
+
+func TestNewToolCall(t *testing.T) {
+	tests := []struct {
+		name        string
+		block       ContentBlock
+		want        ToolInput
+		wantSummary string
+	}{
+		{
+			name:        "bash",
+			block:       ContentBlock{Name: "Bash", Input: map[string]any{"command": "go test ./...", "description": "Run tests"}},
+			want:        &BashInput{Command: "go test ./...", Description: "Run tests"},
+			wantSummary: "go test ./...",
+		},
+		{
+			name:        "codex shell argv",
+			block:       ContentBlock{Name: "shell", Input: map[string]any{"command": []any{"bash", "-lc", "ls -la"}, "workdir": "/work"}},
+			want:        &BashInput{Command: "ls -la", Workdir: "/work"},
+			wantSummary: "ls -la",
+		},
+		{
+			name:        "opencode edit",
+			block:       ContentBlock{Name: "edit", Input: map[string]any{"filePath": "/w/a.go", "oldString": "a", "newString": "b", "replaceAll": true}},
+			want:        &EditInput{FilePath: "/w/a.go", OldString: "a", NewString: "b", ReplaceAll: true},
+			wantSummary: "/w/a.go",
+		},
+		{
+			name:        "gemini shell",
+			block:       ContentBlock{Name: "run_shell_command", Input: map[string]any{"command": "make", "directory": "/w"}},
+			want:        &BashInput{Command: "make", Workdir: "/w"},
+			wantSummary: "make",
+		},
+		{
+			name:        "cursor edit sketch",
+			block:       ContentBlock{Name: "edit_file", Input: map[string]any{"target_file": "a.go", "instructions": "rename", "code_edit": "// ... existing code ...\nfunc b() {}\n"}},
+			want:        &EditInput{FilePath: "a.go", NewString: "// ... existing code ...\nfunc b() {}\n", Sketch: true},
+			wantSummary: "a.go",
+		},
+		{
+			name:        "codex local shell",
+			block:       ContentBlock{Name: "shell", Input: map[string]any{"type": "exec", "command": []any{"git", "status"}, "working_directory": "/work"}},
+			want:        &BashInput{Command: "git status", Workdir: "/work"},
+			wantSummary: "git status",
+		},
+		{
+			name:        "read",
+			block:       ContentBlock{Name: "Read", Input: map[string]any{"file_path": "/a.go", "offset": 10.0, "limit": 20.0}},
+			want:        &ReadInput{FilePath: "/a.go", Offset: 10, Limit: 20},
+			wantSummary: "/a.go",
+		},
+		{
+			name: "multiedit",
+			block: ContentBlock{Name: "MultiEdit", Input: map[string]any{"file_path": "/a.go", "edits": []any{
+				map[string]any{"old_string": "a", "new_string": "b", "replace_all": true},
+			}}},
+			want:        &MultiEditInput{FilePath: "/a.go", Edits: []EditSpan{{OldString: "a", NewString: "b", ReplaceAll: true}}},
+			wantSummary: "/a.go",
+		},
+		{
+			name: "todowrite",
+			block: ContentBlock{Name: "TodoWrite", Input: map[string]any{"todos": []any{
+				map[string]any{"content": "Write tests", "status": "pending", "activeForm": "Writing tests"},
+			}}},
+			want: &TodoWriteInput{Todos: []Todo{{Content: "Write tests", Status: "pending", ActiveForm: "Writing tests"}}},
+		},
+		{
+			name:        "task",
+			block:       ContentBlock{Name: "Task", Input: map[string]any{"description": "Find auth", "prompt": "Find the auth files", "subagent_type": "Explore"}},
+			want:        &TaskInput{Description: "Find auth", Prompt: "Find the auth files", SubagentType: "Explore"},
+			wantSummary: "Find auth",
+		},
+		{
+			name:        "apply_patch",
+			block:       ContentBlock{Name: "apply_patch", Input: map[string]any{"input": "*** Begin Patch\n*** Update File: main.go\n@@\n-old\n+new\n*** Add File: new.go\n+package main\n*** End Patch"}},
+			want:        &ApplyPatchInput{Patch: "*** Begin Patch\n*** Update File: main.go\n@@\n-old\n+new\n*** Add File: new.go\n+package main\n*** End Patch"},
+			wantSummary: "main.go, new.go",
+		},
+		{
+			name:        "wrong parameter types",
+			block:       ContentBlock{Name: "Read", Input: map[string]any{"file_path": 42.0}},
+			wantSummary: "",
+		},
+		{
+			name:        "unknown tool falls back to common keys",
+			block:       ContentBlock{Name: "WebSearch", Input: map[string]any{"query": "go iterators"}},
+			wantSummary: "go iterators",
+		},
+		{
+			name:  "non-object input",
+			block: ContentBlock{Name: "Bash", Input: "ls"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.block.Type = BlockToolUse
+			c := NewToolCall(&tt.block)
+			assert.Equal(t, tt.want, c.Input)
+			assert.Equal(t, tt.wantSummary, c.Summary())
+		})
+	}
+}
+
+func TestToolCalls(t *testing.T) {
+	messages := []Message{
+		{Role: RoleAssistant, Content: []ContentBlock{
+			{Type: BlockText, Text: "Looking"},
+			{Type: BlockToolUse, ToolUseID: "t1", Name: "Read", Input: map[string]any{"file_path": "/a.go"}},
+			{Type: BlockToolUse, ToolUseID: "t2", Name: "Bash", Input: map[string]any{"command": "ls"}},
+		}},
+		{Role: RoleUser, Content: []ContentBlock{
+			{Type: BlockToolResult, ToolUseID: "t2", Content: "a.go"},
+			{Type: BlockToolResult, ToolUseID: "t9", Content: "orphan"},
+		}},
+		{Role: RoleAssistant, Content: []ContentBlock{
+			{Type: BlockToolUse, ToolUseID: "t3", Name: "Glob", Input: map[string]any{"pattern": "*.go"}},
+		}},
+	}
+
+	calls := ToolCalls(messages)
+	require.Len(t, calls, 3)
+
+	assert.Equal(t, ToolRead, calls[0].Kind)
+	assert.Nil(t, calls[0].Result)
+
+	assert.Equal(t, ToolBash, calls[1].Kind)
+	require.NotNil(t, calls[1].Result)
+	assert.Equal(t, "a.go", calls[1].Result.Content)
+	assert.Same(t, &messages[1].Content[0], calls[1].Result)
+
+	assert.Equal(t, ToolGlob, calls[2].Kind)
+	assert.Same(t, &messages[2].Content[0], calls[2].Use)
+}
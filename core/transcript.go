@@ -3,13 +3,19 @@
 // renderers consume.
 package core
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Transcript is the top-level container for a single session.
 type Transcript struct {
+	SchemaVersion   int        `json:"schema_version,omitempty"` // set on documents written to disk; see SchemaVersion
 	SessionID       string     `json:"session_id"`
 	ParentSessionID string     `json:"parent_session_id,omitempty"`
-	Agent           string     `json:"agent"`                // "claude", "codex", "opencode", "cursor"
+	SessionIDs      []string   `json:"session_ids,omitempty"`    // sessions merged into this one, oldest first
+	ContinuesFrom   string     `json:"continues_from,omitempty"` // UUID of the earlier session's message this session resumes
+	Agent           string     `json:"agent"`                // "claude", "codex", "opencode", "cursor", "gemini", "aider"
 	Author          string     `json:"author,omitempty"`     // git user.name from working directory
 	Model           string     `json:"model,omitempty"`      // primary model used
 	Dir             string     `json:"dir,omitempty"`        // working directory
@@ -17,10 +23,12 @@ type Transcript struct {
 	Title           string     `json:"title,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       *time.Time `json:"updated_at,omitempty"`
-	Usage           *Usage     `json:"usage,omitempty"`      // aggregate session usage
+	Usage           *Usage     `json:"usage,omitempty"`      // aggregate usage of Messages; see TotalUsage
 	DiffStats       *DiffStats    `json:"diff_stats,omitempty"` // aggregate edit statistics
+	Files           []FileChange  `json:"files,omitempty"`      // files read or changed, in order of first use
 	Messages        []Message     `json:"messages"`
 	SubAgents       []*Transcript `json:"sub_agents,omitempty"`
+	Branches        []Branch      `json:"branches,omitempty"` // abandoned lines of conversation
 }
 
 // Usage holds token counters. Used both at session level (aggregate) and per
@@ -30,21 +38,72 @@ type Usage struct {
 	OutputTokens        int `json:"output_tokens,omitempty"`
 	CacheReadTokens     int `json:"cache_read_tokens,omitempty"`
 	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
+
+	// Cost is the cost in USD as reported by the agent, or as estimated from
+	// the token counts by package pricing when CostEstimated is set.
+	Cost          float64 `json:"cost,omitempty"`
+	CostEstimated bool    `json:"cost_estimated,omitempty"`
 }
 
 // DiffStats summarizes file-level edit statistics across the session.
 type DiffStats struct {
-	Added   int `json:"added,omitempty"`   // lines added (Write content + Edit new_string)
-	Removed int `json:"removed,omitempty"` // lines removed (Edit old_string)
+	Added   int `json:"added,omitempty"`   // lines added by file-changing tool calls
+	Removed int `json:"removed,omitempty"` // lines removed by file-changing tool calls
 	Changed int `json:"changed,omitempty"` // unique files touched
 }
 
+// FileChange records what a session did to one file. Turns are numbered from
+// 1 in the order GroupTurns returns them, leaving out event turns.
+type FileChange struct {
+	Path       string     `json:"path"`
+	Operations []ToolKind `json:"operations"` // tools that touched the file, in order of first use
+	Added      int        `json:"added,omitempty"`
+	Removed    int        `json:"removed,omitempty"`
+	FirstTurn  int        `json:"first_turn"`
+	LastTurn   int        `json:"last_turn"`
+	ReadOnly   bool       `json:"read_only,omitempty"` // read but never changed
+}
+
+// RelPath returns the file's path relative to dir when it lies inside dir,
+// and its path as recorded otherwise.
+func (f FileChange) RelPath(dir string) string {
+	if dir == "" {
+		return f.Path
+	}
+	if rel, ok := strings.CutPrefix(f.Path, strings.TrimSuffix(dir, "/")+"/"); ok && rel != "" {
+		return rel
+	}
+	return f.Path
+}
+
 // Add accumulates the counts from other into u.
 func (u *Usage) Add(other Usage) {
 	u.InputTokens += other.InputTokens
 	u.OutputTokens += other.OutputTokens
 	u.CacheReadTokens += other.CacheReadTokens
 	u.CacheCreationTokens += other.CacheCreationTokens
+	u.Cost += other.Cost
+	u.CostEstimated = u.CostEstimated || other.CostEstimated
+}
+
+// TotalUsage returns the usage of t together with that of its sub-agents,
+// recursively, or nil if none of them records usage.
+func (t *Transcript) TotalUsage() *Usage {
+	var total Usage
+	found := t.Usage != nil
+	if found {
+		total.Add(*t.Usage)
+	}
+	for _, sub := range t.SubAgents {
+		if u := sub.TotalUsage(); u != nil {
+			total.Add(*u)
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &total
 }
 
 // Message is a single turn in the conversation.
@@ -58,6 +117,14 @@ type Message struct {
 	Usage      *Usage         `json:"usage,omitempty"`
 }
 
+// Branch is a line of conversation abandoned when the user edited a prompt or
+// rewound the session. Its messages are not part of Transcript.Messages.
+type Branch struct {
+	ID         string    `json:"id"`                    // UUID of the branch's first message
+	ParentUUID string    `json:"parent_uuid,omitempty"` // raw parent of the first message; shared with the prompt that replaced it
+	Messages   []Message `json:"messages"`
+}
+
 // Role enumerates who produced a message.
 type Role string
 
@@ -87,6 +154,37 @@ type ContentBlock struct {
 	Content     string       `json:"content,omitempty"`       // tool output, set for "tool_result"
 	IsError     bool         `json:"is_error,omitempty"`      // set for "tool_result"
 	SubAgentRef *SubAgentRef `json:"sub_agent_ref,omitempty"` // set for "tool_use" Task blocks with sub-agents
+	Image       *Image       `json:"image,omitempty"`         // set for "image"; ToolUseID is set when a tool returned it
+	Event       *Event       `json:"event,omitempty"`         // set for "event"
+}
+
+// Event is something the agent recorded about the session itself rather than
+// a message from either party, such as a context compaction. Event blocks
+// appear in system messages.
+type Event struct {
+	Kind      EventKind `json:"kind"`
+	Trigger   string    `json:"trigger,omitempty"`    // what caused it; "auto" or "manual" for compactions
+	PreTokens int       `json:"pre_tokens,omitempty"` // context tokens before a compaction
+	Summary   string    `json:"summary,omitempty"`    // summary carried into the compacted context
+	SessionID string    `json:"session_id,omitempty"` // session that resumed the work, for resume events
+}
+
+// EventKind enumerates session events.
+type EventKind string
+
+const (
+	EventCompaction EventKind = "compaction"
+	EventResume     EventKind = "resume"
+)
+
+// Image is the payload of an image block: either inline base64 data or a
+// reference to an external file or URL.
+type Image struct {
+	MediaType string `json:"media_type,omitempty"` // e.g. "image/png"
+	Data      string `json:"data,omitempty"`       // base64-encoded bytes
+	URL       string `json:"url,omitempty"`        // external file path or URL, when not inline
+	Width     int    `json:"width,omitempty"`      // pixels, when known
+	Height    int    `json:"height,omitempty"`     // pixels, when known
 }
 
 // TextFormat indicates how a text block should be rendered.
@@ -105,4 +203,6 @@ const (
 	BlockThinking   BlockType = "thinking"
 	BlockToolUse    BlockType = "tool_use"
 	BlockToolResult BlockType = "tool_result"
+	BlockImage      BlockType = "image"
+	BlockEvent      BlockType = "event"
 )
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotalUsage(t *testing.T) {
+	assert.Nil(t, (&Transcript{}).TotalUsage())
+	assert.Nil(t, (&Transcript{SubAgents: []*Transcript{{}}}).TotalUsage())
+
+	nested := &Transcript{Usage: &Usage{OutputTokens: 7, Cost: 0.25, CostEstimated: true}}
+	tr := &Transcript{
+		SubAgents: []*Transcript{
+			{Usage: &Usage{InputTokens: 10, CacheReadTokens: 100}, SubAgents: []*Transcript{nested}},
+			{Usage: &Usage{InputTokens: 5, CacheCreationTokens: 50, Cost: 1}},
+		},
+	}
+	assert.Equal(t, &Usage{
+		InputTokens:         15,
+		OutputTokens:        7,
+		CacheReadTokens:     100,
+		CacheCreationTokens: 50,
+		Cost:                1.25,
+		CostEstimated:       true,
+	}, tr.TotalUsage())
+
+	tr.Usage = &Usage{InputTokens: 1}
+	assert.Equal(t, 16, tr.TotalUsage().InputTokens)
+	assert.Equal(t, &Usage{InputTokens: 1}, tr.Usage, "own usage is unchanged")
+}
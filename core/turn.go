@@ -1,55 +1,121 @@
 package core
 
+import "iter"
+
 // Turn groups a user prompt with all subsequent assistant messages,
 // representing one request-response cycle in the conversation.
 type Turn struct {
 	UserMessage       *Message  // nil if the turn starts with an assistant message
 	AssistantMessages []Message // all assistant messages in this turn
+
+	// Event is set for a turn that is a session event, such as a compaction
+	// boundary. Such turns have no user or assistant messages.
+	Event *Message
 }
 
 // GroupTurns splits a flat message list into turns. A new turn starts at each
 // user message that contains human-authored content (text blocks). User
 // messages that contain only tool_result blocks are folded into the current
-// turn as part of the assistant's work.
+// turn as part of the assistant's work. Event messages form turns of their own.
 func GroupTurns(messages []Message) []Turn {
 	var turns []Turn
-	var current *Turn
+	var b turnBuilder
 
 	for i := range messages {
-		msg := &messages[i]
-		if msg.Role == RoleUser {
-			if isToolResultOnly(msg) {
-				// Tool-result-only user messages are part of the agentic loop,
-				// not a new human turn. Fold into the current turn.
-				if current == nil {
-					current = &Turn{}
-				}
-				current.AssistantMessages = append(current.AssistantMessages, *msg)
-			} else {
-				if current != nil {
-					turns = append(turns, *current)
-				}
-				current = &Turn{UserMessage: msg}
+		turns = append(turns, b.add(&messages[i])...)
+	}
+	if done := b.flush(); done != nil {
+		turns = append(turns, *done)
+	}
+	return turns
+}
+
+// StreamTurns groups a message stream into turns like GroupTurns, yielding
+// each turn once the next one starts. Only one turn is held in memory.
+func StreamTurns(messages MessageSeq) iter.Seq2[Turn, error] {
+	return func(yield func(Turn, error) bool) {
+		var b turnBuilder
+		for m, err := range messages {
+			if err != nil {
+				yield(Turn{}, err)
+				return
 			}
-		} else {
-			if current == nil {
-				current = &Turn{}
+			for _, done := range b.add(&m) {
+				if !yield(done, nil) {
+					return
+				}
 			}
-			current.AssistantMessages = append(current.AssistantMessages, *msg)
+		}
+		if done := b.flush(); done != nil {
+			yield(*done, nil)
 		}
 	}
-	if current != nil {
-		turns = append(turns, *current)
+}
+
+// turnBuilder accumulates messages into the current turn.
+type turnBuilder struct {
+	current *Turn
+}
+
+// add appends msg to the current turn and returns the turns it completes:
+// the previous turn when msg starts a new one, followed by msg's own turn when
+// it is an event.
+func (b *turnBuilder) add(msg *Message) []Turn {
+	if isEvent(msg) {
+		var done []Turn
+		if prev := b.flush(); prev != nil {
+			done = append(done, *prev)
+		}
+		return append(done, Turn{Event: msg})
 	}
-	return turns
+	if IsPrompt(msg) {
+		var done []Turn
+		if prev := b.flush(); prev != nil {
+			done = append(done, *prev)
+		}
+		b.current = &Turn{UserMessage: msg}
+		return done
+	}
+	// Tool-result-only user messages are part of the agentic loop, not a new
+	// human turn. Fold them into the current turn with the assistant messages.
+	if b.current == nil {
+		b.current = &Turn{}
+	}
+	b.current.AssistantMessages = append(b.current.AssistantMessages, *msg)
+	return nil
 }
 
-// isToolResultOnly reports whether a message contains only tool_result blocks.
+// isEvent reports whether msg is a system message carrying a session event.
+func isEvent(msg *Message) bool {
+	if msg.Role != RoleSystem {
+		return false
+	}
+	return EventOf(msg) != nil
+}
+
+// flush returns the turn in progress, if any.
+func (b *turnBuilder) flush() *Turn {
+	done := b.current
+	b.current = nil
+	return done
+}
+
+// IsPrompt reports whether msg is a user prompt, which starts a new turn, as
+// opposed to tool results fed back to the agent or a session event.
+func IsPrompt(msg *Message) bool {
+	return msg.Role == RoleUser && !isToolResultOnly(msg)
+}
+
+// isToolResultOnly reports whether a message contains only tool_result blocks
+// and the images those tools returned.
 func isToolResultOnly(msg *Message) bool {
 	if len(msg.Content) == 0 {
 		return false
 	}
 	for _, b := range msg.Content {
+		if b.Type == BlockImage && b.ToolUseID != "" {
+			continue
+		}
 		if b.Type != BlockToolResult {
 			return false
 		}
@@ -57,6 +123,27 @@ func isToolResultOnly(msg *Message) bool {
 	return true
 }
 
+// Usage returns the total usage of the turn's messages, or nil if none of
+// them records usage.
+func (t Turn) Usage() *Usage {
+	var total Usage
+	found := false
+	if t.UserMessage != nil && t.UserMessage.Usage != nil {
+		total.Add(*t.UserMessage.Usage)
+		found = true
+	}
+	for _, m := range t.AssistantMessages {
+		if m.Usage != nil {
+			total.Add(*m.Usage)
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &total
+}
+
 // SplitContent classifies all content blocks from the turn's assistant
 // messages into steps (intermediate work) and response (final output).
 //
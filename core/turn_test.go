@@ -54,6 +54,47 @@ func TestGroupTurns(t *testing.T) {
 				require.Len(t, turns[0].AssistantMessages, 1)
 			},
 		},
+		{
+			name: "tool result with returned image stays in turn",
+			messages: []Message{
+				{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "look"}}},
+				{Role: RoleAssistant, Content: []ContentBlock{{Type: BlockToolUse, ToolUseID: "t1", Name: "Read"}}},
+				{Role: RoleUser, Content: []ContentBlock{
+					{Type: BlockToolResult, ToolUseID: "t1"},
+					{Type: BlockImage, ToolUseID: "t1", Image: &Image{MediaType: "image/png"}},
+				}},
+			},
+			want: 1,
+			checks: func(t *testing.T, turns []Turn) {
+				require.Len(t, turns[0].AssistantMessages, 2)
+			},
+		},
+		{
+			name: "pasted image starts a turn",
+			messages: []Message{
+				{Role: RoleAssistant, Content: []ContentBlock{{Type: BlockText, Text: "hi"}}},
+				{Role: RoleUser, Content: []ContentBlock{{Type: BlockImage, Image: &Image{MediaType: "image/png"}}}},
+			},
+			want: 2,
+		},
+		{
+			name: "event forms its own turn",
+			messages: []Message{
+				{Role: RoleUser, Content: []ContentBlock{{Type: BlockText, Text: "first"}}},
+				{Role: RoleAssistant, Content: []ContentBlock{{Type: BlockText, Text: "reply1"}}},
+				{Role: RoleSystem, Content: []ContentBlock{{Type: BlockEvent, Event: &Event{Kind: EventCompaction}}}},
+				{Role: RoleAssistant, Content: []ContentBlock{{Type: BlockText, Text: "reply2"}}},
+			},
+			want: 3,
+			checks: func(t *testing.T, turns []Turn) {
+				require.Len(t, turns[0].AssistantMessages, 1)
+				require.NotNil(t, turns[1].Event)
+				assert.Nil(t, turns[1].UserMessage)
+				assert.Empty(t, turns[1].AssistantMessages)
+				assert.Nil(t, turns[2].UserMessage)
+				require.Len(t, turns[2].AssistantMessages, 1)
+			},
+		},
 		{
 			name: "multi turn",
 			messages: []Message{
@@ -235,3 +276,24 @@ func TestStepCount(t *testing.T) {
 	}
 	assert.Equal(t, 2, turn.StepCount())
 }
+
+func TestTurnUsage(t *testing.T) {
+	assert.Nil(t, Turn{AssistantMessages: []Message{{Role: RoleAssistant}}}.Usage())
+
+	turn := Turn{
+		UserMessage: &Message{Role: RoleUser},
+		AssistantMessages: []Message{
+			{Role: RoleAssistant, Usage: &Usage{InputTokens: 10, OutputTokens: 5, Cost: 0.25}},
+			{Role: RoleUser},
+			{Role: RoleAssistant, Usage: &Usage{InputTokens: 3, CacheReadTokens: 100, Cost: 0.5, CostEstimated: true}},
+		},
+	}
+	assert.Equal(t, &Usage{InputTokens: 13, OutputTokens: 5, CacheReadTokens: 100, Cost: 0.75, CostEstimated: true}, turn.Usage())
+}
+
+func TestEventLabel(t *testing.T) {
+	assert.Equal(t, "context compacted (auto, 155,123 tokens)",
+		(&Event{Kind: EventCompaction, Trigger: "auto", PreTokens: 155123}).Label())
+	assert.Equal(t, "context compacted", (&Event{Kind: EventCompaction}).Label())
+	assert.Equal(t, "event", (*Event)(nil).Label())
+}
@@ -1,7 +1,7 @@
 // Package install sets up git infrastructure for storing agent session
 // transcripts alongside a repository. It creates an orphan branch, a git
-// worktree, Claude Code hooks for transcript capture, and a git post-commit
-// hook for automatic commits.
+// worktree, Claude Code hooks (or an OpenCode plugin) for transcript capture,
+// and a git post-commit hook for automatic commits.
 package install
 
 import (
@@ -15,7 +15,7 @@ import (
 
 // Config holds the settings for the install command.
 type Config struct {
-	Agent   string   // agent name, e.g. "claude"
+	Agent   string   // agent name, e.g. "claude" or "opencode"
 	Formats []string // output formats, e.g. ["html", "json"]
 	Branch  string   // orphan branch name; empty means simple directory mode
 	OutDir  string   // output directory name relative to repo root, e.g. ".transcripts"
@@ -58,8 +58,15 @@ func Run(cfg Config) error {
 		return fmt.Errorf("update .gitignore: %w", err)
 	}
 
-	if err := installClaudeHook(cfg.Dir, cfg.Agent, cfg.Formats, cfg.OutDir); err != nil {
-		return fmt.Errorf("install Claude Code hook: %w", err)
+	switch cfg.Agent {
+	case "opencode":
+		if err := installOpenCodePlugin(cfg.Dir, cfg.Formats, cfg.OutDir); err != nil {
+			return fmt.Errorf("install OpenCode plugin: %w", err)
+		}
+	default:
+		if err := installClaudeHook(cfg.Dir, cfg.Agent, cfg.Formats, cfg.OutDir); err != nil {
+			return fmt.Errorf("install Claude Code hook: %w", err)
+		}
 	}
 
 	if branchMode {
@@ -240,6 +247,21 @@ func installClaudeHook(repoDir, agent string, formats []string, outDir string) e
 	return os.WriteFile(settingsPath, append(out, '\n'), 0o644)
 }
 
+// installOpenCodePlugin writes a project plugin to .opencode/plugin/ that
+// renders the session transcript via `cg render` whenever a session goes idle.
+// OpenCode has no session-end event; re-rendering on idle keeps the transcript
+// current and the manifest upsert is idempotent.
+func installOpenCodePlugin(repoDir string, formats []string, outDir string) error {
+	pluginDir := filepath.Join(repoDir, ".opencode", "plugin")
+	if err := os.MkdirAll(pluginDir, 0o755); err != nil {
+		return err
+	}
+
+	pluginPath := filepath.Join(pluginDir, "save-transcript.js")
+	plugin := buildOpenCodePlugin(formats, outDir)
+	return os.WriteFile(pluginPath, []byte(plugin), 0o644)
+}
+
 // installPostCommitHook installs or appends to the post-commit hook to
 // auto-commit transcript files in the worktree when the user commits.
 // Uses git rev-parse --git-common-dir to find the correct hooks directory,
@@ -372,6 +394,45 @@ cg manifest upsert --agent %s --file "$TRANSCRIPT_PATH" \
 `, outDir, agent, formatFlags, agent, outDir, hrefExt)
 }
 
+// buildOpenCodePlugin generates the OpenCode plugin with the formats baked in.
+// Child sessions spawned by the task tool are skipped; they are rendered as
+// sub-agents of their parent.
+func buildOpenCodePlugin(formats []string, outDir string) string {
+	var formatFlags string
+	for _, f := range formats {
+		formatFlags += " --format " + f
+	}
+
+	hrefExt := formatExtension(formats[0])
+	for _, f := range formats {
+		if f == "html" {
+			hrefExt = ".html"
+			break
+		}
+	}
+
+	return fmt.Sprintf(`// Installed by cg install — renders OpenCode session transcripts to %s/
+export const SaveTranscript = async ({ $, client, directory }) => {
+  const dest = `+"`${directory}/%s`"+`
+
+  return {
+    event: async ({ event }) => {
+      if (event.type !== "session.idle") return
+      const id = event.properties.sessionID
+      if (!id) return
+
+      const session = await client.session.get({ path: { id } }).catch(() => null)
+      if (session?.data?.parentID) return
+
+      await $`+"`mkdir -p ${dest}`"+`.quiet().nothrow()
+      await $`+"`cg render --agent opencode --session ${id}%s --out ${dest}/${id}`"+`.quiet().nothrow()
+      await $`+"`cg manifest upsert --agent opencode --session ${id} --manifest ${dest}/manifest.json --href ${id}/index%s`"+`.quiet().nothrow()
+    },
+  }
+}
+`, outDir, outDir, formatFlags, hrefExt)
+}
+
 func buildPostCommitHookScript(outDir string) string {
 	return fmt.Sprintf(`
 # cg-transcripts-start
@@ -323,6 +323,30 @@ func TestInstallClaudeHook(t *testing.T) {
 	})
 }
 
+func TestInstallOpenCodePlugin(t *testing.T) {
+	dir := initRepo(t)
+
+	require.NoError(t, Run(Config{
+		Agent:   "opencode",
+		Formats: []string{"json", "html"},
+		OutDir:  ".transcripts",
+		Dir:     dir,
+	}))
+
+	plugin, err := os.ReadFile(filepath.Join(dir, ".opencode", "plugin", "save-transcript.js"))
+	require.NoError(t, err)
+	assert.Contains(t, string(plugin), "session.idle")
+	assert.Contains(t, string(plugin), "cg render --agent opencode --session ${id} --format json --format html")
+	assert.Contains(t, string(plugin), "cg manifest upsert --agent opencode --session ${id}")
+	assert.Contains(t, string(plugin), "--href ${id}/index.html")
+	assert.Contains(t, string(plugin), "`${directory}/.transcripts`")
+
+	t.Run("no claude hook", func(t *testing.T) {
+		_, err := os.Stat(filepath.Join(dir, ".claude", "hooks", "save-transcript.sh"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
+
 func TestBuildSaveTranscriptScript(t *testing.T) {
 	t.Run("single format", func(t *testing.T) {
 		script := buildSaveTranscriptScript("claude", []string{"jsonl"}, ".transcripts")
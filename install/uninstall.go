@@ -40,6 +40,10 @@ func Uninstall(cfg UninstallConfig) error {
 		return fmt.Errorf("remove Claude Code hook: %w", err)
 	}
 
+	if err := removeOpenCodePlugin(cfg.Dir); err != nil {
+		return fmt.Errorf("remove OpenCode plugin: %w", err)
+	}
+
 	if err := removePostCommitHook(cfg.Dir); err != nil {
 		return fmt.Errorf("remove post-commit hook: %w", err)
 	}
@@ -170,6 +174,16 @@ func removeClaudeHook(repoDir string) error {
 	return os.WriteFile(settingsPath, append(out, '\n'), 0o644)
 }
 
+// removeOpenCodePlugin removes the save-transcript.js plugin written for
+// OpenCode, if present.
+func removeOpenCodePlugin(repoDir string) error {
+	pluginPath := filepath.Join(repoDir, ".opencode", "plugin", "save-transcript.js")
+	if err := os.Remove(pluginPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // removePostCommitHook strips the cg-transcripts block from the post-commit hook.
 // If the remaining file is empty or just a shebang, the file is deleted.
 func removePostCommitHook(repoDir string) error {
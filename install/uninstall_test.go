@@ -237,6 +237,22 @@ func TestRemoveClaudeHook(t *testing.T) {
 	})
 }
 
+func TestRemoveOpenCodePlugin(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, installOpenCodePlugin(dir, []string{"html"}, ".transcripts"))
+
+	pluginPath := filepath.Join(dir, ".opencode", "plugin", "save-transcript.js")
+	_, err := os.Stat(pluginPath)
+	require.NoError(t, err)
+
+	require.NoError(t, removeOpenCodePlugin(dir))
+	_, err = os.Stat(pluginPath)
+	assert.True(t, os.IsNotExist(err))
+
+	// Idempotent when the plugin is already gone.
+	require.NoError(t, removeOpenCodePlugin(dir))
+}
+
 func TestRemoveGitignoreEntry(t *testing.T) {
 	t.Run("preserves other entries", func(t *testing.T) {
 		dir := t.TempDir()
@@ -9,11 +9,13 @@ import (
 	"sort"
 
 	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/core/migrate"
 )
 
 // Manifest holds the list of session metadata entries.
 type Manifest struct {
-	Entries []core.ManifestEntry `json:"entries"`
+	SchemaVersion int                  `json:"schema_version"`
+	Entries       []core.ManifestEntry `json:"entries"`
 }
 
 // ReadFile reads a manifest from disk. Returns an empty Manifest if the file
@@ -28,26 +30,50 @@ func ReadFile(path string) (*Manifest, error) {
 	}
 
 	var m Manifest
-	if err := json.Unmarshal(data, &m); err != nil {
+	if err := migrate.Manifest(data, &m); err != nil {
 		return nil, err
 	}
 	return &m, nil
 }
 
-// Upsert adds or replaces an entry matched by SessionID. After upserting, the
-// entries are sorted newest-first by CreatedAt.
+// Upsert adds or replaces an entry matched by SessionID. An entry for merged
+// sessions replaces the entries of every session it covers, so resumed
+// sessions are grouped into one. After upserting, the entries are sorted
+// newest-first by CreatedAt.
 func (m *Manifest) Upsert(entry core.ManifestEntry) {
-	for i, e := range m.Entries {
-		if e.SessionID == entry.SessionID {
-			m.Entries[i] = entry
-			m.sort()
-			return
+	covered := sessionSet(entry)
+	kept := m.Entries[:0]
+	for _, e := range m.Entries {
+		if !overlaps(covered, e) {
+			kept = append(kept, e)
 		}
 	}
-	m.Entries = append(m.Entries, entry)
+	m.Entries = append(kept, entry)
 	m.sort()
 }
 
+// sessionSet returns the IDs of every session e covers.
+func sessionSet(e core.ManifestEntry) map[string]bool {
+	set := map[string]bool{e.SessionID: true}
+	for _, id := range e.SessionIDs {
+		set[id] = true
+	}
+	return set
+}
+
+// overlaps reports whether e covers any session in set.
+func overlaps(set map[string]bool, e core.ManifestEntry) bool {
+	if set[e.SessionID] {
+		return true
+	}
+	for _, id := range e.SessionIDs {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Manifest) sort() {
 	sort.Slice(m.Entries, func(i, j int) bool {
 		return m.Entries[i].CreatedAt.After(m.Entries[j].CreatedAt)
@@ -57,6 +83,7 @@ func (m *Manifest) sort() {
 // WriteFile writes the manifest to disk atomically using a temporary file and
 // rename, which is safe against concurrent writers.
 func (m *Manifest) WriteFile(path string) error {
+	m.SchemaVersion = core.SchemaVersion
 	data, err := json.MarshalIndent(m, "", "  ")
 	if err != nil {
 		return err
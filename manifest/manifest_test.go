@@ -42,6 +42,7 @@ func TestRoundTrip(t *testing.T) {
 
 	got, err := ReadFile(path)
 	require.NoError(t, err)
+	assert.Equal(t, core.SchemaVersion, got.SchemaVersion)
 	require.Len(t, got.Entries, 1)
 	assert.Equal(t, "abc", got.Entries[0].SessionID)
 	assert.Equal(t, 5000, got.Entries[0].Usage.InputTokens)
@@ -49,6 +50,18 @@ func TestRoundTrip(t *testing.T) {
 	assert.Equal(t, 8, got.Entries[0].MessageCount)
 }
 
+func TestReadFileUnversioned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	data := `{"entries": [{"session_id": "abc", "agent": "claude", "created_at": "2026-02-15T10:00:00Z", "href": "claude/abc/index.html"}]}`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	got, err := ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, core.SchemaVersion, got.SchemaVersion)
+	require.Len(t, got.Entries, 1)
+	assert.Equal(t, "abc", got.Entries[0].SessionID)
+}
+
 func TestUpsertAppend(t *testing.T) {
 	now := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
 	m := &Manifest{}
@@ -84,6 +97,28 @@ func TestUpsertReplace(t *testing.T) {
 	assert.True(t, found, "entry 'a' should exist")
 }
 
+func TestUpsertGroupsMergedSessions(t *testing.T) {
+	now := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	m := &Manifest{}
+
+	m.Upsert(entry("a", now))
+	m.Upsert(entry("b", now.Add(time.Hour)))
+	m.Upsert(entry("c", now.Add(2*time.Hour)))
+
+	merged := entry("a", now)
+	merged.SessionIDs = []string{"a", "b"}
+	m.Upsert(merged)
+
+	require.Len(t, m.Entries, 2)
+	assert.Equal(t, "c", m.Entries[0].SessionID)
+	assert.Equal(t, []string{"a", "b"}, m.Entries[1].SessionIDs)
+
+	// A later merge covering more sessions replaces the grouped entry.
+	merged.SessionIDs = []string{"a", "b", "c"}
+	m.Upsert(merged)
+	require.Len(t, m.Entries, 1)
+}
+
 func TestUpsertSortsNewestFirst(t *testing.T) {
 	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
 	t1 := t0.Add(time.Hour)
@@ -140,8 +175,13 @@ func TestNewManifestEntry(t *testing.T) {
 		CreatedAt: now,
 		UpdatedAt: &later,
 		Usage:     &core.Usage{InputTokens: 1000, OutputTokens: 500},
+		Dir:       "/home/ravi/app",
 		DiffStats: &core.DiffStats{Added: 5, Removed: 2, Changed: 1},
-		Messages:  make([]core.Message, 3),
+		Files: []core.FileChange{
+			{Path: "/home/ravi/app/auth.go", Operations: []core.ToolKind{core.ToolEdit}, Added: 5, Removed: 2, FirstTurn: 1, LastTurn: 1},
+			{Path: "/home/ravi/app/go.mod", Operations: []core.ToolKind{core.ToolRead}, FirstTurn: 1, LastTurn: 1, ReadOnly: true},
+		},
+		Messages: make([]core.Message, 3),
 	}
 
 	e := core.NewManifestEntry(tr, "claude/sess-1/index.html")
@@ -154,6 +194,27 @@ func TestNewManifestEntry(t *testing.T) {
 	assert.Equal(t, &later, e.UpdatedAt)
 	assert.Equal(t, 1000, e.Usage.InputTokens)
 	assert.Equal(t, 5, e.DiffStats.Added)
+	assert.Equal(t, []string{"auth.go"}, e.Files)
 	assert.Equal(t, 3, e.MessageCount)
 	assert.Equal(t, "claude/sess-1/index.html", e.Href)
+	assert.Nil(t, e.TotalUsage)
+}
+
+func TestNewManifestEntryTotalUsage(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "sess-1",
+		Usage:     &core.Usage{InputTokens: 1000, Cost: 0.5},
+		SubAgents: []*core.Transcript{
+			{SessionID: "a1", Usage: &core.Usage{InputTokens: 4000, Cost: 2}},
+			{SessionID: "a2"},
+		},
+	}
+
+	e := core.NewManifestEntry(tr, "sess-1/index.html")
+	assert.Equal(t, &core.Usage{InputTokens: 1000, Cost: 0.5}, e.Usage)
+	assert.Equal(t, &core.Usage{InputTokens: 5000, Cost: 2.5}, e.TotalUsage)
+
+	// Sub-agents without usage add nothing.
+	tr.SubAgents = tr.SubAgents[1:]
+	assert.Nil(t, core.NewManifestEntry(tr, "sess-1/index.html").TotalUsage)
 }
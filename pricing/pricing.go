@@ -0,0 +1,232 @@
+// Package pricing estimates what sessions cost from their token usage, using
+// per-model prices for input, output and prompt cache tokens.
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sonnes/chitragupt/core"
+)
+
+// Price is what a model charges, in USD per million tokens.
+type Price struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read"`
+	CacheWrite float64 `json:"cache_write"`
+}
+
+// Cost returns the cost of u in USD.
+func (p Price) Cost(u core.Usage) float64 {
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheReadTokens)*p.CacheRead +
+		float64(u.CacheCreationTokens)*p.CacheWrite) / 1e6
+}
+
+// Table maps model names to prices. A key prices the model of that name and
+// the dated or suffixed variants it is a prefix of, so "claude-sonnet-4"
+// also prices "claude-sonnet-4-20250514". The longest matching key wins.
+type Table map[string]Price
+
+// Lookup returns the price of model. Names are matched case-insensitively,
+// ignoring a provider prefix such as "anthropic/".
+func (t Table) Lookup(model string) (Price, bool) {
+	model = strings.ToLower(model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	if _, rest, ok := strings.Cut(model, "anthropic."); ok {
+		model = rest // Bedrock model IDs such as "us.anthropic.claude-…"
+	}
+	if model == "" {
+		return Price{}, false
+	}
+	if p, ok := t[model]; ok {
+		return p, true
+	}
+	var best string
+	for key := range t {
+		if len(key) > len(best) && strings.HasPrefix(model, strings.ToLower(key)) {
+			best = key
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t[best], true
+}
+
+// defaults holds list prices at the time of writing. Prices change; override
+// them with a pricing file rather than relying on these for billing.
+var defaults = Table{
+	// Anthropic
+	"claude-opus-4":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheRead: 0.5, CacheWrite: 6.25},
+	"claude-opus-4-6":   {Input: 5, Output: 25, CacheRead: 0.5, CacheWrite: 6.25},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheRead: 0.1, CacheWrite: 1.25},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	"claude-3-opus":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheRead: 0.03, CacheWrite: 0.3},
+	"claude-4-opus":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75}, // Cursor
+	"claude-4-sonnet":   {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},   // Cursor
+
+	// OpenAI
+	"gpt-5":        {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5-mini":   {Input: 0.25, Output: 2, CacheRead: 0.025},
+	"gpt-5-nano":   {Input: 0.05, Output: 0.4, CacheRead: 0.005},
+	"gpt-4.1":      {Input: 2, Output: 8, CacheRead: 0.5},
+	"gpt-4.1-mini": {Input: 0.4, Output: 1.6, CacheRead: 0.1},
+	"gpt-4.1-nano": {Input: 0.1, Output: 0.4, CacheRead: 0.025},
+	"gpt-4o":       {Input: 2.5, Output: 10, CacheRead: 1.25},
+	"gpt-4o-mini":  {Input: 0.15, Output: 0.6, CacheRead: 0.075},
+	"o3":           {Input: 2, Output: 8, CacheRead: 0.5},
+	"o3-mini":      {Input: 1.1, Output: 4.4, CacheRead: 0.55},
+	"o4-mini":      {Input: 1.1, Output: 4.4, CacheRead: 0.275},
+
+	// Google
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10, CacheRead: 0.31},
+	"gemini-2.5-flash":      {Input: 0.3, Output: 2.5, CacheRead: 0.075},
+	"gemini-2.5-flash-lite": {Input: 0.1, Output: 0.4, CacheRead: 0.025},
+	"gemini-2.0-flash":      {Input: 0.1, Output: 0.4, CacheRead: 0.025},
+}
+
+// Default returns a copy of the built-in price table.
+func Default() Table {
+	return maps.Clone(defaults)
+}
+
+// DefaultPath returns the pricing file read when none is given:
+// cg/pricing.json in the user's configuration directory.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cg", "pricing.json")
+}
+
+// LoadFile returns the built-in table with the prices in the JSON file at
+// path added or replacing those of the same model. The file maps model names
+// to prices:
+//
+//	{"claude-sonnet-4": {"input": 3, "output": 15, "cache_read": 0.3, "cache_write": 3.75}}
+func LoadFile(path string) (Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pricing file: %w", err)
+	}
+	var overrides Table
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parse pricing file %s: %w", path, err)
+	}
+	t := Default()
+	for model, p := range overrides {
+		t[strings.ToLower(model)] = p
+	}
+	return t, nil
+}
+
+// Estimator is a core.Transformer that fills in the cost of messages and
+// transcripts whose agent did not report one.
+type Estimator struct {
+	table Table
+}
+
+// New creates an Estimator that prices usage with table.
+func New(table Table) *Estimator {
+	return &Estimator{table: table}
+}
+
+// Transform implements core.Transformer. Each message with usage and no
+// reported cost is priced by its model, or the transcript's when it has
+// none. A transcript's cost is the sum of its messages' costs; one without
+// messages, such as a streamed header, is priced from its aggregate usage
+// and primary model. Sub-agents are priced the same way. Costs already set
+// are kept, so applying an Estimator twice changes nothing.
+func (e *Estimator) Transform(t *core.Transcript) error {
+	var total float64
+	priced, estimated := false, false
+	for i := range t.Messages {
+		m := &t.Messages[i]
+		e.priceMessage(m, t.Model)
+		if m.Usage != nil {
+			total += m.Usage.Cost
+			priced = true
+			estimated = estimated || m.Usage.CostEstimated
+		}
+	}
+	for _, b := range t.Branches {
+		for i := range b.Messages {
+			e.priceMessage(&b.Messages[i], t.Model)
+		}
+	}
+
+	if u := t.Usage; u != nil && u.Cost == 0 {
+		if priced {
+			u.Cost, u.CostEstimated = total, estimated
+		} else if p, ok := e.table.Lookup(t.Model); ok {
+			u.Cost = p.Cost(*u)
+			u.CostEstimated = u.Cost > 0
+		}
+	}
+
+	for _, sub := range t.SubAgents {
+		if err := e.Transform(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PriceStream prices the messages of a stream the way Transform prices those
+// of a transcript, using header's model for messages without one. Once the
+// stream has been read to the end, header's cost is set from its messages if
+// it has none. Apply Transform to header afterwards to price its branches
+// and sub-agents.
+func (e *Estimator) PriceStream(header *core.Transcript, messages core.MessageSeq) core.MessageSeq {
+	return func(yield func(core.Message, error) bool) {
+		var total float64
+		priced, estimated := false, false
+		for m, err := range messages {
+			if err != nil {
+				yield(core.Message{}, err)
+				return
+			}
+			e.priceMessage(&m, header.Model)
+			if m.Usage != nil {
+				total += m.Usage.Cost
+				priced = true
+				estimated = estimated || m.Usage.CostEstimated
+			}
+			if !yield(m, nil) {
+				return
+			}
+		}
+		if u := header.Usage; u != nil && u.Cost == 0 && priced {
+			u.Cost, u.CostEstimated = total, estimated
+		}
+	}
+}
+
+// priceMessage estimates the cost of m if it has usage but no cost.
+func (e *Estimator) priceMessage(m *core.Message, model string) {
+	if m.Usage == nil || m.Usage.Cost != 0 {
+		return
+	}
+	if m.Model != "" {
+		model = m.Model
+	}
+	if p, ok := e.table.Lookup(model); ok {
+		m.Usage.Cost = p.Cost(*m.Usage)
+		m.Usage.CostEstimated = m.Usage.Cost > 0
+	}
+}
@@ -0,0 +1,159 @@
+package pricing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	table := Default()
+
+	tests := []struct {
+		model string
+		want  Price
+		ok    bool
+	}{
+		{"claude-sonnet-4", table["claude-sonnet-4"], true},
+		{"claude-sonnet-4-5-20250929", table["claude-sonnet-4"], true},
+		{"claude-opus-4-6", table["claude-opus-4-6"], true},
+		{"claude-opus-4-1-20250805", table["claude-opus-4"], true},
+		{"Claude-Haiku-4-5", table["claude-haiku-4-5"], true},
+		{"anthropic/claude-haiku-4-5", table["claude-haiku-4-5"], true},
+		{"us.anthropic.claude-sonnet-4-20250514-v1:0", table["claude-sonnet-4"], true},
+		{"gpt-5-codex", table["gpt-5"], true},
+		{"gpt-5-mini-2025-08-07", table["gpt-5-mini"], true},
+		{"gpt-4o-mini", table["gpt-4o-mini"], true},
+		{"llama-3", Price{}, false},
+		{"", Price{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, ok := table.Lookup(tt.model)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPriceCost(t *testing.T) {
+	p := Price{Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75}
+	u := core.Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheReadTokens: 2_000_000, CacheCreationTokens: 400_000}
+	assert.InDelta(t, 3+1.5+0.6+1.5, p.Cost(u), 1e-9)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"Claude-Sonnet-4": {"input": 2, "output": 10},
+		"in-house-model": {"input": 1, "output": 1}
+	}`), 0o644))
+
+	table, err := LoadFile(path)
+	require.NoError(t, err)
+
+	p, ok := table.Lookup("claude-sonnet-4-5")
+	require.True(t, ok)
+	assert.Equal(t, Price{Input: 2, Output: 10}, p)
+	_, ok = table.Lookup("in-house-model-v2")
+	assert.True(t, ok)
+	assert.Equal(t, defaults["gpt-5"], table["gpt-5"])
+
+	_, err = LoadFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0o644))
+	_, err = LoadFile(path)
+	assert.ErrorContains(t, err, "parse pricing file")
+}
+
+func usageMessage(model string, u core.Usage) core.Message {
+	return core.Message{Role: core.RoleAssistant, Model: model, Usage: &u}
+}
+
+func TestEstimatorTransform(t *testing.T) {
+	table := Table{
+		"big":   {Input: 10, Output: 20},
+		"small": {Input: 1, Output: 2},
+	}
+	sub := &core.Transcript{
+		SessionID: "sub",
+		Model:     "small",
+		Usage:     &core.Usage{InputTokens: 1_000_000},
+		Messages:  []core.Message{usageMessage("", core.Usage{InputTokens: 1_000_000})},
+	}
+	tr := &core.Transcript{
+		Model: "big",
+		Usage: &core.Usage{InputTokens: 3_000_000, OutputTokens: 1_000_000},
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "hi"}}},
+			usageMessage("big", core.Usage{InputTokens: 1_000_000}),
+			usageMessage("small", core.Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000}),
+			usageMessage("unknown", core.Usage{InputTokens: 1_000_000}),
+		},
+		Branches:  []core.Branch{{Messages: []core.Message{usageMessage("", core.Usage{OutputTokens: 1_000_000})}}},
+		SubAgents: []*core.Transcript{sub},
+	}
+
+	require.NoError(t, New(table).Transform(tr))
+
+	assert.Nil(t, tr.Messages[0].Usage)
+	assert.Equal(t, &core.Usage{InputTokens: 1_000_000, Cost: 10, CostEstimated: true}, tr.Messages[1].Usage)
+	assert.InDelta(t, 3, tr.Messages[2].Usage.Cost, 1e-9)
+	assert.Zero(t, tr.Messages[3].Usage.Cost)
+	assert.False(t, tr.Messages[3].Usage.CostEstimated)
+	assert.InDelta(t, 20, tr.Branches[0].Messages[0].Usage.Cost, 1e-9)
+
+	assert.InDelta(t, 13, tr.Usage.Cost, 1e-9)
+	assert.True(t, tr.Usage.CostEstimated)
+	assert.InDelta(t, 1, sub.Usage.Cost, 1e-9)
+	assert.InDelta(t, 1, sub.Messages[0].Usage.Cost, 1e-9)
+
+	// Applying it again changes nothing.
+	before := *tr.Usage
+	require.NoError(t, New(table).Transform(tr))
+	assert.Equal(t, before, *tr.Usage)
+}
+
+func TestEstimatorKeepsReportedCost(t *testing.T) {
+	tr := &core.Transcript{
+		Model:    "big",
+		Usage:    &core.Usage{InputTokens: 1_000_000, Cost: 0.5},
+		Messages: []core.Message{usageMessage("big", core.Usage{InputTokens: 1_000_000, Cost: 0.5})},
+	}
+	require.NoError(t, New(Table{"big": {Input: 10}}).Transform(tr))
+	assert.Equal(t, &core.Usage{InputTokens: 1_000_000, Cost: 0.5}, tr.Usage)
+	assert.Equal(t, &core.Usage{InputTokens: 1_000_000, Cost: 0.5}, tr.Messages[0].Usage)
+}
+
+func TestEstimatorHeaderOnly(t *testing.T) {
+	tr := &core.Transcript{Model: "big", Usage: &core.Usage{InputTokens: 1_000_000}}
+	require.NoError(t, New(Table{"big": {Input: 10}}).Transform(tr))
+	assert.Equal(t, &core.Usage{InputTokens: 1_000_000, Cost: 10, CostEstimated: true}, tr.Usage)
+}
+
+func TestPriceStreamMatchesTransform(t *testing.T) {
+	table := Table{"big": {Input: 10, Output: 20}}
+	messages := func() []core.Message {
+		return []core.Message{
+			usageMessage("", core.Usage{InputTokens: 123_456}),
+			usageMessage("big", core.Usage{InputTokens: 7, OutputTokens: 654_321}),
+		}
+	}
+
+	want := &core.Transcript{Model: "big", Usage: &core.Usage{}, Messages: messages()}
+	require.NoError(t, New(table).Transform(want))
+
+	header := &core.Transcript{Model: "big", Usage: &core.Usage{}}
+	var got []core.Message
+	for m, err := range New(table).PriceStream(header, core.Messages(messages())) {
+		require.NoError(t, err)
+		got = append(got, m)
+	}
+	assert.Equal(t, want.Messages, got)
+	assert.Equal(t, want.Usage, header.Usage)
+}
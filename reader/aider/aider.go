@@ -0,0 +1,633 @@
+// Package aider reads aider chat logs (.aider.chat.history.md and
+// .aider.input.history in a repository root).
+//
+// The chat history is a Markdown log appended to by every aider run:
+//
+//	# aider chat started at 2026-01-01 09:00:00
+//
+//	> Model: gpt-4o with diff edit format
+//
+//	#### add a hello function
+//
+//	hello.py
+//	```python
+//	<<<<<<< SEARCH
+//	=======
+//	def hello(): ...
+//	>>>>>>> REPLACE
+//	```
+//
+//	> Applied edit to hello.py
+//	> Tokens: 1.2k sent, 300 received. Cost: $0.01 message, $0.02 session.
+//
+// "#### " lines are user prompts, "> " lines are aider's own output, and
+// everything else is the model's reply. Each "aider chat started" header
+// begins a new session. The input history records a timestamp for every
+// prompt and is used to date the messages.
+package aider
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+)
+
+// Reader reads aider chat history files.
+type Reader struct {
+	// Dir, when set, is searched recursively for history files. By default
+	// only the chat history in the current directory is read.
+	Dir string
+
+	// Report, when set, collects warnings about skipped sessions and lines
+	// that are not part of any session or prompt.
+	Report *reader.Report
+}
+
+const (
+	chatHistoryFile  = ".aider.chat.history.md"
+	inputHistoryFile = ".aider.input.history"
+)
+
+// timeLayout is the timestamp format of session headers.
+const timeLayout = "2006-01-02 15:04:05"
+
+// sessionIDLayout formats a session start time as its ID.
+const sessionIDLayout = "2006-01-02T15-04-05"
+
+var (
+	tokensRe = regexp.MustCompile(`([\d.]+[kKmM]?) (sent|received|cache write|cache hit)`)
+	costRe   = regexp.MustCompile(`Cost: \$([\d.]+) message`)
+)
+
+// session is one "aider chat started" section of a chat history file.
+type session struct {
+	start time.Time
+	lines []string
+}
+
+// prompt is one entry of the input history.
+type prompt struct {
+	time time.Time
+	text string
+}
+
+// ReadFile parses a history file. For a chat history holding several
+// sessions, the most recent session is returned; use ReadProject to get all
+// of them. An input history on its own yields a transcript of prompts only.
+func (r *Reader) ReadFile(path string) (*core.Transcript, error) {
+	if filepath.Base(path) == inputHistoryFile {
+		return readInputHistory(path, r.Report)
+	}
+
+	transcripts, err := readChatHistory(path, r.Report)
+	if err != nil {
+		return nil, err
+	}
+	if len(transcripts) == 0 {
+		return nil, fmt.Errorf("no sessions found in %s", path)
+	}
+	return transcripts[len(transcripts)-1], nil
+}
+
+// ReadSession locates a session by its ID (the session start time, formatted
+// as 2006-01-02T15-04-05) among the history files under Dir.
+func (r *Reader) ReadSession(sessionID string) (*core.Transcript, error) {
+	files, err := r.historyFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range files {
+		transcripts, err := readChatHistory(path, r.Report)
+		if err != nil {
+			continue
+		}
+		for _, t := range transcripts {
+			if t.SessionID == sessionID {
+				return t, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("session %s not found", sessionID)
+}
+
+// ReadProject returns all sessions recorded in a repository. The project is
+// the repository path, either verbatim or in Claude's dash-encoded form
+// ("/Users/foo/bar" → "-Users-foo-bar").
+func (r *Reader) ReadProject(project string) ([]*core.Transcript, error) {
+	for _, dir := range []string{project, strings.ReplaceAll(project, "-", "/")} {
+		path := filepath.Join(dir, chatHistoryFile)
+		if _, err := os.Stat(path); err == nil {
+			return readChatHistory(path, r.Report)
+		}
+	}
+
+	files, err := r.historyFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			continue
+		}
+		if dir == project || strings.ReplaceAll(dir, "/", "-") == project {
+			return readChatHistory(path, r.Report)
+		}
+	}
+	return nil, nil
+}
+
+// ReadAll returns every session from every chat history under Dir, or from
+// the current directory's chat history when Dir is unset.
+func (r *Reader) ReadAll() ([]*core.Transcript, error) {
+	files, err := r.historyFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*core.Transcript
+	for _, path := range files {
+		transcripts, err := readChatHistory(path, r.Report)
+		if err != nil {
+			r.Report.SkippedFile(path, err)
+			continue
+		}
+		all = append(all, transcripts...)
+	}
+	return all, nil
+}
+
+// Sniff reports whether path is an aider history file or a directory
+// containing a chat history.
+func (r *Reader) Sniff(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		_, err := os.Stat(filepath.Join(path, chatHistoryFile))
+		return err == nil
+	}
+	name := filepath.Base(path)
+	return name == chatHistoryFile || name == inputHistoryFile
+}
+
+// historyFiles walks Dir for chat history files, skipping hidden directories
+// and dependency trees. Without a Dir it only checks the current directory,
+// so agent-wide scans do not walk whatever tree cg happens to run in.
+func (r *Reader) historyFiles() ([]string, error) {
+	if r.Dir == "" {
+		if _, err := os.Stat(chatHistoryFile); err != nil {
+			return nil, err
+		}
+		return []string{chatHistoryFile}, nil
+	}
+	root := r.Dir
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == chatHistoryFile {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// readChatHistory parses every session in a chat history file. Lines before
+// the first session header are reported to report.
+func readChatHistory(path string, report *reader.Report) ([]*core.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open history file: %w", err)
+	}
+	defer f.Close()
+
+	var sessions []*session
+	var current *session
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if ts, ok := strings.CutPrefix(line, "# aider chat started at "); ok {
+			start, err := time.ParseInLocation(timeLayout, strings.TrimSpace(ts), time.Local)
+			if err != nil {
+				malformed(report, path, n, "session start time: "+err.Error())
+			}
+			current = &session{start: start}
+			sessions = append(sessions, current)
+			continue
+		}
+		if current == nil {
+			if strings.TrimSpace(line) != "" {
+				malformed(report, path, n, "line before the first session header")
+			}
+			continue
+		}
+		current.lines = append(current.lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("scan history file: %w", err)
+	}
+
+	dir, _ := filepath.Abs(filepath.Dir(path))
+	author := reader.GitAuthor(dir)
+	prompts, _ := readPrompts(filepath.Join(filepath.Dir(path), inputHistoryFile), report)
+
+	var transcripts []*core.Transcript
+	for _, s := range sessions {
+		t := buildTranscript(s, prompts)
+		if t == nil {
+			continue
+		}
+		t.Dir = dir
+		t.Author = author
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, nil
+}
+
+// buildTranscript converts one session into a core.Transcript, or nil when
+// the session has no prompts.
+func buildTranscript(s *session, prompts []prompt) *core.Transcript {
+	var messages []core.Message
+	model := ""
+	var userLines, replyLines []string
+	edits := 0
+
+	flushUser := func() {
+		if len(userLines) == 0 {
+			return
+		}
+		text := strings.TrimSpace(strings.Join(userLines, "\n"))
+		userLines = nil
+		if text == "" {
+			return
+		}
+		messages = append(messages, core.Message{
+			Role:      core.RoleUser,
+			Timestamp: promptTime(prompts, text, s.start),
+			Content: []core.ContentBlock{{
+				Type:   core.BlockText,
+				Format: core.FormatPlain,
+				Text:   text,
+			}},
+		})
+	}
+	flushReply := func() {
+		if len(replyLines) == 0 {
+			return
+		}
+		blocks := parseReply(replyLines, &edits)
+		replyLines = nil
+		if len(blocks) == 0 {
+			return
+		}
+		messages = append(messages, core.Message{Role: core.RoleAssistant, Model: model, Content: blocks})
+	}
+	// lastAssistant returns the most recent assistant message of the current
+	// exchange, if the model has replied since the last prompt.
+	lastAssistant := func() *core.Message {
+		if n := len(messages); n > 0 && messages[n-1].Role == core.RoleAssistant {
+			return &messages[n-1]
+		}
+		return nil
+	}
+
+	for _, line := range s.lines {
+		if text, ok := strings.CutPrefix(line, "#### "); ok || line == "####" {
+			flushReply()
+			userLines = append(userLines, text)
+			continue
+		}
+		flushUser()
+
+		if note, ok := cutNote(line); ok {
+			flushReply()
+			switch {
+			case strings.HasPrefix(note, "Model: "), strings.HasPrefix(note, "Main model: "):
+				_, rest, _ := strings.Cut(note, ": ")
+				name, _, _ := strings.Cut(rest, " ")
+				model = name
+			case strings.HasPrefix(note, "Applied edit to "):
+				if m := lastAssistant(); m != nil {
+					applyEdit(m, strings.TrimPrefix(note, "Applied edit to "))
+				}
+			case strings.HasPrefix(note, "Tokens: "):
+				if m := lastAssistant(); m != nil {
+					u := parseUsage(note)
+					m.Usage = &u
+				}
+			}
+			continue
+		}
+		replyLines = append(replyLines, line)
+	}
+	flushUser()
+	flushReply()
+
+	if len(messages) == 0 || !hasRole(messages, core.RoleUser) {
+		return nil
+	}
+
+	t := &core.Transcript{
+		SessionID: s.start.Format(sessionIDLayout),
+		Agent:     "aider",
+		Model:     model,
+		Title:     reader.DeriveTitle(messages),
+		CreatedAt: s.start,
+		Usage:     reader.AggregateUsage(messages),
+		Messages:  messages,
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if ts := messages[i].Timestamp; ts != nil && ts.After(t.CreatedAt) {
+			t.UpdatedAt = ts
+			break
+		}
+	}
+	return t
+}
+
+// parseReply splits the model's reply into text blocks and Edit tool_use
+// blocks, one per SEARCH/REPLACE section. The file name line and code fence
+// around each section are dropped from the text.
+func parseReply(lines []string, edits *int) []core.ContentBlock {
+	var blocks []core.ContentBlock
+	var text []string
+	file := ""
+
+	flushText := func() {
+		s := strings.TrimSpace(strings.Join(text, "\n"))
+		text = nil
+		if s != "" {
+			blocks = append(blocks, core.ContentBlock{Type: core.BlockText, Format: core.FormatMarkdown, Text: s})
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		if !isMarker(lines[i], "<<<<<<< SEARCH") {
+			text = append(text, lines[i])
+			continue
+		}
+
+		// The file name precedes the opening fence. Consecutive sections in
+		// one fence reuse the previous file name.
+		text = trimTrailingBlank(text)
+		if n := len(text); n > 0 && strings.HasPrefix(strings.TrimSpace(text[n-1]), "```") {
+			text = trimTrailingBlank(text[:n-1])
+		}
+		if n := len(text); n > 0 {
+			file = strings.Trim(strings.TrimSpace(text[n-1]), "`*")
+			text = text[:n-1]
+		}
+		flushText()
+
+		var search, replace []string
+		target := &search
+		for i++; i < len(lines); i++ {
+			if isMarker(lines[i], "=======") && target == &search {
+				target = &replace
+				continue
+			}
+			if isMarker(lines[i], ">>>>>>> REPLACE") {
+				break
+			}
+			*target = append(*target, lines[i])
+		}
+		if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) == "```" {
+			i++
+		}
+
+		*edits++
+		blocks = append(blocks, core.ContentBlock{
+			Type:      core.BlockToolUse,
+			ToolUseID: "edit-" + strconv.Itoa(*edits),
+			Name:      "Edit",
+			Input: map[string]any{
+				"file_path":  file,
+				"old_string": joinLines(search),
+				"new_string": joinLines(replace),
+			},
+		})
+	}
+	flushText()
+	return blocks
+}
+
+// applyEdit records a successful result for each unanswered edit to file.
+func applyEdit(m *core.Message, file string) {
+	answered := make(map[string]bool)
+	for _, b := range m.Content {
+		if b.Type == core.BlockToolResult {
+			answered[b.ToolUseID] = true
+		}
+	}
+
+	var results []core.ContentBlock
+	for _, b := range m.Content {
+		if b.Type != core.BlockToolUse || answered[b.ToolUseID] {
+			continue
+		}
+		if in, ok := b.Input.(map[string]any); ok && in["file_path"] == file {
+			results = append(results, core.ContentBlock{
+				Type:      core.BlockToolResult,
+				ToolUseID: b.ToolUseID,
+				Content:   "Applied edit to " + file,
+			})
+		}
+	}
+	m.Content = append(m.Content, results...)
+}
+
+// parseUsage decodes a "Tokens: 2.5k sent, 1k cache hit, 89 received. Cost:
+// $0.01 message, $0.02 session." note.
+func parseUsage(note string) core.Usage {
+	var u core.Usage
+	for _, m := range tokensRe.FindAllStringSubmatch(note, -1) {
+		n := parseCount(m[1])
+		switch m[2] {
+		case "sent":
+			u.InputTokens = n
+		case "received":
+			u.OutputTokens = n
+		case "cache write":
+			u.CacheCreationTokens = n
+		case "cache hit":
+			u.CacheReadTokens = n
+		}
+	}
+	if m := costRe.FindStringSubmatch(note); m != nil {
+		u.Cost, _ = strconv.ParseFloat(m[1], 64)
+	}
+	return u
+}
+
+// parseCount parses aider's abbreviated token counts ("89", "2.5k", "1.2M").
+func parseCount(s string) int {
+	mult := 1.0
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		mult, s = 1e3, s[:len(s)-1]
+	case 'm', 'M':
+		mult, s = 1e6, s[:len(s)-1]
+	}
+	f, _ := strconv.ParseFloat(s, 64)
+	return int(f*mult + 0.5)
+}
+
+// readInputHistory builds a prompts-only transcript from an input history.
+func readInputHistory(path string, report *reader.Report) (*core.Transcript, error) {
+	prompts, err := readPrompts(path, report)
+	if err != nil {
+		return nil, err
+	}
+	if len(prompts) == 0 {
+		return nil, fmt.Errorf("no prompts found in %s", path)
+	}
+
+	messages := make([]core.Message, len(prompts))
+	for i, p := range prompts {
+		ts := p.time
+		messages[i] = core.Message{
+			Role:      core.RoleUser,
+			Timestamp: &ts,
+			Content: []core.ContentBlock{{
+				Type:   core.BlockText,
+				Format: core.FormatPlain,
+				Text:   p.text,
+			}},
+		}
+	}
+
+	dir, _ := filepath.Abs(filepath.Dir(path))
+	t := &core.Transcript{
+		SessionID: prompts[0].time.Format(sessionIDLayout),
+		Agent:     "aider",
+		Author:    reader.GitAuthor(dir),
+		Dir:       dir,
+		Title:     reader.DeriveTitle(messages),
+		CreatedAt: prompts[0].time,
+		Messages:  messages,
+	}
+	if last := prompts[len(prompts)-1].time; last.After(t.CreatedAt) {
+		t.UpdatedAt = &last
+	}
+	return t, nil
+}
+
+// readPrompts parses an input history, where each entry is a "# <time>"
+// header followed by "+"-prefixed prompt lines. Other non-blank lines and
+// headers without a valid time are reported to report.
+func readPrompts(path string, report *reader.Report) ([]prompt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open input history: %w", err)
+	}
+	defer f.Close()
+
+	var prompts []prompt
+	var lines []string
+	var ts time.Time
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(lines, "\n")); text != "" {
+			prompts = append(prompts, prompt{time: ts, text: text})
+		}
+		lines = nil
+	}
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if h, ok := strings.CutPrefix(line, "# "); ok {
+			flush()
+			var err error
+			ts, err = time.ParseInLocation("2006-01-02 15:04:05.999999", strings.TrimSpace(h), time.Local)
+			if err != nil {
+				malformed(report, path, n, "prompt time: "+err.Error())
+			}
+			continue
+		}
+		if text, ok := strings.CutPrefix(line, "+"); ok {
+			lines = append(lines, text)
+		} else if strings.TrimSpace(line) != "" {
+			malformed(report, path, n, "line is neither a prompt header nor a prompt line")
+		}
+	}
+	flush()
+	return prompts, sc.Err()
+}
+
+// malformed reports line n of path, which the reader skipped.
+func malformed(report *reader.Report, path string, n int, msg string) {
+	report.Add(reader.Warning{Kind: reader.WarnMalformedLine, Path: path, Line: n, Message: msg})
+}
+
+// promptTime finds the input history timestamp of a prompt sent after start.
+func promptTime(prompts []prompt, text string, start time.Time) *time.Time {
+	for _, p := range prompts {
+		if p.text == text && !p.time.Before(start) {
+			ts := p.time
+			return &ts
+		}
+	}
+	return nil
+}
+
+// cutNote strips the "> " prefix aider puts on its own output.
+func cutNote(line string) (string, bool) {
+	if line == ">" {
+		return "", true
+	}
+	return strings.CutPrefix(line, "> ")
+}
+
+func isMarker(line, marker string) bool {
+	return strings.TrimSpace(line) == marker
+}
+
+func trimTrailingBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// joinLines joins section lines, restoring the trailing newline of each line.
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func hasRole(messages []core.Message, role core.Role) bool {
+	for _, m := range messages {
+		if m.Role == role {
+			return true
+		}
+	}
+	return false
+}
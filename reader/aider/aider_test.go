@@ -0,0 +1,237 @@
+package aider
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRepo = filepath.Join("testdata", "repo")
+
+func readAll(t *testing.T) []*core.Transcript {
+	t.Helper()
+	transcripts, err := readChatHistory(filepath.Join(testRepo, chatHistoryFile), nil)
+	require.NoError(t, err)
+	require.Len(t, transcripts, 2)
+	return transcripts
+}
+
+func TestSessions(t *testing.T) {
+	transcripts := readAll(t)
+
+	first := transcripts[0]
+	assert.Equal(t, "2026-01-01T09-00-00", first.SessionID)
+	assert.Equal(t, "aider", first.Agent)
+	assert.Equal(t, "gpt-4o", first.Model)
+	assert.Equal(t, "what does main.go do?", first.Title)
+	assert.True(t, filepath.IsAbs(first.Dir))
+	require.Len(t, first.Messages, 2)
+	assert.Equal(t, "It starts the **HTTP server**.", first.Messages[1].Content[0].Text)
+	assert.Equal(t, core.FormatMarkdown, first.Messages[1].Content[0].Format)
+
+	second := transcripts[1]
+	assert.Equal(t, "2026-01-02T10-00-00", second.SessionID)
+	assert.Equal(t, "gpt-4o", second.Model)
+}
+
+func TestPrompts(t *testing.T) {
+	tr := readAll(t)[1]
+
+	// prompt, reply, /exit
+	require.Len(t, tr.Messages, 3)
+	user := tr.Messages[0]
+	assert.Equal(t, core.RoleUser, user.Role)
+	assert.Equal(t, "add a hello function\nand call it from main", user.Content[0].Text)
+
+	t.Run("timestamps come from the input history", func(t *testing.T) {
+		require.NotNil(t, user.Timestamp)
+		assert.Equal(t, 5, user.Timestamp.Second())
+		require.NotNil(t, tr.UpdatedAt)
+		assert.Equal(t, 3, tr.UpdatedAt.Minute())
+	})
+}
+
+func TestEditBlocks(t *testing.T) {
+	tr := readAll(t)[1]
+	blocks := tr.Messages[1].Content
+
+	// text, 3 edits, 3 results
+	require.Len(t, blocks, 7)
+	assert.Equal(t, "I'll add the function and call it.", blocks[0].Text)
+
+	t.Run("search/replace becomes Edit", func(t *testing.T) {
+		b := blocks[1]
+		assert.Equal(t, core.BlockToolUse, b.Type)
+		assert.Equal(t, "Edit", b.Name)
+		m := b.Input.(map[string]any)
+		assert.Equal(t, "hello.py", m["file_path"])
+		assert.Equal(t, "", m["old_string"])
+		assert.Equal(t, "def hello():\n    print(\"hi\")\n", m["new_string"])
+	})
+
+	t.Run("sections in one fence share the file name", func(t *testing.T) {
+		assert.Equal(t, "main.py", blocks[2].Input.(map[string]any)["file_path"])
+		assert.Equal(t, "main.py", blocks[3].Input.(map[string]any)["file_path"])
+		assert.Equal(t, "import os\n", blocks[3].Input.(map[string]any)["old_string"])
+	})
+
+	t.Run("applied edits get results", func(t *testing.T) {
+		for i, b := range blocks[4:] {
+			assert.Equal(t, core.BlockToolResult, b.Type)
+			assert.Equal(t, blocks[1+i].ToolUseID, b.ToolUseID)
+		}
+	})
+
+	t.Run("diff stats count edits", func(t *testing.T) {
+		ds := core.ComputeDiffStats(tr)
+		require.NotNil(t, ds)
+		assert.Equal(t, 2, ds.Changed)
+		// hello.py +2; main.py: "pass" → "hello()" and one added import.
+		assert.Equal(t, 4, ds.Added)
+		assert.Equal(t, 1, ds.Removed)
+	})
+}
+
+func TestUsage(t *testing.T) {
+	tr := readAll(t)[1]
+
+	u := tr.Messages[1].Usage
+	require.NotNil(t, u)
+	assert.Equal(t, 2500, u.InputTokens)
+	assert.Equal(t, 1000, u.CacheReadTokens)
+	assert.Equal(t, 120, u.OutputTokens)
+	assert.InDelta(t, 0.01, u.Cost, 1e-9)
+}
+
+func TestReadFile(t *testing.T) {
+	r := &Reader{}
+
+	t.Run("chat history returns the latest session", func(t *testing.T) {
+		tr, err := r.ReadFile(filepath.Join(testRepo, chatHistoryFile))
+		require.NoError(t, err)
+		assert.Equal(t, "2026-01-02T10-00-00", tr.SessionID)
+	})
+
+	t.Run("input history", func(t *testing.T) {
+		tr, err := r.ReadFile(filepath.Join(testRepo, inputHistoryFile))
+		require.NoError(t, err)
+		assert.Len(t, tr.Messages, 3)
+		assert.Equal(t, "what does main.go do?", tr.Title)
+	})
+}
+
+func TestReadSession(t *testing.T) {
+	r := &Reader{Dir: "testdata"}
+
+	tr, err := r.ReadSession("2026-01-01T09-00-00")
+	require.NoError(t, err)
+	assert.Equal(t, "what does main.go do?", tr.Title)
+
+	_, err = r.ReadSession("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestReadProject(t *testing.T) {
+	r := &Reader{Dir: "testdata"}
+	abs, err := filepath.Abs(testRepo)
+	require.NoError(t, err)
+
+	for _, project := range []string{testRepo, abs} {
+		transcripts, err := r.ReadProject(project)
+		require.NoError(t, err)
+		assert.Len(t, transcripts, 2, "project %q", project)
+	}
+
+	transcripts, err := r.ReadProject("/nonexistent")
+	require.NoError(t, err)
+	assert.Empty(t, transcripts)
+}
+
+func TestReadAll(t *testing.T) {
+	r := &Reader{Dir: "testdata"}
+
+	transcripts, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, transcripts, 2)
+
+	t.Run("without Dir only the current directory is read", func(t *testing.T) {
+		abs, err := filepath.Abs(testRepo)
+		require.NoError(t, err)
+		repo := os.DirFS(abs)
+		cwd := t.TempDir()
+		require.NoError(t, os.CopyFS(filepath.Join(cwd, "nested"), repo))
+		t.Chdir(cwd)
+
+		_, err = (&Reader{}).ReadAll()
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+
+		require.NoError(t, os.CopyFS(".", repo))
+		transcripts, err := (&Reader{}).ReadAll()
+		require.NoError(t, err)
+		assert.Len(t, transcripts, 2)
+	})
+}
+
+func TestReportWarnings(t *testing.T) {
+	t.Run("testdata is clean", func(t *testing.T) {
+		report := &reader.Report{}
+		_, err := (&Reader{Dir: testRepo, Report: report}).ReadAll()
+		require.NoError(t, err)
+		assert.Empty(t, report.Warnings())
+	})
+
+	t.Run("malformed lines", func(t *testing.T) {
+		dir := t.TempDir()
+		chat := filepath.Join(dir, chatHistoryFile)
+		require.NoError(t, os.WriteFile(chat, []byte("stray note\n\n"+
+			"# aider chat started at 2026-01-01 09:00:00\n\n#### hi\n\nhello\n"), 0o644))
+		input := filepath.Join(dir, inputHistoryFile)
+		require.NoError(t, os.WriteFile(input, []byte("\n# 2026-01-01 09:00:05.123456\n+hi\ngarbage\n"+
+			"# yesterday\n+again\n"), 0o644))
+
+		report := &reader.Report{}
+		tr, err := (&Reader{Report: report}).ReadFile(chat)
+		require.NoError(t, err)
+		assert.Len(t, tr.Messages, 2)
+
+		warnings := report.Warnings()
+		require.Len(t, warnings, 3)
+		assert.Equal(t, reader.Warning{
+			Kind:    reader.WarnMalformedLine,
+			Path:    chat,
+			Line:    1,
+			Message: "line before the first session header",
+		}, warnings[0])
+		assert.Equal(t, reader.Warning{
+			Kind:    reader.WarnMalformedLine,
+			Path:    input,
+			Line:    4,
+			Message: "line is neither a prompt header nor a prompt line",
+		}, warnings[1])
+		assert.Equal(t, reader.WarnMalformedLine, warnings[2].Kind)
+		assert.Equal(t, 5, warnings[2].Line)
+	})
+}
+
+func TestParseCount(t *testing.T) {
+	assert.Equal(t, 89, parseCount("89"))
+	assert.Equal(t, 2500, parseCount("2.5k"))
+	assert.Equal(t, 1200000, parseCount("1.2M"))
+}
+
+func TestSniff(t *testing.T) {
+	r := &Reader{}
+
+	assert.True(t, r.Sniff(filepath.Join(testRepo, chatHistoryFile)))
+	assert.True(t, r.Sniff(filepath.Join(testRepo, inputHistoryFile)))
+	assert.True(t, r.Sniff(testRepo))
+
+	assert.False(t, r.Sniff("testdata"))
+	assert.False(t, r.Sniff("aider.go"))
+}
@@ -3,50 +3,111 @@ package claude
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
 )
 
 // Reader reads Claude Code JSONL session files.
 type Reader struct {
 	// Dir overrides the default session directory (~/.claude/projects/).
 	Dir string
+
+	// MaxLineSize is the JSONL line length above which long content strings
+	// are truncated with a marker. Zero means 1 MB. Lines of any length are
+	// read; the limit only bounds what is kept.
+	MaxLineSize int
+
+	// IncludeReadResults keeps the output of Read tool calls. By default it
+	// is dropped as file-content noise; audits need it to show exactly what
+	// the agent saw before an edit.
+	IncludeReadResults bool
+
+	// MergeResumed stitches sessions that were resumed with --resume or
+	// --continue into the session they continue, with a resume event at each
+	// boundary. Reading any session of a chain returns the whole chain.
+	MergeResumed bool
+
+	// Report, when set, collects warnings about skipped files, malformed
+	// lines and unknown content blocks.
+	Report *reader.Report
 }
 
-// maxLineSize is the maximum JSONL line size (1 MB). Claude Code tool results
-// can exceed the default 64 KB bufio.Scanner buffer.
-const maxLineSize = 1 << 20
+// defaultMaxLineSize is the default truncation threshold (1 MB). Claude Code
+// tool results with large file dumps or base64 images regularly exceed it.
+const defaultMaxLineSize = 1 << 20
 
 // Raw JSON deserialization types. These mirror the JSONL structure on disk.
 
 type rawEntry struct {
-	Type        string     `json:"type"`
-	UUID        string     `json:"uuid"`
-	ParentUUID  *string    `json:"parentUuid"`
-	SessionID   string     `json:"sessionId"`
-	Timestamp   string     `json:"timestamp"`
-	CWD         string     `json:"cwd"`
-	GitBranch   string     `json:"gitBranch"`
-	IsSidechain bool       `json:"isSidechain"`
-	AgentID     string     `json:"agentId"`
-	Message     rawMessage `json:"message"`
+	Type       string  `json:"type"`
+	UUID       string  `json:"uuid"`
+	ParentUUID *string `json:"parentUuid"`
+	// LogicalParentUUID links the first entry after a compaction, whose
+	// parentUuid is null, to the conversation before it.
+	LogicalParentUUID *string    `json:"logicalParentUuid"`
+	SessionID         string     `json:"sessionId"`
+	Timestamp         string     `json:"timestamp"`
+	CWD               string     `json:"cwd"`
+	GitBranch         string     `json:"gitBranch"`
+	IsSidechain       bool       `json:"isSidechain"`
+	AgentID           string     `json:"agentId"`
+	Message           rawMessage `json:"message"`
+
+	// A "system" entry with subtype "compact_boundary" marks where context
+	// was compacted; the user entry after it carries the summary.
+	Subtype          string              `json:"subtype"`
+	CompactMetadata  *rawCompactMetadata `json:"compactMetadata"`
+	IsCompactSummary bool                `json:"isCompactSummary"`
+
+	// LeafUUID is set on "summary" entries and names the last message of the
+	// conversation they summarize. A resumed session starts with one.
+	LeafUUID string `json:"leafUuid"`
+}
+
+type rawCompactMetadata struct {
+	Trigger   string `json:"trigger"`
+	PreTokens int    `json:"preTokens"`
 }
 
 type rawMessage struct {
-	ID      string            `json:"id"`
-	Role    string            `json:"role"`
-	Model   string            `json:"model"`
-	Content []json.RawMessage `json:"content"`
-	Usage   *rawUsage         `json:"usage"`
+	ID      string     `json:"id"`
+	Role    string     `json:"role"`
+	Model   string     `json:"model"`
+	Content rawContent `json:"content"`
+	Usage   *rawUsage  `json:"usage"`
+}
+
+// rawContent is a message's content blocks. Plain-string content, as some
+// user entries carry, is decoded as a single text block.
+type rawContent []json.RawMessage
+
+func (c *rawContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		block, err := json.Marshal(map[string]string{"type": "text", "text": text})
+		if err != nil {
+			return err
+		}
+		*c = rawContent{block}
+		return nil
+	}
+	var blocks []json.RawMessage
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	*c = blocks
+	return nil
 }
 
 type rawUsage struct {
@@ -57,36 +118,57 @@ type rawUsage struct {
 }
 
 type rawContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Thinking  string          `json:"thinking"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     any             `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   any             `json:"content"`
+	IsError   bool            `json:"is_error"`
+	Source    *rawImageSource `json:"source"`
+}
+
+// rawImageSource is the source of an image block: inline base64 data or a URL.
+type rawImageSource struct {
 	Type      string `json:"type"`
-	Text      string `json:"text"`
-	Thinking  string `json:"thinking"`
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Input     any    `json:"input"`
-	ToolUseID string `json:"tool_use_id"`
-	Content   any    `json:"content"`
-	IsError   bool   `json:"is_error"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+	URL       string `json:"url"`
 }
 
 // ReadFile parses a single Claude Code JSONL session file and any sub-agent files.
 func (r *Reader) ReadFile(path string) (*core.Transcript, error) {
+	if !r.MergeResumed {
+		return r.readFile(path)
+	}
+	return r.readChain(path)
+}
+
+// readFile parses a single session file without merging resumed sessions.
+func (r *Reader) readFile(path string) (*core.Transcript, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open session file: %w", err)
 	}
 	defer f.Close()
 
-	entries, err := scanEntries(f)
+	var tree entryTree
+	entries, err := r.scanEntries(f, &tree)
 	if err != nil {
 		return nil, fmt.Errorf("scan session file: %w", err)
 	}
 
-	t, err := buildTranscript(entries)
+	entries, branches := r.splitBranches(entries, &tree)
+	t, err := r.buildTranscript(entries)
 	if err != nil {
 		return nil, err
 	}
+	t.Branches = branches
+	t.ContinuesFrom = tree.continuesFrom()
 
-	if err := attachSubagents(path, t); err != nil {
+	if err := r.attachSubagents(path, t); err != nil {
 		return nil, fmt.Errorf("attach subagents: %w", err)
 	}
 
@@ -95,12 +177,21 @@ func (r *Reader) ReadFile(path string) (*core.Transcript, error) {
 
 // ReadSession locates and parses a session by its UUID across all projects.
 func (r *Reader) ReadSession(sessionID string) (*core.Transcript, error) {
+	path, err := r.sessionPath(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReadFile(path)
+}
+
+// sessionPath returns the path of the session file with the given UUID.
+func (r *Reader) sessionPath(sessionID string) (string, error) {
 	dir := r.dir()
 	fileName := sessionID + ".jsonl"
 
 	projectDirs, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("read projects directory: %w", err)
+		return "", fmt.Errorf("read projects directory: %w", err)
 	}
 
 	for _, d := range projectDirs {
@@ -109,11 +200,11 @@ func (r *Reader) ReadSession(sessionID string) (*core.Transcript, error) {
 		}
 		path := filepath.Join(dir, d.Name(), fileName)
 		if _, err := os.Stat(path); err == nil {
-			return r.ReadFile(path)
+			return path, nil
 		}
 	}
 
-	return nil, fmt.Errorf("session %s not found", sessionID)
+	return "", fmt.Errorf("session %s not found", sessionID)
 }
 
 // ReadProject returns all session transcripts for a named project directory.
@@ -130,16 +221,116 @@ func (r *Reader) ReadProject(project string) ([]*core.Transcript, error) {
 		if de.IsDir() || !strings.HasSuffix(de.Name(), ".jsonl") {
 			continue
 		}
-		t, err := r.ReadFile(filepath.Join(projectDir, de.Name()))
+		path := filepath.Join(projectDir, de.Name())
+		t, err := r.readFile(path)
 		if err != nil {
+			r.Report.SkippedFile(path, err)
 			continue
 		}
 		transcripts = append(transcripts, t)
 	}
 
+	if r.MergeResumed {
+		transcripts = core.MergeResumed(transcripts)
+	}
 	return transcripts, nil
 }
 
+// readChain reads the session file at path merged with the sessions it
+// resumes and that resume it. Only sessions linked to it are parsed; they are
+// merged with core.MergeResumed, as ReadProject does.
+func (r *Reader) readChain(path string) (*core.Transcript, error) {
+	t, err := r.readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	linked, err := r.linkedSessions(path, t)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range core.MergeResumed(linked) {
+		if m.SessionID == t.SessionID || slices.Contains(m.SessionIDs, t.SessionID) {
+			return m, nil
+		}
+	}
+	return t, nil
+}
+
+// linkedSessions returns t and the other sessions in its project directory
+// that it is linked to through resumes, directly or via another linked
+// session. A resumed session replays the messages of the one it continues, so
+// two sessions are linked when one file mentions the first or last message
+// uuid, or the ContinuesFrom uuid, of the other. Files are only searched for
+// those uuids; just the ones that match are parsed.
+func (r *Reader) linkedSessions(path string, t *core.Transcript) ([]*core.Transcript, error) {
+	dir := filepath.Dir(path)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read project directory: %w", err)
+	}
+	var others []string
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".jsonl") {
+			continue
+		}
+		if p := filepath.Join(dir, de.Name()); p != filepath.Join(dir, filepath.Base(path)) {
+			others = append(others, p)
+		}
+	}
+
+	linked := []*core.Transcript{t}
+	for next := 0; next < len(linked); next++ {
+		probes := resumeLinks(linked[next])
+		if len(probes) == 0 {
+			continue
+		}
+		rest := others[:0]
+		for _, p := range others {
+			data, err := os.ReadFile(p)
+			if err != nil || !containsAny(data, probes) {
+				rest = append(rest, p)
+				continue
+			}
+			lt, err := r.readFile(p)
+			if err != nil {
+				r.Report.SkippedFile(p, err)
+				continue
+			}
+			linked = append(linked, lt)
+		}
+		others = rest
+	}
+	return linked, nil
+}
+
+// resumeLinks returns the quoted uuids through which t is linked to the
+// session it resumes and to sessions resuming it.
+func resumeLinks(t *core.Transcript) [][]byte {
+	var ids []string
+	if t.ContinuesFrom != "" {
+		ids = append(ids, t.ContinuesFrom)
+	}
+	if n := len(t.Messages); n > 0 {
+		ids = append(ids, t.Messages[0].UUID, t.Messages[n-1].UUID)
+	}
+	var probes [][]byte
+	for _, id := range ids {
+		if id != "" {
+			probes = append(probes, []byte(`"`+id+`"`))
+		}
+	}
+	return probes
+}
+
+func containsAny(data []byte, subs [][]byte) bool {
+	for _, sub := range subs {
+		if bytes.Contains(data, sub) {
+			return true
+		}
+	}
+	return false
+}
+
 // ReadAll returns every session transcript across all projects.
 func (r *Reader) ReadAll() ([]*core.Transcript, error) {
 	dir := r.dir()
@@ -155,6 +346,7 @@ func (r *Reader) ReadAll() ([]*core.Transcript, error) {
 		}
 		transcripts, err := r.ReadProject(d.Name())
 		if err != nil {
+			r.Report.SkippedFile(filepath.Join(dir, d.Name()), err)
 			continue
 		}
 		all = append(all, transcripts...)
@@ -171,35 +363,373 @@ func (r *Reader) dir() string {
 	return filepath.Join(home, ".claude", "projects")
 }
 
-// scanEntries reads JSONL lines, keeping only user and assistant message entries.
-func scanEntries(r io.Reader) ([]rawEntry, error) {
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, maxLineSize), maxLineSize)
+// Sniff reports whether path is a Claude Code JSONL session, a project
+// directory of sessions, or the projects directory itself.
+func (r *Reader) Sniff(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		return sniffFile(path)
+	}
+	for _, pattern := range []string{"*.jsonl", filepath.Join("*", "*.jsonl")} {
+		matches, _ := filepath.Glob(filepath.Join(path, pattern))
+		for _, m := range matches {
+			if sniffFile(m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sniffFile checks the first lines of a JSONL file for a Claude Code entry.
+// Sessions may open with summary or snapshot lines that carry no session ID.
+func sniffFile(path string) bool {
+	if !strings.HasSuffix(path, ".jsonl") {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
 
-	var entries []rawEntry
-	for scanner.Scan() {
+	br := bufio.NewReader(f)
+	for i := 0; i < 5; i++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) == 0 {
+			return false
+		}
 		var entry rawEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return false
 		}
-		if entry.IsSidechain {
-			continue
+		if entry.SessionID != "" && entry.Type != "" {
+			return true
 		}
-		if entry.Type != "user" && entry.Type != "assistant" {
-			continue
+		if err != nil {
+			return false
 		}
+	}
+	return false
+}
+
+// scanEntries reads JSONL lines, keeping only user and assistant message
+// entries. When tree is non-nil, it records the links between all entries.
+func (r *Reader) scanEntries(rd io.Reader, tree *entryTree) ([]rawEntry, error) {
+	var entries []rawEntry
+	err := r.eachEntry(rd, tree, func(entry rawEntry) bool {
 		entries = append(entries, entry)
+		return true
+	})
+	return entries, err
+}
+
+// eachEntry calls fn for each user and assistant message entry, and each
+// compaction boundary, until fn returns false. Only one line is held in
+// memory at a time. When tree is non-nil, every main-chain entry is added to
+// it, including those fn skips. Malformed lines are reported and skipped.
+func (r *Reader) eachEntry(rd io.Reader, tree *entryTree, fn func(rawEntry) bool) error {
+	return r.eachLine(rd, func(n int, line []byte) bool {
+		entry, ok := r.parseEntry(rd, n, line)
+		if !ok || entry.IsSidechain {
+			return true
+		}
+		if tree != nil {
+			tree.add(entry)
+		}
+		if !isMessageEntry(entry) {
+			return true
+		}
+		return fn(entry)
+	})
+}
+
+// isMessageEntry reports whether entry is grouped into messages: a user or
+// assistant message entry, or a compaction boundary.
+func isMessageEntry(entry rawEntry) bool {
+	return entry.Type == "user" || entry.Type == "assistant" || isCompactBoundary(entry)
+}
+
+// parseEntry decodes line n of rd, reporting lines that are not valid JSON
+// and content blocks of unknown types.
+func (r *Reader) parseEntry(rd io.Reader, n int, line []byte) (rawEntry, bool) {
+	var entry rawEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		if len(bytes.TrimSpace(line)) > 0 {
+			r.Report.Add(reader.Warning{
+				Kind:    reader.WarnMalformedLine,
+				Path:    sourceName(rd),
+				Line:    n,
+				Message: err.Error(),
+			})
+		}
+		return rawEntry{}, false
+	}
+	if r.Report != nil && (entry.Type == "user" || entry.Type == "assistant") {
+		for _, typ := range unknownBlockTypes(entry.Message.Content) {
+			r.Report.Add(reader.Warning{
+				Kind:    reader.WarnUnknownBlock,
+				Path:    sourceName(rd),
+				Line:    n,
+				Message: fmt.Sprintf("content block type %q", typ),
+			})
+		}
+	}
+	return entry, true
+}
+
+// unknownBlockTypes returns the types of content blocks mapContentBlock
+// does not map.
+func unknownBlockTypes(content []json.RawMessage) []string {
+	var unknown []string
+	for _, raw := range content {
+		var b struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(raw, &b); err != nil {
+			continue
+		}
+		switch b.Type {
+		case "text", "thinking", "tool_use", "tool_result", "image":
+		default:
+			unknown = append(unknown, b.Type)
+		}
+	}
+	return unknown
+}
+
+// sourceName returns the file name behind rd, if it is a file.
+func sourceName(rd io.Reader) string {
+	if f, ok := rd.(interface{ Name() string }); ok {
+		return f.Name()
+	}
+	return ""
+}
+
+// eachLine calls fn with the 1-based number of each line of rd until fn
+// returns false. Lines longer than MaxLineSize have their content truncated by
+// truncateLine.
+func (r *Reader) eachLine(rd io.Reader, fn func(int, []byte) bool) error {
+	limit := r.lineLimit()
+	br := bufio.NewReader(rd)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) > limit {
+			line = truncateLine(line, min(limit, reader.TruncatedFieldSize))
+		}
+		if len(line) > 0 && !fn(n, line) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// lineLimit returns the line length above which content is truncated.
+func (r *Reader) lineLimit() int {
+	if r.MaxLineSize <= 0 {
+		return defaultMaxLineSize
+	}
+	return r.MaxLineSize
+}
+
+// truncateLine shortens every string longer than keep bytes in the entry's
+// message content, and drops the toolUseResult copy of tool output. Lines
+// that are not JSON objects are returned unchanged.
+func truncateLine(line []byte, keep int) []byte {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var entry map[string]any
+	if err := dec.Decode(&entry); err != nil {
+		return line
+	}
+	delete(entry, "toolUseResult")
+	if msg, ok := entry["message"].(map[string]any); ok {
+		msg["content"] = reader.TruncateStrings(msg["content"], keep)
+	}
+	out, err := json.Marshal(entry)
+	if err != nil {
+		return line
+	}
+	return out
+}
+
+// StreamFile implements reader.StreamReader. A first pass over the file
+// collects the header fields, holding one message at a time; the returned
+// stream re-reads the file and yields messages as they are completed.
+// Sub-agent transcripts are read in full and attached to the header.
+func (r *Reader) StreamFile(path string) (*core.Transcript, core.MessageSeq, error) {
+	if r.MergeResumed {
+		// Merging needs every session of the chain in memory.
+		t, err := r.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		header := *t
+		header.Messages = nil
+		return &header, core.Messages(t.Messages), nil
+	}
+
+	// The branch structure is only known once every entry has been seen.
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open session file: %w", err)
+	}
+	var tree entryTree
+	err = r.eachEntry(f, &tree, func(rawEntry) bool { return true })
+	f.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("scan session file: %w", err)
+	}
+	branchOf := tree.branches()
+
+	var first, last rawEntry
+	var abandoned []rawEntry
+	var total core.Usage
+	agentIDs := make(map[string]string)
+	model, title := "", ""
+	seenUser := false
+	n := 0
+
+	observe := func(entry rawEntry) {
+		if branchOf[entry.UUID] != "" {
+			abandoned = append(abandoned, entry)
+			return
+		}
+		if n == 0 {
+			first = entry
+		}
+		last = entry
+		n++
+		if model == "" && entry.Type == "assistant" {
+			model = entry.Message.Model
+		}
+	}
+	for m, err := range r.streamMessages(path, branchOf, observe) {
+		if err != nil {
+			return nil, nil, fmt.Errorf("scan session file: %w", err)
+		}
+		if m.Usage != nil {
+			total.Add(*m.Usage)
+		}
+		collectAgentIDs(&m, nil, agentIDs)
+		// deriveTitle only considers the first user message.
+		if m.Role == core.RoleUser && !seenUser {
+			seenUser = true
+			title = deriveTitle([]core.Message{m})
+		}
+	}
+	if n == 0 {
+		return nil, nil, fmt.Errorf("no messages found in session")
+	}
+
+	t := &core.Transcript{
+		SessionID: first.SessionID,
+		Agent:     "claude",
+		Author:    reader.GitAuthor(first.CWD),
+		Model:     model,
+		Dir:       first.CWD,
+		GitBranch: first.GitBranch,
+		Title:     title,
+		CreatedAt: parseTime(first.Timestamp),
+
+		ContinuesFrom: tree.continuesFrom(),
+	}
+	if last.Timestamp != first.Timestamp {
+		updated := parseTime(last.Timestamp)
+		t.UpdatedAt = &updated
+	}
+	if total != (core.Usage{}) {
+		t.Usage = &total
+	}
+	if len(abandoned) > 0 {
+		t.Branches = r.buildBranches(abandoned, branchOf, &tree)
+	}
+
+	subIndex, err := r.loadSubagents(path, t)
+	if err != nil {
+		return nil, nil, fmt.Errorf("attach subagents: %w", err)
+	}
+	for id, agentID := range agentIDs {
+		if _, found := subIndex[agentID]; !found {
+			delete(agentIDs, id)
+		}
+	}
+
+	messages := r.streamMessages(path, branchOf, nil)
+	if len(agentIDs) == 0 {
+		return t, messages, nil
+	}
+	return t, func(yield func(core.Message, error) bool) {
+		for m, err := range messages {
+			if err == nil {
+				linkSubagents(&m, agentIDs)
+			}
+			if !yield(m, err) {
+				return
+			}
+		}
+	}, nil
+}
+
+// streamMessages opens path on each iteration and yields grouped messages,
+// skipping entries on abandoned branches. observe, when non-nil, sees every
+// entry, including skipped ones, before it is grouped.
+func (r *Reader) streamMessages(path string, branchOf map[string]string, observe func(rawEntry)) core.MessageSeq {
+	return func(yield func(core.Message, error) bool) {
+		f, err := os.Open(path)
+		if err != nil {
+			yield(core.Message{}, fmt.Errorf("open session file: %w", err))
+			return
+		}
+		defer f.Close()
+
+		g := messageGrouper{includeRead: r.IncludeReadResults}
+		stopped := false
+		err = r.eachEntry(f, nil, func(entry rawEntry) bool {
+			if observe != nil {
+				observe(entry)
+			}
+			if branchOf[entry.UUID] != "" {
+				return true
+			}
+			for _, m := range g.add(entry) {
+				if !yield(m, nil) {
+					stopped = true
+					return false
+				}
+			}
+			return true
+		})
+		if stopped {
+			return
+		}
+		if err != nil {
+			yield(core.Message{}, err)
+			return
+		}
+		for _, m := range g.flush() {
+			if !yield(m, nil) {
+				return
+			}
+		}
 	}
-	return entries, scanner.Err()
 }
 
 // buildTranscript assembles a core.Transcript from filtered raw entries.
-func buildTranscript(entries []rawEntry) (*core.Transcript, error) {
+func (r *Reader) buildTranscript(entries []rawEntry) (*core.Transcript, error) {
 	if len(entries) == 0 {
 		return nil, fmt.Errorf("no messages found in session")
 	}
 
-	messages := groupAndMapMessages(entries)
+	messages := r.groupAndMapMessages(entries)
 	first := entries[0]
 	last := entries[len(entries)-1]
 
@@ -213,95 +743,289 @@ func buildTranscript(entries []rawEntry) (*core.Transcript, error) {
 	return &core.Transcript{
 		SessionID: first.SessionID,
 		Agent:     "claude",
-		Author:    gitAuthor(first.CWD),
+		Author:    reader.GitAuthor(first.CWD),
 		Model:     findPrimaryModel(entries),
 		Dir:       first.CWD,
 		GitBranch: first.GitBranch,
 		Title:     deriveTitle(messages),
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
-		Usage:     aggregateUsage(messages),
+		Usage:     reader.AggregateUsage(messages),
 		Messages:  messages,
 	}, nil
 }
 
-// gitAuthor returns the git user.name configured in dir, or "" on any error.
-func gitAuthor(dir string) string {
-	if dir == "" {
-		return ""
+// --- Branches ---
+
+// entryTree records how the entries of a session link to each other through
+// uuid/parentUuid, to tell the live conversation apart from branches the user
+// abandoned by editing a prompt or rewinding.
+type entryTree struct {
+	parent map[string]string // uuid → parent uuid ("" for roots)
+	prompt map[string]bool   // uuids of human prompt entries
+	leaf   string            // uuid of the last message entry
+	first  string            // uuid of the first message entry
+	resume string            // leafUuid of a summary entry before the first message
+}
+
+// add records entry's link to its parent.
+func (t *entryTree) add(entry rawEntry) {
+	if entry.Type == "summary" && entry.LeafUUID != "" && t.first == "" && t.resume == "" {
+		t.resume = entry.LeafUUID
 	}
-	cmd := exec.Command("git", "config", "user.name")
-	cmd.Dir = dir
-	out, err := cmd.Output()
-	if err != nil {
-		return ""
+	if entry.UUID == "" {
+		return
+	}
+	if t.parent == nil {
+		t.parent = make(map[string]string)
+		t.prompt = make(map[string]bool)
 	}
-	return strings.TrimSpace(string(out))
+	parent := ""
+	if entry.ParentUUID != nil {
+		parent = *entry.ParentUUID
+	} else if entry.LogicalParentUUID != nil {
+		parent = *entry.LogicalParentUUID
+	}
+	t.parent[entry.UUID] = parent
+	if entry.Type != "user" && entry.Type != "assistant" {
+		return
+	}
+	t.leaf = entry.UUID
+	if t.first == "" {
+		t.first = entry.UUID
+	}
+	if entry.Type == "user" && !isToolResultOnly(entry) && !entry.IsCompactSummary {
+		t.prompt[entry.UUID] = true
+	}
+}
+
+// continuesFrom returns the uuid of the message in an earlier session that
+// this one resumes: the leaf named by a leading summary entry, or the parent
+// of the first message when it lies outside the file. It returns "" for
+// sessions started afresh.
+func (t *entryTree) continuesFrom() string {
+	if t.resume != "" {
+		if _, own := t.parent[t.resume]; !own {
+			return t.resume
+		}
+	}
+	if parent := t.parent[t.first]; parent != "" {
+		if _, own := t.parent[parent]; !own {
+			return parent
+		}
+	}
+	return ""
+}
+
+// branches maps the uuid of each entry on an abandoned branch to the uuid of
+// the prompt that starts the branch. The active path runs from the last
+// message back to the root; a branch forks off it at a prompt that was later
+// replaced. Entries that never meet the active path are kept in the main
+// transcript. The map is empty for linear sessions.
+func (t *entryTree) branches() map[string]string {
+	if t.leaf == "" {
+		return nil
+	}
+	active := make(map[string]bool)
+	for id := t.leaf; id != "" && !active[id]; id = t.parent[id] {
+		active[id] = true
+	}
+
+	resolved := make(map[string]string, len(t.parent))
+	for id := range t.parent {
+		if active[id] {
+			continue
+		}
+		var path []string
+		root := ""
+		for cur := id; len(path) <= len(t.parent); {
+			if r, ok := resolved[cur]; ok {
+				root = r
+				break
+			}
+			path = append(path, cur)
+			parent := t.parent[cur]
+			if parent == "" {
+				break
+			}
+			if active[parent] {
+				// Only an edited or rewound prompt starts a branch; other
+				// forks, such as parallel tool results, stay in the main line.
+				if t.prompt[cur] {
+					root = cur
+				}
+				break
+			}
+			cur = parent
+		}
+		for _, p := range path {
+			resolved[p] = root
+		}
+	}
+
+	branchOf := make(map[string]string)
+	for id, root := range resolved {
+		if root != "" {
+			branchOf[id] = root
+		}
+	}
+	return branchOf
+}
+
+// splitBranches separates entries on abandoned branches from the main line.
+func (r *Reader) splitBranches(entries []rawEntry, tree *entryTree) ([]rawEntry, []core.Branch) {
+	branchOf := tree.branches()
+	if len(branchOf) == 0 {
+		return entries, nil
+	}
+	var main, abandoned []rawEntry
+	for _, e := range entries {
+		if branchOf[e.UUID] != "" {
+			abandoned = append(abandoned, e)
+		} else {
+			main = append(main, e)
+		}
+	}
+	return main, r.buildBranches(abandoned, branchOf, tree)
+}
+
+// buildBranches groups abandoned entries by the prompt that starts their
+// branch, in file order.
+func (r *Reader) buildBranches(entries []rawEntry, branchOf map[string]string, tree *entryTree) []core.Branch {
+	index := make(map[string]int)
+	var branches []core.Branch
+	var groups [][]rawEntry
+	for _, e := range entries {
+		root := branchOf[e.UUID]
+		i, ok := index[root]
+		if !ok {
+			i = len(branches)
+			index[root] = i
+			branches = append(branches, core.Branch{ID: root, ParentUUID: tree.parent[root]})
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], e)
+	}
+	for i := range branches {
+		branches[i].Messages = r.groupAndMapMessages(groups[i])
+	}
+	return branches
 }
 
 // groupAndMapMessages merges streaming assistant chunks into single messages
 // and maps all entries to core.Message values.
+func (r *Reader) groupAndMapMessages(entries []rawEntry) []core.Message {
+	var messages []core.Message
+	g := messageGrouper{includeRead: r.IncludeReadResults}
+	for _, entry := range entries {
+		messages = append(messages, g.add(entry)...)
+	}
+	return append(messages, g.flush()...)
+}
+
+// messageGrouper maps entries to core.Message values one at a time.
 //
 // Assistant messages arrive as multiple JSONL lines sharing the same message.id,
 // each carrying one content block. Tool-result user entries can be interleaved
-// between chunks of the same assistant message. This function handles that
+// between chunks of the same assistant message. The grouper handles that
 // interleaving by tracking the current assistant message group.
-func groupAndMapMessages(entries []rawEntry) []core.Message {
-	var messages []core.Message
-	var currentAssistant *core.Message
-	var currentMsgID string
+//
+// A compaction boundary is held back until the summary entry that follows it
+// has been folded into its event.
+type messageGrouper struct {
+	current   *core.Message
+	currentID string
+	event     *core.Message
+
+	includeRead bool // keep Read tool results
+}
 
-	emit := func() {
-		if currentAssistant != nil {
-			messages = append(messages, *currentAssistant)
-			currentAssistant = nil
-			currentMsgID = ""
+// add folds entry into the current group and returns the messages it
+// completes, in order.
+func (g *messageGrouper) add(entry rawEntry) []core.Message {
+	if isCompactBoundary(entry) {
+		done := g.flush()
+		msg := buildEventMessage(entry)
+		g.event = &msg
+		return done
+	}
+	if entry.IsCompactSummary {
+		var done []core.Message
+		if g.event == nil {
+			// A summary without a boundary still marks a compaction.
+			done = g.flush()
+			msg := buildEventMessage(entry)
+			g.event = &msg
 		}
+		g.event.Content[0].Event.Summary = compactSummary(entry)
+		return append(done, g.flush()...)
 	}
 
-	for _, entry := range entries {
-		if entry.Type == "assistant" {
-			msgID := entry.Message.ID
-			if msgID == currentMsgID && currentAssistant != nil {
-				// Same assistant message — append content blocks, update usage.
-				currentAssistant.Content = append(currentAssistant.Content,
-					mapContentBlocks(entry.Message.Content, core.RoleAssistant)...)
-				if entry.Message.Usage != nil {
-					u := mapUsage(entry.Message.Usage)
-					currentAssistant.Usage = &u
-				}
-			} else {
-				emit()
-				currentMsgID = msgID
-				msg := buildAssistantMessage(entry)
-				currentAssistant = &msg
+	var done []core.Message
+	if g.event != nil {
+		done = append(done, *g.event)
+		g.event = nil
+	}
+
+	if entry.Type == "assistant" {
+		msgID := entry.Message.ID
+		if msgID == g.currentID && g.current != nil {
+			// Same assistant message — append content blocks, update usage.
+			g.current.Content = append(g.current.Content,
+				mapContentBlocks(entry.Message.Content, core.RoleAssistant)...)
+			if entry.Message.Usage != nil {
+				u := mapUsage(entry.Message.Usage)
+				g.current.Usage = &u
 			}
-		} else {
-			// User entry.
-			if isToolResultOnly(entry) {
-				// Fold tool results into the in-progress assistant message,
-				// skipping Read results (large file content noise).
-				if currentAssistant != nil {
-					for _, raw := range entry.Message.Content {
-						b, ok := mapContentBlock(raw, core.RoleUser)
-						if !ok || b.Type != core.BlockToolResult {
-							continue
-						}
-						if isReadToolResult(currentAssistant, b.ToolUseID) {
-							continue
-						}
-						currentAssistant.Content = append(currentAssistant.Content, b)
+			return nil
+		}
+		done = append(done, g.flush()...)
+		g.currentID = msgID
+		msg := buildAssistantMessage(entry)
+		g.current = &msg
+		return done
+	}
+
+	// User entry.
+	if isToolResultOnly(entry) {
+		// Fold tool results into the in-progress assistant message,
+		// skipping Read results (large file content noise) unless asked to
+		// keep them, but always keeping any images they returned.
+		if g.current != nil {
+			for _, raw := range entry.Message.Content {
+				for _, b := range mapContentBlock(raw, core.RoleUser) {
+					// Keep tool results and the images they returned.
+					if b.ToolUseID == "" {
+						continue
 					}
+					if b.Type == core.BlockToolResult && !g.includeRead && isReadToolResult(g.current, b.ToolUseID) {
+						continue
+					}
+					g.current.Content = append(g.current.Content, b)
 				}
-			} else {
-				// Real human turn — flush pending assistant.
-				emit()
-				messages = append(messages, buildUserMessage(entry))
 			}
 		}
+		return done
+	}
+
+	// Real human turn — flush pending assistant.
+	return append(append(done, g.flush()...), buildUserMessage(entry))
+}
+
+// flush returns the in-progress assistant message and pending compaction
+// event, if any.
+func (g *messageGrouper) flush() []core.Message {
+	var done []core.Message
+	if g.current != nil {
+		done = append(done, *g.current)
+		g.current = nil
+		g.currentID = ""
+	}
+	if g.event != nil {
+		done = append(done, *g.event)
+		g.event = nil
 	}
-	emit()
-	return messages
+	return done
 }
 
 func buildAssistantMessage(entry rawEntry) core.Message {
@@ -323,6 +1047,45 @@ func buildAssistantMessage(entry rawEntry) core.Message {
 	return m
 }
 
+// isCompactBoundary reports whether entry marks a context compaction.
+func isCompactBoundary(entry rawEntry) bool {
+	return entry.Type == "system" && entry.Subtype == "compact_boundary"
+}
+
+// buildEventMessage maps a compaction entry to a system message carrying a
+// compaction event.
+func buildEventMessage(entry rawEntry) core.Message {
+	ts := parseTime(entry.Timestamp)
+	ev := &core.Event{Kind: core.EventCompaction}
+	if md := entry.CompactMetadata; md != nil {
+		ev.Trigger = md.Trigger
+		ev.PreTokens = md.PreTokens
+	}
+	m := core.Message{
+		UUID:      entry.UUID,
+		Role:      core.RoleSystem,
+		Timestamp: &ts,
+		Content:   []core.ContentBlock{{Type: core.BlockEvent, Event: ev}},
+	}
+	if entry.ParentUUID != nil {
+		m.ParentUUID = *entry.ParentUUID
+	} else if entry.LogicalParentUUID != nil {
+		m.ParentUUID = *entry.LogicalParentUUID
+	}
+	return m
+}
+
+// compactSummary joins the text blocks of a compaction summary entry.
+func compactSummary(entry rawEntry) string {
+	var parts []string
+	for _, b := range mapContentBlocks(entry.Message.Content, core.RoleUser) {
+		if b.Type == core.BlockText {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
 func buildUserMessage(entry rawEntry) core.Message {
 	ts := parseTime(entry.Timestamp)
 	m := core.Message{
@@ -341,17 +1104,17 @@ func buildUserMessage(entry rawEntry) core.Message {
 func mapContentBlocks(raw []json.RawMessage, role core.Role) []core.ContentBlock {
 	var blocks []core.ContentBlock
 	for _, r := range raw {
-		if b, ok := mapContentBlock(r, role); ok {
-			blocks = append(blocks, b)
-		}
+		blocks = append(blocks, mapContentBlock(r, role)...)
 	}
 	return blocks
 }
 
-func mapContentBlock(raw json.RawMessage, role core.Role) (core.ContentBlock, bool) {
+// mapContentBlock decodes one raw content block. A tool_result is followed by
+// an image block for each image the tool returned; unknown types yield nil.
+func mapContentBlock(raw json.RawMessage, role core.Role) []core.ContentBlock {
 	var b rawContentBlock
 	if err := json.Unmarshal(raw, &b); err != nil {
-		return core.ContentBlock{}, false
+		return nil
 	}
 
 	switch b.Type {
@@ -360,39 +1123,106 @@ func mapContentBlock(raw json.RawMessage, role core.Role) (core.ContentBlock, bo
 		if role == core.RoleAssistant {
 			format = core.FormatMarkdown
 		}
-		return core.ContentBlock{
+		return []core.ContentBlock{{
 			Type:   core.BlockText,
 			Format: format,
 			Text:   b.Text,
-		}, true
+		}}
 
 	case "thinking":
-		return core.ContentBlock{
+		return []core.ContentBlock{{
 			Type: core.BlockThinking,
 			Text: b.Thinking,
-		}, true
+		}}
 
 	case "tool_use":
-		return core.ContentBlock{
+		return []core.ContentBlock{{
 			Type:      core.BlockToolUse,
 			ToolUseID: b.ID,
 			Name:      b.Name,
 			Input:     b.Input,
-		}, true
+		}}
 
 	case "tool_result":
-		return core.ContentBlock{
+		blocks := []core.ContentBlock{{
 			Type:      core.BlockToolResult,
 			ToolUseID: b.ToolUseID,
 			Content:   extractToolResultContent(b.Content),
 			IsError:   b.IsError,
-		}, true
+		}}
+		for _, img := range extractToolResultImages(b.Content) {
+			blocks = append(blocks, core.ContentBlock{
+				Type:      core.BlockImage,
+				ToolUseID: b.ToolUseID,
+				Image:     img,
+			})
+		}
+		return blocks
+
+	case "image":
+		img := mapImage(b.Source)
+		if img == nil {
+			return nil
+		}
+		return []core.ContentBlock{{Type: core.BlockImage, Image: img}}
+
+	default:
+		return nil
+	}
+}
 
+// mapImage converts an image source. Inline data cut short by truncateLine
+// is dropped, leaving only the media type.
+func mapImage(src *rawImageSource) *core.Image {
+	if src == nil {
+		return nil
+	}
+	switch src.Type {
+	case "base64":
+		img := &core.Image{MediaType: src.MediaType}
+		if !strings.Contains(src.Data, reader.TruncatedMarker) {
+			img.Data = src.Data
+			img.Width, img.Height = core.ImageSize(src.Data)
+		}
+		return img
+	case "url":
+		return &core.Image{MediaType: src.MediaType, URL: src.URL}
 	default:
-		return core.ContentBlock{}, false
+		return nil
 	}
 }
 
+// extractToolResultImages returns the images in an array-valued tool_result
+// content, such as a Read of a screenshot.
+func extractToolResultImages(v any) []*core.Image {
+	items, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	var images []*core.Image
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok || m["type"] != "image" {
+			continue
+		}
+		src, _ := m["source"].(map[string]any)
+		if src == nil {
+			continue
+		}
+		str := func(k string) string { s, _ := src[k].(string); return s }
+		img := mapImage(&rawImageSource{
+			Type:      str("type"),
+			MediaType: str("media_type"),
+			Data:      str("data"),
+			URL:       str("url"),
+		})
+		if img != nil {
+			images = append(images, img)
+		}
+	}
+	return images
+}
+
 // extractToolResultContent handles tool_result content which can be a string
 // or an array of {"type":"text","text":"..."} objects.
 func extractToolResultContent(v any) string {
@@ -470,36 +1300,13 @@ func deriveTitle(messages []core.Message) string {
 			if text == "" || strings.Contains(text, "<ide_opened_file>") {
 				continue
 			}
-			return truncate(text, 80)
+			return reader.Truncate(text, 80)
 		}
 		break
 	}
 	return ""
 }
 
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	if i := strings.LastIndex(s[:maxLen], " "); i > 0 {
-		return s[:i] + "..."
-	}
-	return s[:maxLen] + "..."
-}
-
-func aggregateUsage(messages []core.Message) *core.Usage {
-	var total core.Usage
-	for _, m := range messages {
-		if m.Usage != nil {
-			total.Add(*m.Usage)
-		}
-	}
-	if total == (core.Usage{}) {
-		return nil
-	}
-	return &total
-}
-
 func findPrimaryModel(entries []rawEntry) string {
 	for _, e := range entries {
 		if e.Type == "assistant" && e.Message.Model != "" {
@@ -560,33 +1367,30 @@ func discoverSubagentFiles(mainPath string) (map[string]string, error) {
 // scanSubagentEntries reads JSONL lines from a sub-agent file.
 // Unlike scanEntries, it does NOT filter isSidechain (all sub-agent entries have it set).
 // Filters to user and assistant types only.
-func scanSubagentEntries(r io.Reader) ([]rawEntry, error) {
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, maxLineSize), maxLineSize)
-
+func (r *Reader) scanSubagentEntries(rd io.Reader) ([]rawEntry, error) {
 	var entries []rawEntry
-	for scanner.Scan() {
-		var entry rawEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
-			continue
+	err := r.eachLine(rd, func(n int, line []byte) bool {
+		entry, ok := r.parseEntry(rd, n, line)
+		if !ok {
+			return true
 		}
-		if entry.Type != "user" && entry.Type != "assistant" {
-			continue
+		if entry.Type == "user" || entry.Type == "assistant" {
+			entries = append(entries, entry)
 		}
-		entries = append(entries, entry)
-	}
-	return entries, scanner.Err()
+		return true
+	})
+	return entries, err
 }
 
 // buildSubagentTranscript reads a sub-agent JSONL file and returns a Transcript.
-func buildSubagentTranscript(path, parentSessionID string) (*core.Transcript, error) {
+func (r *Reader) buildSubagentTranscript(path, parentSessionID string) (*core.Transcript, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open subagent file: %w", err)
 	}
 	defer f.Close()
 
-	entries, err := scanSubagentEntries(f)
+	entries, err := r.scanSubagentEntries(f)
 	if err != nil {
 		return nil, fmt.Errorf("scan subagent file: %w", err)
 	}
@@ -594,7 +1398,7 @@ func buildSubagentTranscript(path, parentSessionID string) (*core.Transcript, er
 		return nil, nil
 	}
 
-	messages := groupAndMapMessages(entries)
+	messages := r.groupAndMapMessages(entries)
 	if len(messages) == 0 {
 		return nil, nil
 	}
@@ -620,7 +1424,7 @@ func buildSubagentTranscript(path, parentSessionID string) (*core.Transcript, er
 		Title:           deriveTitle(messages),
 		CreatedAt:       createdAt,
 		UpdatedAt:       updatedAt,
-		Usage:           aggregateUsage(messages),
+		Usage:           reader.AggregateUsage(messages),
 		Messages:        messages,
 	}, nil
 }
@@ -662,13 +1466,34 @@ func extractTaskAgentInfo(input any) core.SubAgentRef {
 
 // attachSubagents discovers, parses, and links sub-agent transcripts to the
 // main transcript. No-op when the subagents directory doesn't exist.
-func attachSubagents(mainPath string, t *core.Transcript) error {
+func (r *Reader) attachSubagents(mainPath string, t *core.Transcript) error {
+	subIndex, err := r.loadSubagents(mainPath, t)
+	if err != nil || len(subIndex) == 0 {
+		return err
+	}
+
+	// Build tool_result index from main transcript: tool_use_id → agent ID.
+	agentIDs := make(map[string]string)
+	for _, msg := range t.Messages {
+		collectAgentIDs(&msg, subIndex, agentIDs)
+	}
+
+	for i := range t.Messages {
+		linkSubagents(&t.Messages[i], agentIDs)
+	}
+
+	return nil
+}
+
+// loadSubagents parses the sub-agent files of the session at mainPath,
+// appends them to t.SubAgents and returns them indexed by agent ID.
+func (r *Reader) loadSubagents(mainPath string, t *core.Transcript) (map[string]*core.Transcript, error) {
 	files, err := discoverSubagentFiles(mainPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if files == nil {
-		return nil
+		return nil, nil
 	}
 
 	// Collect and sort agent IDs for deterministic ordering.
@@ -681,9 +1506,9 @@ func attachSubagents(mainPath string, t *core.Transcript) error {
 	// Parse each sub-agent file into a Transcript.
 	subIndex := make(map[string]*core.Transcript)
 	for _, agentID := range agentIDs {
-		sub, err := buildSubagentTranscript(files[agentID], t.SessionID)
+		sub, err := r.buildSubagentTranscript(files[agentID], t.SessionID)
 		if err != nil {
-			return fmt.Errorf("parse subagent %s: %w", agentID, err)
+			return nil, fmt.Errorf("parse subagent %s: %w", agentID, err)
 		}
 		if sub == nil {
 			continue
@@ -691,44 +1516,172 @@ func attachSubagents(mainPath string, t *core.Transcript) error {
 		t.SubAgents = append(t.SubAgents, sub)
 		subIndex[agentID] = sub
 	}
+	return subIndex, nil
+}
 
-	if len(subIndex) == 0 {
-		return nil
+// collectAgentIDs records, for each tool_result in msg that names a known
+// sub-agent, the tool_use_id → agent ID mapping. A nil subIndex records
+// every agent ID found.
+func collectAgentIDs(msg *core.Message, subIndex map[string]*core.Transcript, agentIDs map[string]string) {
+	for _, b := range msg.Content {
+		if b.Type != core.BlockToolResult || b.ToolUseID == "" {
+			continue
+		}
+		agentID := extractAgentIDFromResult(b.Content)
+		if agentID == "" {
+			delete(agentIDs, b.ToolUseID)
+			continue
+		}
+		if _, found := subIndex[agentID]; subIndex != nil && !found {
+			delete(agentIDs, b.ToolUseID)
+			continue
+		}
+		agentIDs[b.ToolUseID] = agentID
 	}
+}
 
-	// Build tool_result index from main transcript: tool_use_id → result content.
-	resultContent := make(map[string]string)
-	for _, msg := range t.Messages {
-		for _, b := range msg.Content {
-			if b.Type == core.BlockToolResult && b.ToolUseID != "" {
-				resultContent[b.ToolUseID] = b.Content
-			}
+// linkSubagents sets SubAgentRef on the Task tool_use blocks in msg whose
+// results named a sub-agent.
+func linkSubagents(msg *core.Message, agentIDs map[string]string) {
+	for j := range msg.Content {
+		b := &msg.Content[j]
+		if b.Type != core.BlockToolUse || b.Name != "Task" {
+			continue
+		}
+		agentID, ok := agentIDs[b.ToolUseID]
+		if !ok {
+			continue
 		}
+		ref := extractTaskAgentInfo(b.Input)
+		ref.AgentID = agentID
+		b.SubAgentRef = &ref
 	}
+}
 
-	// Walk all Task tool_use blocks and set SubAgentRef.
-	for i := range t.Messages {
-		for j := range t.Messages[i].Content {
-			b := &t.Messages[i].Content[j]
-			if b.Type != core.BlockToolUse || b.Name != "Task" {
-				continue
-			}
-			content, ok := resultContent[b.ToolUseID]
-			if !ok {
-				continue
-			}
-			agentID := extractAgentIDFromResult(content)
-			if agentID == "" {
-				continue
-			}
-			if _, found := subIndex[agentID]; !found {
-				continue
-			}
-			ref := extractTaskAgentInfo(b.Input)
-			ref.AgentID = agentID
-			b.SubAgentRef = &ref
+// --- Tail ---
+
+// Tail follows a session file as it is written. It implements reader.Tail.
+// Entries on abandoned branches cannot be told apart until the session ends,
+// so a tail shows every branch in file order.
+type Tail struct {
+	r      *Reader
+	path   string
+	offset int64 // byte offset just past the last complete line read
+	line   int   // number of the last complete line read
+	g      messageGrouper
+
+	header   core.Transcript
+	usage    core.Usage
+	seenUser bool
+}
+
+// TailFile implements reader.TailReader.
+func (r *Reader) TailFile(path string) (reader.Tail, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("open session file: %w", err)
+	}
+	return &Tail{
+		r:      r,
+		path:   path,
+		g:      messageGrouper{includeRead: r.IncludeReadResults},
+		header: core.Transcript{Agent: "claude"},
+	}, nil
+}
+
+// TailSession implements reader.TailReader.
+func (r *Reader) TailSession(sessionID string) (reader.Tail, error) {
+	path, err := r.sessionPath(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return r.TailFile(path)
+}
+
+// Next implements reader.Tail.
+func (t *Tail) Next() ([]core.Message, error) {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("open session file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(t.offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek session file: %w", err)
+	}
+
+	limit := t.r.lineLimit()
+	br := bufio.NewReader(f)
+	var done []core.Message
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			// A line without its newline is still being written.
+			return done, nil
+		}
+		if err != nil {
+			return done, fmt.Errorf("read session file: %w", err)
 		}
+		t.offset += int64(len(line))
+		t.line++
+		if len(line) > limit {
+			line = truncateLine(line, min(limit, reader.TruncatedFieldSize))
+		}
+
+		entry, ok := t.r.parseEntry(f, t.line, line)
+		if !ok || entry.IsSidechain || !isMessageEntry(entry) {
+			continue
+		}
+		t.observe(entry)
+		for _, m := range t.g.add(entry) {
+			t.complete(&m)
+			done = append(done, m)
+		}
+	}
+}
+
+// Flush implements reader.Tail.
+func (t *Tail) Flush() []core.Message {
+	done := t.g.flush()
+	for i := range done {
+		t.complete(&done[i])
 	}
+	return done
+}
 
-	return nil
+// Header implements reader.Tail.
+func (t *Tail) Header() *core.Transcript {
+	h := t.header
+	if t.usage != (core.Usage{}) {
+		u := t.usage
+		h.Usage = &u
+	}
+	return &h
+}
+
+// observe records the header fields carried by entry.
+func (t *Tail) observe(entry rawEntry) {
+	h := &t.header
+	if h.SessionID == "" {
+		h.SessionID = entry.SessionID
+		h.Author = reader.GitAuthor(entry.CWD)
+		h.Dir = entry.CWD
+		h.GitBranch = entry.GitBranch
+		h.CreatedAt = parseTime(entry.Timestamp)
+	} else if ts := parseTime(entry.Timestamp); !ts.Equal(h.CreatedAt) {
+		h.UpdatedAt = &ts
+	}
+	if h.Model == "" && entry.Type == "assistant" {
+		h.Model = entry.Message.Model
+	}
+}
+
+// complete records the header fields carried by a completed message.
+func (t *Tail) complete(m *core.Message) {
+	if m.Usage != nil {
+		t.usage.Add(*m.Usage)
+	}
+	// deriveTitle only considers the first user message.
+	if m.Role == core.RoleUser && !t.seenUser {
+		t.seenUser = true
+		t.header.Title = deriveTitle([]core.Message{*m})
+	}
 }
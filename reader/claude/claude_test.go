@@ -1,11 +1,16 @@
 package claude
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -65,7 +70,7 @@ func TestScanEntries(t *testing.T) {
 			require.NoError(t, err)
 			defer f.Close()
 
-			entries, err := scanEntries(f)
+			entries, err := (&Reader{}).scanEntries(f, nil)
 			require.NoError(t, err)
 			assert.Len(t, entries, tt.wantCount)
 		})
@@ -74,11 +79,12 @@ func TestScanEntries(t *testing.T) {
 
 func TestGroupAndMapMessages(t *testing.T) {
 	tests := []struct {
-		name       string
-		file       string
-		wantMsgs   int
-		wantBlocks []int
-		wantRoles  []core.Role
+		name        string
+		file        string
+		includeRead bool
+		wantMsgs    int
+		wantBlocks  []int
+		wantRoles   []core.Role
 	}{
 		{
 			name:       "simple user-assistant pair",
@@ -101,6 +107,14 @@ func TestGroupAndMapMessages(t *testing.T) {
 			wantBlocks: []int{1, 3},
 			wantRoles:  []core.Role{core.RoleUser, core.RoleAssistant},
 		},
+		{
+			name:        "Read results kept when requested",
+			file:        "tool_loop.jsonl",
+			includeRead: true,
+			wantMsgs:    2,
+			wantBlocks:  []int{1, 4},
+			wantRoles:   []core.Role{core.RoleUser, core.RoleAssistant},
+		},
 		{
 			name:       "new human turn flushes pending assistant",
 			file:       "multi_turn.jsonl",
@@ -116,10 +130,11 @@ func TestGroupAndMapMessages(t *testing.T) {
 			require.NoError(t, err)
 			defer f.Close()
 
-			entries, err := scanEntries(f)
+			r := &Reader{IncludeReadResults: tt.includeRead}
+			entries, err := r.scanEntries(f, nil)
 			require.NoError(t, err)
 
-			messages := groupAndMapMessages(entries)
+			messages := r.groupAndMapMessages(entries)
 			require.Len(t, messages, tt.wantMsgs)
 
 			for i, m := range messages {
@@ -178,6 +193,46 @@ func TestContentBlockMapping(t *testing.T) {
 	})
 }
 
+func TestImageBlocks(t *testing.T) {
+	tr := readTestdata(t, "image.jsonl")
+	require.Len(t, tr.Messages, 4)
+
+	t.Run("pasted screenshot", func(t *testing.T) {
+		blocks := tr.Messages[0].Content
+		require.Len(t, blocks, 2)
+		b := blocks[1]
+		assert.Equal(t, core.BlockImage, b.Type)
+		assert.Empty(t, b.ToolUseID)
+		require.NotNil(t, b.Image)
+		assert.Equal(t, "image/png", b.Image.MediaType)
+		assert.NotEmpty(t, b.Image.Data)
+		assert.Equal(t, 4, b.Image.Width)
+		assert.Equal(t, 3, b.Image.Height)
+	})
+
+	t.Run("image returned by Read is kept", func(t *testing.T) {
+		// The Read result text is dropped, but its image is folded into the
+		// assistant message after the tool call.
+		blocks := tr.Messages[1].Content
+		require.Len(t, blocks, 2)
+		assert.Equal(t, core.BlockToolUse, blocks[0].Type)
+		assert.Equal(t, core.BlockImage, blocks[1].Type)
+		assert.Equal(t, "toolu_1", blocks[1].ToolUseID)
+		assert.Equal(t, 4, blocks[1].Image.Width)
+	})
+
+	t.Run("external image", func(t *testing.T) {
+		b := tr.Messages[3].Content[1]
+		assert.Equal(t, core.BlockImage, b.Type)
+		assert.Equal(t, &core.Image{URL: "https://example.com/mockup.png"}, b.Image)
+	})
+}
+
+func TestImageTruncated(t *testing.T) {
+	src := &rawImageSource{Type: "base64", MediaType: "image/png", Data: "iVBORw0K" + reader.TruncatedMarker + "99 bytes]"}
+	assert.Equal(t, &core.Image{MediaType: "image/png"}, mapImage(src))
+}
+
 func TestExtractToolResultContent(t *testing.T) {
 	tests := []struct {
 		name string
@@ -286,6 +341,116 @@ func TestReadAll(t *testing.T) {
 	assert.Len(t, transcripts, 2)
 }
 
+func TestMergeResumed(t *testing.T) {
+	r := setupProjectDir(t, "resume_first.jsonl", "-my-project", "sess-r1")
+	data, err := os.ReadFile(testdataPath("resume_second.jsonl"))
+	require.NoError(t, err)
+	second := filepath.Join(r.Dir, "-my-project", "sess-r2.jsonl")
+	require.NoError(t, os.WriteFile(second, data, 0o644))
+
+	transcripts, err := r.ReadProject("-my-project")
+	require.NoError(t, err)
+	assert.Len(t, transcripts, 2, "sessions stay separate by default")
+
+	r.MergeResumed = true
+	transcripts, err = r.ReadProject("-my-project")
+	require.NoError(t, err)
+	require.Len(t, transcripts, 1)
+
+	tr := transcripts[0]
+	assert.Equal(t, "sess-r1", tr.SessionID)
+	assert.Equal(t, []string{"sess-r1", "sess-r2"}, tr.SessionIDs)
+	assert.Equal(t, &core.Usage{InputTokens: 30, OutputTokens: 13}, tr.Usage)
+	require.Len(t, tr.Messages, 5)
+	assert.Equal(t, "Login added.", tr.Messages[1].Content[0].Text)
+	assert.Equal(t, &core.Event{Kind: core.EventResume, SessionID: "sess-r2"}, core.EventOf(&tr.Messages[2]))
+	assert.Equal(t, "Now add tests", tr.Messages[3].Content[0].Text)
+
+	// Reading either file returns the whole chain.
+	got, err := r.ReadFile(second)
+	require.NoError(t, err)
+	assert.Equal(t, tr, got)
+	got, err = r.ReadSession("sess-r1")
+	require.NoError(t, err)
+	assert.Equal(t, tr.SessionIDs, got.SessionIDs)
+
+	// Only sessions linked to the one read are parsed: an unrelated broken
+	// file in the same directory is not reported.
+	report := &reader.Report{}
+	r.Report = report
+	require.NoError(t, os.WriteFile(filepath.Join(r.Dir, "-my-project", "late.jsonl"), []byte("not json\n"), 0o644))
+	got, err = r.ReadSession("sess-r2")
+	require.NoError(t, err)
+	assert.Equal(t, tr.SessionIDs, got.SessionIDs)
+	assert.Empty(t, report.Warnings())
+}
+
+func TestMergeResumedReadFileMatchesReadProject(t *testing.T) {
+	r := setupProjectDir(t, "resume_first.jsonl", "-my-project", "sess-r1")
+	data, err := os.ReadFile(testdataPath("resume_second.jsonl"))
+	require.NoError(t, err)
+	projectDir := filepath.Join(r.Dir, "-my-project")
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "sess-r2.jsonl"), data, 0o644))
+
+	// A later session that also resumes sess-r1 starts a chain of its own.
+	third := strings.NewReplacer(
+		"sess-r2", "sess-r3",
+		`"r-u2"`, `"r-u3"`,
+		`"r-a2"`, `"r-a3"`,
+		"2025-01-16", "2025-01-17",
+	).Replace(string(data))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "sess-r3.jsonl"), []byte(third), 0o644))
+
+	r.MergeResumed = true
+	transcripts, err := r.ReadProject("-my-project")
+	require.NoError(t, err)
+	require.Len(t, transcripts, 2)
+	assert.Equal(t, []string{"sess-r1", "sess-r2"}, transcripts[0].SessionIDs)
+	assert.Equal(t, "sess-r3", transcripts[1].SessionID)
+
+	for _, tr := range transcripts {
+		ids := append([]string{tr.SessionID}, tr.SessionIDs...)
+		for _, id := range ids {
+			got, err := r.ReadSession(id)
+			require.NoError(t, err)
+			assert.Equal(t, tr, got, "reading %s", id)
+		}
+	}
+}
+
+func TestContinuesFrom(t *testing.T) {
+	lines := []string{
+		`{"type":"summary","summary":"Earlier work","leafUuid":"old-leaf"}`,
+		`{"type":"user","sessionId":"s2","uuid":"u1","parentUuid":"old-leaf","timestamp":"2025-01-15T10:00:00Z","message":{"role":"user","content":[{"type":"text","text":"continue"}]}}`,
+	}
+	path := filepath.Join(t.TempDir(), "s2.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+	r := &Reader{}
+	tr, err := r.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old-leaf", tr.ContinuesFrom)
+
+	header, _, err := r.StreamFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old-leaf", header.ContinuesFrom)
+
+	tr = readTestdata(t, "simple.jsonl")
+	assert.Empty(t, tr.ContinuesFrom)
+}
+
+func TestSniff(t *testing.T) {
+	r := setupProjectDir(t, "simple.jsonl", "-project-a", "sess-1")
+
+	assert.True(t, r.Sniff(testdataPath("simple.jsonl")))
+	assert.True(t, r.Sniff(filepath.Join(r.Dir, "-project-a")))
+	assert.True(t, r.Sniff(r.Dir))
+
+	assert.False(t, r.Sniff("../codex/testdata/simple.jsonl"))
+	assert.False(t, r.Sniff(testdataPath("nonexistent.jsonl")))
+	assert.False(t, r.Sniff(t.TempDir()))
+}
+
 // --- Sub-agent tests ---
 
 // setupSubagentDir creates a temp directory with a main session file and a
@@ -346,7 +511,7 @@ func TestScanSubagentEntries(t *testing.T) {
 	require.NoError(t, err)
 	defer f.Close()
 
-	entries, err := scanSubagentEntries(f)
+	entries, err := (&Reader{}).scanSubagentEntries(f)
 	require.NoError(t, err)
 	assert.Len(t, entries, 4)
 
@@ -359,7 +524,7 @@ func TestScanSubagentEntries(t *testing.T) {
 
 func TestBuildSubagentTranscript(t *testing.T) {
 	path := testdataPath("subagent_child.jsonl")
-	sub, err := buildSubagentTranscript(path, "sess-main-1")
+	sub, err := (&Reader{}).buildSubagentTranscript(path, "sess-main-1")
 	require.NoError(t, err)
 	require.NotNil(t, sub)
 
@@ -512,3 +677,332 @@ func TestAttachSubagents(t *testing.T) {
 		assert.Nil(t, tr.SubAgents)
 	})
 }
+
+func TestStreamFile(t *testing.T) {
+	paths := []string{
+		testdataPath("simple.jsonl"),
+		testdataPath("multi_turn.jsonl"),
+		testdataPath("streaming_chunks.jsonl"),
+		testdataPath("tool_loop.jsonl"),
+		testdataPath("tool_error.jsonl"),
+		testdataPath("all_block_types.jsonl"),
+		testdataPath("ide_title.jsonl"),
+		testdataPath("mixed_entries.jsonl"),
+		testdataPath("branch.jsonl"),
+		testdataPath("compaction.jsonl"),
+		testdataPath("resume_second.jsonl"),
+		setupSubagentDir(t, "subagent_main.jsonl", "subagent_child.jsonl", "ae267a1"),
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			r := &Reader{}
+			want, err := r.ReadFile(path)
+			require.NoError(t, err)
+
+			header, messages, err := r.StreamFile(path)
+			require.NoError(t, err)
+			assert.Nil(t, header.Messages)
+
+			var got []core.Message
+			for m, err := range messages {
+				require.NoError(t, err)
+				got = append(got, m)
+			}
+			header.Messages = got
+			assert.Equal(t, want, header)
+		})
+	}
+}
+
+func TestStreamFileEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"type":"summary"}`+"\n"), 0o644))
+
+	_, _, err := (&Reader{}).StreamFile(path)
+	assert.EqualError(t, err, "no messages found in session")
+}
+
+func TestReadFileLongLines(t *testing.T) {
+	big := strings.Repeat("x", 2<<20)
+	lines := []string{
+		`{"type":"user","sessionId":"s1","uuid":"u1","timestamp":"2025-01-15T10:00:00Z","message":{"role":"user","content":[{"type":"text","text":"dump it"}]}}`,
+		`{"type":"assistant","sessionId":"s1","uuid":"a1","timestamp":"2025-01-15T10:00:01Z","message":{"id":"m1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"cat big.log"}}],"usage":{"input_tokens":3,"output_tokens":4}}}`,
+		`{"type":"user","sessionId":"s1","uuid":"u2","timestamp":"2025-01-15T10:00:02Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"` + big + `"}]},"toolUseResult":{"stdout":"` + big + `"}}`,
+		`{"type":"assistant","sessionId":"s1","uuid":"a2","timestamp":"2025-01-15T10:00:03Z","message":{"id":"m2","role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"done"}]}}`,
+	}
+	path := filepath.Join(t.TempDir(), "s1.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+	tests := []struct {
+		name string
+		r    *Reader
+		keep int
+	}{
+		{"default threshold", &Reader{}, reader.TruncatedFieldSize},
+		{"custom threshold", &Reader{MaxLineSize: 1024}, 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := tt.r.ReadFile(path)
+			require.NoError(t, err)
+			require.Len(t, tr.Messages, 3)
+
+			blocks := tr.Messages[1].Content
+			require.Len(t, blocks, 2)
+			assert.Equal(t, "cat big.log", blocks[0].Input.(map[string]any)["command"])
+			result := blocks[1].Content
+			assert.Equal(t, strings.Repeat("x", tt.keep)+
+				fmt.Sprintf("\n[… truncated %d bytes]", len(big)-tt.keep), result)
+			assert.Equal(t, &core.Usage{InputTokens: 3, OutputTokens: 4}, tr.Messages[1].Usage)
+			assert.Equal(t, "done", tr.Messages[2].Content[0].Text)
+		})
+	}
+}
+
+func TestCompaction(t *testing.T) {
+	r := &Reader{}
+	tr, err := r.ReadFile(testdataPath("compaction.jsonl"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Refactor the parser", tr.Title)
+	assert.Empty(t, tr.Branches)
+	require.Len(t, tr.Messages, 5)
+
+	ev := tr.Messages[2]
+	assert.Equal(t, core.RoleSystem, ev.Role)
+	assert.Equal(t, "c1", ev.UUID)
+	assert.Equal(t, "a1", ev.ParentUUID)
+	require.Len(t, ev.Content, 1)
+	assert.Equal(t, core.BlockEvent, ev.Content[0].Type)
+	assert.Equal(t, &core.Event{
+		Kind:      core.EventCompaction,
+		Trigger:   "auto",
+		PreTokens: 155123,
+		Summary:   "This session is being continued from a previous conversation. The parser refactor is half done.",
+	}, ev.Content[0].Event)
+
+	assert.Equal(t, core.RoleUser, tr.Messages[3].Role)
+	assert.Equal(t, "Keep going", tr.Messages[3].Content[0].Text)
+
+	turns := core.GroupTurns(tr.Messages)
+	require.Len(t, turns, 3)
+	assert.Equal(t, &tr.Messages[2], turns[1].Event)
+}
+
+func TestBranches(t *testing.T) {
+	tr := readTestdata(t, "branch.jsonl")
+
+	var uuids []string
+	for _, m := range tr.Messages {
+		uuids = append(uuids, m.UUID)
+	}
+	assert.Equal(t, []string{"u1", "a1", "a2", "u4", "a4", "u5", "a5"}, uuids)
+	assert.Equal(t, "2025-01-15T10:03:01Z", tr.UpdatedAt.Format(time.RFC3339))
+
+	require.Len(t, tr.Branches, 1)
+	b := tr.Branches[0]
+	assert.Equal(t, "u3", b.ID)
+	assert.Equal(t, "a2", b.ParentUUID)
+	require.Len(t, b.Messages, 2)
+	assert.Equal(t, "Now add logout", b.Messages[0].Content[0].Text)
+	assert.Equal(t, "Logout added.", b.Messages[1].Content[0].Text)
+
+	// The replacing prompt shares the branch's parent.
+	assert.Equal(t, b.ParentUUID, tr.Messages[3].ParentUUID)
+}
+
+func TestEntryTreeBranches(t *testing.T) {
+	entry := func(typ, uuid, parent string, content string) rawEntry {
+		e := rawEntry{Type: typ, UUID: uuid}
+		if parent != "" {
+			e.ParentUUID = &parent
+		}
+		e.Message.Content = []json.RawMessage{json.RawMessage(content)}
+		return e
+	}
+	prompt := `{"type":"text","text":"hi"}`
+	result := `{"type":"tool_result","tool_use_id":"t1","content":"ok"}`
+	text := `{"type":"text","text":"ok"}`
+
+	tests := []struct {
+		name    string
+		entries []rawEntry
+		want    map[string]string
+	}{
+		{
+			name: "linear",
+			entries: []rawEntry{
+				entry("user", "u1", "", prompt),
+				entry("assistant", "a1", "u1", text),
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "unlinked entries stay in main line",
+			entries: []rawEntry{
+				entry("user", "u1", "", prompt),
+				entry("assistant", "a1", "", text),
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "tool result sibling is not a branch",
+			entries: []rawEntry{
+				entry("user", "u1", "", prompt),
+				entry("assistant", "a1", "u1", text),
+				entry("assistant", "a2", "a1", text),
+				entry("user", "r1", "a1", result),
+				entry("user", "r2", "a2", result),
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "rewound with nested replies",
+			entries: []rawEntry{
+				entry("user", "u1", "", prompt),
+				entry("assistant", "a1", "u1", text),
+				entry("user", "u2", "a1", prompt),
+				entry("assistant", "a2", "u2", text),
+				entry("user", "u3", "a2", prompt),
+				entry("user", "u4", "a1", prompt),
+			},
+			want: map[string]string{"u2": "u2", "a2": "u2", "u3": "u2"},
+		},
+		{
+			name: "compaction follows the logical parent",
+			entries: func() []rawEntry {
+				after := entry("user", "u2", "", prompt)
+				logical := "a1"
+				after.LogicalParentUUID = &logical
+				return []rawEntry{
+					entry("user", "u1", "", prompt),
+					entry("assistant", "a1", "u1", text),
+					after,
+				}
+			}(),
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tree entryTree
+			for _, e := range tt.entries {
+				tree.add(e)
+			}
+			assert.Equal(t, tt.want, tree.branches())
+		})
+	}
+}
+
+func TestReportWarnings(t *testing.T) {
+	lines := []string{
+		`{"type":"user","sessionId":"s1","uuid":"u1","timestamp":"2025-01-15T10:00:00Z","message":{"role":"user","content":[{"type":"text","text":"hi"}]}}`,
+		`{"type":"assistant","sessionId":"s1","uuid":"a1",`,
+		``,
+		`{"type":"assistant","sessionId":"s1","uuid":"a2","parentUuid":"u1","timestamp":"2025-01-15T10:00:01Z","message":{"id":"m1","role":"assistant","content":[{"type":"server_tool_use","id":"x"},{"type":"text","text":"hello"}]}}`,
+	}
+	projectDir := filepath.Join(t.TempDir(), "proj")
+	require.NoError(t, os.MkdirAll(projectDir, 0o755))
+	good := filepath.Join(projectDir, "s1.jsonl")
+	require.NoError(t, os.WriteFile(good, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+	empty := filepath.Join(projectDir, "s2.jsonl")
+	require.NoError(t, os.WriteFile(empty, nil, 0o644))
+
+	report := &reader.Report{}
+	r := &Reader{Dir: filepath.Dir(projectDir), Report: report}
+	ts, err := r.ReadProject("proj")
+	require.NoError(t, err)
+	require.Len(t, ts, 1)
+	assert.Len(t, ts[0].Messages, 2)
+
+	warnings := report.Warnings()
+	require.Len(t, warnings, 3)
+	assert.Equal(t, reader.WarnMalformedLine, warnings[0].Kind)
+	assert.Equal(t, good, warnings[0].Path)
+	assert.Equal(t, 2, warnings[0].Line)
+	assert.Equal(t, reader.Warning{
+		Kind:    reader.WarnUnknownBlock,
+		Path:    good,
+		Line:    4,
+		Message: `content block type "server_tool_use"`,
+	}, warnings[1])
+	assert.Equal(t, reader.WarnSkippedFile, warnings[2].Kind)
+	assert.Equal(t, empty, warnings[2].Path)
+}
+
+func TestTail(t *testing.T) {
+	lines := []string{
+		`{"type":"user","sessionId":"s1","uuid":"u1","cwd":"/work","timestamp":"2025-01-15T10:00:00Z","message":{"role":"user","content":[{"type":"text","text":"add tests"}]}}`,
+		`{"type":"assistant","sessionId":"s1","uuid":"a1","parentUuid":"u1","timestamp":"2025-01-15T10:00:01Z","message":{"id":"m1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test"}}],"usage":{"input_tokens":3,"output_tokens":4}}}`,
+		`{"type":"user","sessionId":"s1","uuid":"u2","parentUuid":"a1","timestamp":"2025-01-15T10:00:02Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`,
+		`{"type":"assistant","sessionId":"s1","uuid":"a2","parentUuid":"u2","timestamp":"2025-01-15T10:00:03Z","message":{"id":"m2","role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"done"}],"usage":{"input_tokens":5,"output_tokens":6}}}`,
+	}
+	path := filepath.Join(t.TempDir(), "s1.jsonl")
+	appendLines := func(s string) {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		require.NoError(t, err)
+		_, err = f.WriteString(s)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	appendLines(lines[0] + "\n" + lines[1] + "\n")
+	tail, err := (&Reader{}).TailFile(path)
+	require.NoError(t, err)
+
+	// The assistant message may still receive tool results.
+	got, err := tail.Next()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, core.RoleUser, got[0].Role)
+
+	h := tail.Header()
+	assert.Equal(t, "s1", h.SessionID)
+	assert.Equal(t, "add tests", h.Title)
+	assert.Equal(t, "claude-sonnet-4", h.Model)
+	assert.Equal(t, "/work", h.Dir)
+
+	// A partially written line is left for the next call.
+	appendLines(lines[2] + "\n" + lines[3][:40])
+	got, err = tail.Next()
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	appendLines(lines[3][40:] + "\n")
+	got, err = tail.Next()
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Len(t, got[0].Content, 2)
+	assert.Equal(t, core.BlockToolResult, got[0].Content[1].Type)
+
+	got = tail.Flush()
+	require.Len(t, got, 1)
+	assert.Equal(t, "done", got[0].Content[0].Text)
+	assert.Equal(t, &core.Usage{InputTokens: 8, OutputTokens: 10}, tail.Header().Usage)
+
+	got, err = tail.Next()
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestTailSession(t *testing.T) {
+	r := &Reader{Dir: t.TempDir()}
+	projectDir := filepath.Join(r.Dir, "proj")
+	require.NoError(t, os.MkdirAll(projectDir, 0o755))
+	data, err := os.ReadFile(testdataPath("simple.jsonl"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "sess-1.jsonl"), data, 0o644))
+
+	tail, err := r.TailSession("sess-1")
+	require.NoError(t, err)
+	got, err := tail.Next()
+	require.NoError(t, err)
+
+	want, err := r.ReadSession("sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, want.Messages, append(got, tail.Flush()...))
+
+	_, err = r.TailSession("missing")
+	assert.Error(t, err)
+}
@@ -1,8 +1,733 @@
 // Package codex reads OpenAI Codex CLI session logs (JSONL rollouts in ~/.codex/sessions/).
 package codex
 
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+)
+
 // Reader reads Codex CLI JSONL rollout files.
 type Reader struct {
 	// Dir overrides the default session directory (~/.codex/sessions/).
 	Dir string
+
+	// Report, when set, collects warnings about skipped sessions, malformed
+	// lines and records of unknown types.
+	Report *reader.Report
+}
+
+// maxLineSize is the rollout line length above which long strings are
+// truncated (1 MB). Function call outputs with large command output regularly
+// exceed it; lines of any length are read.
+const maxLineSize = 1 << 20
+
+// Raw JSON deserialization types. These mirror the rollout structure on disk.
+//
+// Each rollout line is an envelope {"timestamp", "type", "payload"} where type
+// is one of session_meta, turn_context, response_item, event_msg or compacted.
+// Rollouts written by older Codex versions have no envelope: the first line is
+// the session metadata and every following line is a bare response item.
+
+type rawLine struct {
+	Timestamp string          `json:"timestamp"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+type rawSessionMeta struct {
+	ID        string  `json:"id"`
+	Timestamp string  `json:"timestamp"`
+	CWD       string  `json:"cwd"`
+	Git       *rawGit `json:"git"`
+}
+
+type rawGit struct {
+	Branch string `json:"branch"`
+}
+
+type rawTurnContext struct {
+	CWD   string `json:"cwd"`
+	Model string `json:"model"`
+}
+
+type rawItem struct {
+	Type      string           `json:"type"`
+	Role      string           `json:"role"`
+	Content   []rawContentPart `json:"content"`
+	Summary   []rawContentPart `json:"summary"`
+	Name      string           `json:"name"`
+	Arguments string           `json:"arguments"`
+	Input     string           `json:"input"`
+	CallID    string           `json:"call_id"`
+	Output    json.RawMessage  `json:"output"`
+	Action    map[string]any   `json:"action"`
+}
+
+type rawContentPart struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type rawEvent struct {
+	Type string        `json:"type"`
+	Info *rawTokenInfo `json:"info"`
+}
+
+type rawTokenInfo struct {
+	TotalTokenUsage rawUsage `json:"total_token_usage"`
+	LastTokenUsage  rawUsage `json:"last_token_usage"`
+}
+
+type rawUsage struct {
+	InputTokens       int `json:"input_tokens"`
+	CachedInputTokens int `json:"cached_input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+}
+
+// rawExecOutput is the JSON document Codex stores in function_call_output for
+// shell-style tools.
+type rawExecOutput struct {
+	Output   string `json:"output"`
+	Metadata *struct {
+		ExitCode int `json:"exit_code"`
+	} `json:"metadata"`
+}
+
+// entry is a single decoded rollout line with its timestamp.
+type entry struct {
+	Timestamp string
+	Kind      string // session_meta, turn_context, response_item, event_msg
+	Meta      *rawSessionMeta
+	Context   *rawTurnContext
+	Item      *rawItem
+	Event     *rawEvent
+}
+
+// ReadFile parses a single Codex rollout file.
+func (r *Reader) ReadFile(path string) (*core.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open session file: %w", err)
+	}
+	defer f.Close()
+
+	entries, err := r.scanEntries(f)
+	if err != nil {
+		return nil, fmt.Errorf("scan session file: %w", err)
+	}
+
+	return buildTranscript(entries)
+}
+
+// ReadSession locates and parses a session by its UUID. Rollout file names
+// end with the session ID: rollout-<timestamp>-<sessionID>.jsonl.
+func (r *Reader) ReadSession(sessionID string) (*core.Transcript, error) {
+	files, err := r.rolloutFiles()
+	if err != nil {
+		return nil, err
+	}
+	suffix := "-" + sessionID + ".jsonl"
+	for _, path := range files {
+		if strings.HasSuffix(filepath.Base(path), suffix) {
+			return r.ReadFile(path)
+		}
+	}
+	return nil, fmt.Errorf("session %s not found", sessionID)
+}
+
+// ReadProject returns all sessions whose working directory matches project.
+// Codex does not group rollouts by project on disk, so project is matched
+// against each session's cwd, either verbatim or in Claude's dash-encoded
+// form ("/Users/foo/bar" → "-Users-foo-bar").
+func (r *Reader) ReadProject(project string) ([]*core.Transcript, error) {
+	all, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var transcripts []*core.Transcript
+	for _, t := range all {
+		if t.Dir == project || strings.ReplaceAll(t.Dir, "/", "-") == project {
+			transcripts = append(transcripts, t)
+		}
+	}
+	return transcripts, nil
+}
+
+// ReadAll returns every session transcript under the sessions directory.
+func (r *Reader) ReadAll() ([]*core.Transcript, error) {
+	files, err := r.rolloutFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*core.Transcript
+	for _, path := range files {
+		t, err := r.ReadFile(path)
+		if err != nil {
+			r.Report.SkippedFile(path, err)
+			continue
+		}
+		all = append(all, t)
+	}
+	return all, nil
+}
+
+func (r *Reader) dir() string {
+	if r.Dir != "" {
+		return r.Dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".codex", "sessions")
+}
+
+// Sniff reports whether path is a Codex rollout file or a sessions directory
+// containing rollouts.
+func (r *Reader) Sniff(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		return sniffFile(path)
+	}
+	for _, pattern := range []string{"rollout-*.jsonl", filepath.Join("*", "*", "*", "rollout-*.jsonl")} {
+		matches, _ := filepath.Glob(filepath.Join(path, pattern))
+		if len(matches) > 0 && sniffFile(matches[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffFile checks the first line of a JSONL file for session metadata,
+// either in an envelope or as a bare legacy header.
+func sniffFile(path string) bool {
+	if !strings.HasSuffix(path, ".jsonl") {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	var line []byte
+	if err := eachLine(f, func(_ int, l []byte) bool {
+		line = l
+		return false
+	}); err != nil || line == nil {
+		return false
+	}
+	var first struct {
+		rawLine
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(line, &first); err != nil {
+		return false
+	}
+	if first.Type == "session_meta" {
+		return true
+	}
+	return first.Type == "" && first.ID != "" && first.Timestamp != ""
+}
+
+// rolloutFiles walks the YYYY/MM/DD tree and returns all rollout-*.jsonl
+// paths in lexical (chronological) order.
+func (r *Reader) rolloutFiles() ([]string, error) {
+	dir := r.dir()
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("read sessions directory: %w", err)
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := d.Name()
+		if d.IsDir() || !strings.HasPrefix(name, "rollout-") || !strings.HasSuffix(name, ".jsonl") {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk sessions directory: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// scanEntries reads rollout lines, decoding the payload of each envelope.
+// Lines that cannot be parsed or carry irrelevant record types are skipped;
+// malformed lines and records of unknown types are reported.
+func (r *Reader) scanEntries(rd io.Reader) ([]entry, error) {
+	var entries []entry
+	first := true
+	err := eachLine(rd, func(n int, line []byte) bool {
+		var raw rawLine
+		if err := json.Unmarshal(line, &raw); err != nil {
+			if len(bytes.TrimSpace(line)) > 0 {
+				r.warn(rd, n, reader.WarnMalformedLine, err.Error())
+			}
+			return true
+		}
+		var e entry
+		var ok bool
+		if len(raw.Payload) == 0 {
+			// Legacy rollout without an envelope.
+			e, ok = decodeLegacy(line, first)
+		} else {
+			if !knownRecordTypes[raw.Type] {
+				r.warn(rd, n, reader.WarnUnknownBlock, fmt.Sprintf("record type %q", raw.Type))
+			}
+			e, ok = decodeEnvelope(raw)
+		}
+		first = false
+		if !ok {
+			return true
+		}
+		if it := e.Item; it != nil && !knownItemTypes[it.Type] {
+			r.warn(rd, n, reader.WarnUnknownBlock, fmt.Sprintf("response item type %q", it.Type))
+		}
+		entries = append(entries, e)
+		return true
+	})
+	return entries, err
+}
+
+// knownRecordTypes are the rollout envelope types the reader understands,
+// including those it deliberately ignores.
+var knownRecordTypes = map[string]bool{
+	"session_meta":  true,
+	"turn_context":  true,
+	"response_item": true,
+	"event_msg":     true,
+	"compacted":     true,
+}
+
+// knownItemTypes are the response item types mapMessages maps.
+var knownItemTypes = map[string]bool{
+	"message":                 true,
+	"reasoning":               true,
+	"function_call":           true,
+	"function_call_output":    true,
+	"custom_tool_call":        true,
+	"custom_tool_call_output": true,
+	"local_shell_call":        true,
+}
+
+// warn reports skipped data on line n of rd.
+func (r *Reader) warn(rd io.Reader, n int, kind reader.WarningKind, msg string) {
+	path := ""
+	if f, ok := rd.(interface{ Name() string }); ok {
+		path = f.Name()
+	}
+	r.Report.Add(reader.Warning{Kind: kind, Path: path, Line: n, Message: msg})
+}
+
+// eachLine calls fn with the 1-based number of each line of rd until fn
+// returns false. Lines longer than maxLineSize have their strings truncated by
+// truncateLine.
+func eachLine(rd io.Reader, fn func(int, []byte) bool) error {
+	br := bufio.NewReader(rd)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(line) > maxLineSize {
+			line = truncateLine(line, reader.TruncatedFieldSize)
+		}
+		if len(line) > 0 && !fn(n, line) {
+			return nil
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// truncateLine shortens every string longer than keep bytes in the payload of
+// a rollout line, or in the line itself for legacy rollouts. Tool output that
+// is itself a JSON document is truncated inside, so it still decodes. Lines
+// that are not JSON objects are returned unchanged.
+func truncateLine(line []byte, keep int) []byte {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var entry map[string]any
+	if err := dec.Decode(&entry); err != nil {
+		return line
+	}
+	payload, ok := entry["payload"].(map[string]any)
+	if !ok {
+		payload = entry
+	}
+	for k, v := range payload {
+		if s, ok := v.(string); ok && k == "output" {
+			payload[k] = truncateOutput(s, keep)
+			continue
+		}
+		payload[k] = reader.TruncateStrings(v, keep)
+	}
+	out, err := json.Marshal(entry)
+	if err != nil {
+		return line
+	}
+	return out
+}
+
+// truncateOutput truncates a function_call_output string, which holds either
+// plain text or a JSON-encoded rawExecOutput.
+func truncateOutput(s string, keep int) string {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return reader.TruncateStrings(s, keep).(string)
+	}
+	out, err := json.Marshal(reader.TruncateStrings(doc, keep))
+	if err != nil {
+		return reader.TruncateStrings(s, keep).(string)
+	}
+	return string(out)
+}
+
+func decodeEnvelope(raw rawLine) (entry, bool) {
+	e := entry{Timestamp: raw.Timestamp, Kind: raw.Type}
+	switch raw.Type {
+	case "session_meta":
+		var m rawSessionMeta
+		if err := json.Unmarshal(raw.Payload, &m); err != nil {
+			return entry{}, false
+		}
+		e.Meta = &m
+	case "turn_context":
+		var c rawTurnContext
+		if err := json.Unmarshal(raw.Payload, &c); err != nil {
+			return entry{}, false
+		}
+		e.Context = &c
+	case "response_item":
+		var it rawItem
+		if err := json.Unmarshal(raw.Payload, &it); err != nil {
+			return entry{}, false
+		}
+		e.Item = &it
+	case "event_msg":
+		var ev rawEvent
+		if err := json.Unmarshal(raw.Payload, &ev); err != nil {
+			return entry{}, false
+		}
+		if ev.Type != "token_count" {
+			return entry{}, false
+		}
+		e.Event = &ev
+	default:
+		return entry{}, false
+	}
+	return e, true
+}
+
+func decodeLegacy(line []byte, first bool) (entry, bool) {
+	if first {
+		var m rawSessionMeta
+		if err := json.Unmarshal(line, &m); err == nil && m.ID != "" {
+			return entry{Timestamp: m.Timestamp, Kind: "session_meta", Meta: &m}, true
+		}
+	}
+	var it rawItem
+	if err := json.Unmarshal(line, &it); err != nil || it.Type == "" {
+		return entry{}, false
+	}
+	return entry{Kind: "response_item", Item: &it}, true
+}
+
+// buildTranscript assembles a core.Transcript from decoded rollout entries.
+func buildTranscript(entries []entry) (*core.Transcript, error) {
+	t := &core.Transcript{Agent: "codex"}
+
+	for _, e := range entries {
+		switch {
+		case e.Meta != nil && t.SessionID == "":
+			t.SessionID = e.Meta.ID
+			t.Dir = e.Meta.CWD
+			t.CreatedAt = parseTime(e.Meta.Timestamp)
+			if t.CreatedAt.IsZero() {
+				t.CreatedAt = parseTime(e.Timestamp)
+			}
+			if e.Meta.Git != nil {
+				t.GitBranch = e.Meta.Git.Branch
+			}
+		case e.Context != nil:
+			if t.Model == "" {
+				t.Model = e.Context.Model
+			}
+			if t.Dir == "" {
+				t.Dir = e.Context.CWD
+			}
+		}
+	}
+
+	messages := mapMessages(entries, t.Model)
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages found in session")
+	}
+
+	if t.CreatedAt.IsZero() && messages[0].Timestamp != nil {
+		t.CreatedAt = *messages[0].Timestamp
+	}
+	if last := messages[len(messages)-1].Timestamp; last != nil && !last.Equal(t.CreatedAt) {
+		updated := *last
+		t.UpdatedAt = &updated
+	}
+
+	t.Author = reader.GitAuthor(t.Dir)
+	t.Title = reader.DeriveTitle(messages)
+	t.Usage = reader.AggregateUsage(messages)
+	t.Messages = messages
+	return t, nil
+}
+
+// mapMessages converts response items into core.Message values.
+//
+// Every item produced by the model (reasoning, function calls, assistant
+// text) is accumulated into one assistant message per model response. A
+// token_count event marks the end of a response and carries its usage; the
+// tool outputs that follow are still folded into that message so each
+// tool_use is followed by its tool_result, mirroring the Claude reader.
+func mapMessages(entries []entry, model string) []core.Message {
+	var messages []core.Message
+	var current *core.Message
+	complete := false
+	var lastTotal rawUsage
+
+	emit := func() {
+		if current != nil && len(current.Content) > 0 {
+			messages = append(messages, *current)
+		}
+		current = nil
+		complete = false
+	}
+
+	assistant := func(ts *time.Time) *core.Message {
+		if current == nil || complete {
+			emit()
+			current = &core.Message{Role: core.RoleAssistant, Model: model, Timestamp: ts}
+		}
+		return current
+	}
+
+	for _, e := range entries {
+		if e.Context != nil && e.Context.Model != "" {
+			model = e.Context.Model
+			continue
+		}
+
+		if e.Event != nil {
+			info := e.Event.Info
+			// Codex re-emits token_count on rate-limit updates without new
+			// usage; only count events whose running total has advanced.
+			if info == nil || info.TotalTokenUsage == lastTotal {
+				continue
+			}
+			lastTotal = info.TotalTokenUsage
+			if current != nil {
+				u := mapUsage(info.LastTokenUsage)
+				if current.Usage != nil {
+					u.Add(*current.Usage)
+				}
+				current.Usage = &u
+				complete = true
+			}
+			continue
+		}
+
+		it := e.Item
+		if it == nil {
+			continue
+		}
+		ts := timestampPtr(e.Timestamp)
+
+		switch it.Type {
+		case "message":
+			if it.Role == "assistant" {
+				if text := joinParts(it.Content); text != "" {
+					m := assistant(ts)
+					m.Content = append(m.Content, core.ContentBlock{
+						Type:   core.BlockText,
+						Format: core.FormatMarkdown,
+						Text:   text,
+					})
+				}
+				continue
+			}
+			if it.Role != "user" {
+				continue
+			}
+			text := joinParts(it.Content)
+			if text == "" || isInjectedContext(text) {
+				continue
+			}
+			emit()
+			messages = append(messages, core.Message{
+				Role:      core.RoleUser,
+				Timestamp: ts,
+				Content: []core.ContentBlock{{
+					Type:   core.BlockText,
+					Format: core.FormatPlain,
+					Text:   text,
+				}},
+			})
+
+		case "reasoning":
+			text := joinParts(it.Content)
+			if text == "" {
+				text = joinParts(it.Summary)
+			}
+			if text == "" {
+				continue
+			}
+			m := assistant(ts)
+			m.Content = append(m.Content, core.ContentBlock{Type: core.BlockThinking, Text: text})
+
+		case "function_call":
+			m := assistant(ts)
+			m.Content = append(m.Content, core.ContentBlock{
+				Type:      core.BlockToolUse,
+				ToolUseID: it.CallID,
+				Name:      it.Name,
+				Input:     decodeArguments(it.Arguments),
+			})
+
+		case "custom_tool_call":
+			m := assistant(ts)
+			m.Content = append(m.Content, core.ContentBlock{
+				Type:      core.BlockToolUse,
+				ToolUseID: it.CallID,
+				Name:      it.Name,
+				Input:     map[string]any{"input": it.Input},
+			})
+
+		case "local_shell_call":
+			m := assistant(ts)
+			m.Content = append(m.Content, core.ContentBlock{
+				Type:      core.BlockToolUse,
+				ToolUseID: it.CallID,
+				Name:      "shell",
+				Input:     it.Action,
+			})
+
+		case "function_call_output", "custom_tool_call_output":
+			if current == nil {
+				continue
+			}
+			content, isError := decodeOutput(it.Output)
+			current.Content = append(current.Content, core.ContentBlock{
+				Type:      core.BlockToolResult,
+				ToolUseID: it.CallID,
+				Content:   content,
+				IsError:   isError,
+			})
+		}
+	}
+	emit()
+	return messages
+}
+
+// isInjectedContext reports whether a user message was injected by Codex
+// (environment context, AGENTS.md instructions) rather than typed by a human.
+func isInjectedContext(text string) bool {
+	text = strings.TrimSpace(text)
+	for _, prefix := range []string{"<environment_context>", "<user_instructions>", "# AGENTS.md instructions"} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinParts concatenates the text of input_text, output_text, summary_text
+// and reasoning_text parts.
+func joinParts(parts []rawContentPart) string {
+	var texts []string
+	for _, p := range parts {
+		if p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// decodeArguments parses a function_call arguments string into a map. Input
+// that is not a JSON object is preserved under the "arguments" key.
+func decodeArguments(s string) any {
+	var m map[string]any
+	if err := json.Unmarshal([]byte(s), &m); err == nil && m != nil {
+		return m
+	}
+	if s == "" {
+		return nil
+	}
+	return map[string]any{"arguments": s}
+}
+
+// decodeOutput extracts the textual output of a tool call. Output is usually a
+// string, which for shell tools itself holds a JSON document with the command
+// output and exit code; a non-zero exit code marks the result as an error.
+// Newer Codex versions may store output as an array of content parts.
+func decodeOutput(raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		var parts []rawContentPart
+		if err := json.Unmarshal(raw, &parts); err == nil {
+			return joinParts(parts), false
+		}
+		return string(raw), false
+	}
+
+	var out rawExecOutput
+	if err := json.Unmarshal([]byte(s), &out); err == nil && out.Metadata != nil {
+		return out.Output, out.Metadata.ExitCode != 0
+	}
+	return s, false
+}
+
+// mapUsage converts OpenAI-style usage, where input tokens include cached
+// tokens, into core.Usage where cache reads are counted separately.
+func mapUsage(raw rawUsage) core.Usage {
+	return core.Usage{
+		InputTokens:     raw.InputTokens - raw.CachedInputTokens,
+		OutputTokens:    raw.OutputTokens,
+		CacheReadTokens: raw.CachedInputTokens,
+	}
+}
+
+func timestampPtr(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t := parseTime(s)
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
 }
@@ -0,0 +1,284 @@
+package codex
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testdataPath(name string) string {
+	return filepath.Join("testdata", name)
+}
+
+func readTestdata(t *testing.T, name string) *core.Transcript {
+	t.Helper()
+	r := &Reader{}
+	tr, err := r.ReadFile(testdataPath(name))
+	require.NoError(t, err)
+	return tr
+}
+
+// setupSessionsDir copies testdata files into a temp directory structured as
+// ~/.codex/sessions/YYYY/MM/DD/rollout-<ts>-<sessionID>.jsonl.
+func setupSessionsDir(t *testing.T, files map[string]string) *Reader {
+	t.Helper()
+	dir := t.TempDir()
+	dayDir := filepath.Join(dir, "2026", "01", "01")
+	require.NoError(t, os.MkdirAll(dayDir, 0o755))
+
+	for sessionID, name := range files {
+		data, err := os.ReadFile(testdataPath(name))
+		require.NoError(t, err)
+		path := filepath.Join(dayDir, "rollout-2026-01-01T09-00-00-"+sessionID+".jsonl")
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	return &Reader{Dir: dir}
+}
+
+func TestBuildTranscript(t *testing.T) {
+	tr := readTestdata(t, "simple.jsonl")
+
+	assert.Equal(t, "0199a1b2-c3d4-7e5f-8a9b-0c1d2e3f4a5b", tr.SessionID)
+	assert.Equal(t, "codex", tr.Agent)
+	assert.Equal(t, "gpt-5-codex", tr.Model)
+	assert.Equal(t, "/work", tr.Dir)
+	assert.Equal(t, "main", tr.GitBranch)
+	assert.Equal(t, "fix the bug", tr.Title)
+	assert.False(t, tr.CreatedAt.IsZero())
+	require.NotNil(t, tr.UpdatedAt)
+
+	t.Run("environment context is dropped", func(t *testing.T) {
+		require.Len(t, tr.Messages, 2)
+		assert.Equal(t, core.RoleUser, tr.Messages[0].Role)
+		assert.Equal(t, "fix the bug", tr.Messages[0].Content[0].Text)
+		assert.Equal(t, core.FormatPlain, tr.Messages[0].Content[0].Format)
+	})
+
+	t.Run("assistant text is markdown", func(t *testing.T) {
+		m := tr.Messages[1]
+		assert.Equal(t, core.RoleAssistant, m.Role)
+		assert.Equal(t, "gpt-5-codex", m.Model)
+		require.Len(t, m.Content, 1)
+		assert.Equal(t, core.FormatMarkdown, m.Content[0].Format)
+		assert.Equal(t, "I'll fix that bug.", m.Content[0].Text)
+	})
+
+	t.Run("duplicate token_count is counted once", func(t *testing.T) {
+		require.NotNil(t, tr.Usage)
+		assert.Equal(t, 100, tr.Usage.InputTokens)
+		assert.Equal(t, 5, tr.Usage.CacheReadTokens)
+		assert.Equal(t, 50, tr.Usage.OutputTokens)
+	})
+}
+
+func TestToolCalls(t *testing.T) {
+	tr := readTestdata(t, "tool_loop.jsonl")
+
+	// user, assistant(thinking, shell, result), assistant(patch, result), assistant(text)
+	require.Len(t, tr.Messages, 4)
+	assert.Len(t, tr.Messages[1].Content, 3)
+	assert.Len(t, tr.Messages[2].Content, 2)
+	assert.Len(t, tr.Messages[3].Content, 1)
+
+	t.Run("reasoning summary becomes thinking", func(t *testing.T) {
+		b := tr.Messages[1].Content[0]
+		assert.Equal(t, core.BlockThinking, b.Type)
+		assert.Equal(t, "**Listing files**", b.Text)
+	})
+
+	t.Run("function_call arguments are decoded", func(t *testing.T) {
+		b := tr.Messages[1].Content[1]
+		assert.Equal(t, core.BlockToolUse, b.Type)
+		assert.Equal(t, "call_1", b.ToolUseID)
+		assert.Equal(t, "shell", b.Name)
+		m, ok := b.Input.(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, []any{"bash", "-lc", "ls"}, m["command"])
+		assert.Equal(t, "/work", m["workdir"])
+	})
+
+	t.Run("function_call_output is folded after the call", func(t *testing.T) {
+		b := tr.Messages[1].Content[2]
+		assert.Equal(t, core.BlockToolResult, b.Type)
+		assert.Equal(t, "call_1", b.ToolUseID)
+		assert.Equal(t, "main.go\ngo.mod\n", b.Content)
+		assert.False(t, b.IsError)
+	})
+
+	t.Run("custom tool call with non-zero exit is an error", func(t *testing.T) {
+		use := tr.Messages[2].Content[0]
+		assert.Equal(t, "apply_patch", use.Name)
+		m, ok := use.Input.(map[string]any)
+		require.True(t, ok)
+		assert.Contains(t, m["input"], "*** Begin Patch")
+
+		res := tr.Messages[2].Content[1]
+		assert.Equal(t, "call_2", res.ToolUseID)
+		assert.Equal(t, "patch rejected\n", res.Content)
+		assert.True(t, res.IsError)
+	})
+
+	t.Run("per-response usage", func(t *testing.T) {
+		require.NotNil(t, tr.Messages[1].Usage)
+		assert.Equal(t, 200, tr.Messages[1].Usage.InputTokens)
+		assert.Equal(t, 800, tr.Messages[1].Usage.CacheReadTokens)
+		assert.Equal(t, 20, tr.Messages[1].Usage.OutputTokens)
+
+		require.NotNil(t, tr.Usage)
+		assert.Equal(t, 400, tr.Usage.InputTokens)
+		assert.Equal(t, 2900, tr.Usage.CacheReadTokens)
+		assert.Equal(t, 60, tr.Usage.OutputTokens)
+	})
+}
+
+func TestLegacyRollout(t *testing.T) {
+	tr := readTestdata(t, "legacy.jsonl")
+
+	assert.Equal(t, "sess-legacy", tr.SessionID)
+	assert.Equal(t, "hello", tr.Title)
+	assert.False(t, tr.CreatedAt.IsZero())
+	require.Len(t, tr.Messages, 2)
+	assert.Equal(t, "hi there", tr.Messages[1].Content[0].Text)
+}
+
+func TestDecodeOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		want      string
+		wantError bool
+	}{
+		{"plain string", `"failed to parse arguments"`, "failed to parse arguments", false},
+		{"exec success", `"{\"output\":\"ok\",\"metadata\":{\"exit_code\":0}}"`, "ok", false},
+		{"exec failure", `"{\"output\":\"boom\",\"metadata\":{\"exit_code\":2}}"`, "boom", true},
+		{"content parts", `[{"type":"input_text","text":"a"},{"type":"input_text","text":"b"}]`, "a\n\nb", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isErr := decodeOutput([]byte(tt.raw))
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantError, isErr)
+		})
+	}
+}
+
+func TestReadFileLongLines(t *testing.T) {
+	big := strings.Repeat("x", 2<<20)
+	lines := []string{
+		`{"timestamp":"2026-01-01T10:00:00.000Z","type":"session_meta","payload":{"id":"sess-long","timestamp":"2026-01-01T10:00:00.000Z","cwd":"/work"}}`,
+		`{"timestamp":"2026-01-01T10:00:01.000Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"dump the log"}]}}`,
+		`{"timestamp":"2026-01-01T10:00:02.000Z","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{\"command\":[\"cat\",\"big.log\"]}","call_id":"call_1"}}`,
+		`{"timestamp":"2026-01-01T10:00:03.000Z","type":"response_item","payload":{"type":"function_call_output","call_id":"call_1","output":"{\"output\":\"` + big + `\",\"metadata\":{\"exit_code\":1}}"}}`,
+		`{"timestamp":"2026-01-01T10:00:04.000Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"done"}]}}`,
+	}
+	path := filepath.Join(t.TempDir(), "rollout-2026-01-01T10-00-00-sess-long.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+	assert.True(t, (&Reader{}).Sniff(path))
+	tr, err := (&Reader{}).ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "sess-long", tr.SessionID)
+
+	require.Len(t, tr.Messages, 2)
+	result := tr.Messages[1].Content[1]
+	assert.Equal(t, core.BlockToolResult, result.Type)
+	assert.True(t, result.IsError, "exit code survives truncation")
+	assert.Less(t, len(result.Content), 128<<10)
+	assert.Contains(t, result.Content, "[… truncated")
+	assert.Equal(t, "done", tr.Messages[1].Content[2].Text)
+}
+
+func TestReportWarnings(t *testing.T) {
+	lines := []string{
+		`{"timestamp":"2026-01-01T10:00:00.000Z","type":"session_meta","payload":{"id":"sess-warn","timestamp":"2026-01-01T10:00:00.000Z","cwd":"/work"}}`,
+		`{"timestamp":"2026-01-01T10:00:01.000Z","type":"response_item","payload":{"type":"message","role":"user",`,
+		``,
+		`{"timestamp":"2026-01-01T10:00:02.000Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"input_text","text":"hi"}]}}`,
+		`{"timestamp":"2026-01-01T10:00:03.000Z","type":"response_item","payload":{"type":"web_search_call","id":"ws_1"}}`,
+		`{"timestamp":"2026-01-01T10:00:04.000Z","type":"event_msg","payload":{"type":"agent_message","message":"hello"}}`,
+		`{"timestamp":"2026-01-01T10:00:05.000Z","type":"snapshot","payload":{"id":"x"}}`,
+		`{"timestamp":"2026-01-01T10:00:06.000Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"output_text","text":"hello"}]}}`,
+	}
+	path := filepath.Join(t.TempDir(), "rollout-2026-01-01T10-00-00-sess-warn.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+	report := &reader.Report{}
+	tr, err := (&Reader{Report: report}).ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, tr.Messages, 2)
+
+	warnings := report.Warnings()
+	require.Len(t, warnings, 3)
+	assert.Equal(t, reader.WarnMalformedLine, warnings[0].Kind)
+	assert.Equal(t, path, warnings[0].Path)
+	assert.Equal(t, 2, warnings[0].Line)
+	assert.Equal(t, reader.Warning{
+		Kind:    reader.WarnUnknownBlock,
+		Path:    path,
+		Line:    5,
+		Message: `response item type "web_search_call"`,
+	}, warnings[1])
+	assert.Equal(t, reader.Warning{
+		Kind:    reader.WarnUnknownBlock,
+		Path:    path,
+		Line:    7,
+		Message: `record type "snapshot"`,
+	}, warnings[2])
+}
+
+func TestReadSession(t *testing.T) {
+	r := setupSessionsDir(t, map[string]string{"sess-tools": "tool_loop.jsonl"})
+
+	tr, err := r.ReadSession("sess-tools")
+	require.NoError(t, err)
+	assert.Equal(t, "sess-tools", tr.SessionID)
+
+	_, err = r.ReadSession("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestReadProject(t *testing.T) {
+	r := setupSessionsDir(t, map[string]string{
+		"sess-1": "simple.jsonl",
+		"sess-2": "tool_loop.jsonl",
+		"sess-3": "legacy.jsonl", // no cwd
+	})
+
+	transcripts, err := r.ReadProject("-work")
+	require.NoError(t, err)
+	assert.Len(t, transcripts, 2)
+
+	transcripts, err = r.ReadProject("/work")
+	require.NoError(t, err)
+	assert.Len(t, transcripts, 2)
+}
+
+func TestReadAll(t *testing.T) {
+	r := setupSessionsDir(t, map[string]string{
+		"sess-1": "simple.jsonl",
+		"sess-2": "tool_loop.jsonl",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(r.Dir, "notes.txt"), []byte("ignore"), 0o644))
+
+	transcripts, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, transcripts, 2)
+}
+
+func TestSniff(t *testing.T) {
+	r := setupSessionsDir(t, map[string]string{"sess-1": "simple.jsonl"})
+
+	assert.True(t, r.Sniff(testdataPath("simple.jsonl")))
+	assert.True(t, r.Sniff(testdataPath("legacy.jsonl")))
+	assert.True(t, r.Sniff(r.Dir))
+
+	assert.False(t, r.Sniff("../claude/testdata/simple.jsonl"))
+	assert.False(t, r.Sniff(t.TempDir()))
+}
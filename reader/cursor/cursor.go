@@ -1,8 +1,687 @@
 // Package cursor reads Cursor session data (SQLite state.vscdb key-value store).
+//
+// Cursor keeps composer (chat/agent) conversations in the cursorDiskKV table of
+// the global state.vscdb:
+//
+//	composerData:<composerID>          composer metadata and bubble headers
+//	bubbleId:<composerID>:<bubbleID>   one bubble (user prompt or assistant step)
+//
+// Each workspace has its own state.vscdb under workspaceStorage/<hash>/ whose
+// ItemTable lists the composers opened in that workspace, and a workspace.json
+// naming the folder. The databases are queried through the sqlite3 command
+// line tool in read-only mode, which keeps cg free of cgo.
 package cursor
 
-// Reader reads Cursor sessions from a state.vscdb SQLite database.
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+)
+
+// Reader reads Cursor sessions from state.vscdb SQLite databases.
 type Reader struct {
-	// DBPath overrides the default database path.
-	DBPath string
+	// Dir overrides Cursor's user data directory, which contains
+	// globalStorage/ and workspaceStorage/ (e.g. ~/.config/Cursor/User).
+	Dir string
+
+	// Report, when set, collects warnings about skipped sessions and database
+	// rows that are not valid JSON.
+	Report *reader.Report
+}
+
+// sqliteHeader is the magic string at the start of every SQLite database.
+const sqliteHeader = "SQLite format 3\x00"
+
+// Bubble types as stored by Cursor.
+const (
+	bubbleUser      = 1
+	bubbleAssistant = 2
+)
+
+// Raw JSON deserialization types. These mirror the values stored in
+// cursorDiskKV and ItemTable.
+
+type rawComposer struct {
+	ComposerID    string          `json:"composerId"`
+	Name          string          `json:"name"`
+	CreatedAt     int64           `json:"createdAt"`
+	LastUpdatedAt int64           `json:"lastUpdatedAt"`
+	Headers       []rawHeader     `json:"fullConversationHeadersOnly"`
+	Conversation  []rawBubble     `json:"conversation"`
+	ModelConfig   *rawModelConfig `json:"modelConfig"`
+}
+
+type rawHeader struct {
+	BubbleID string `json:"bubbleId"`
+	Type     int    `json:"type"`
+}
+
+type rawModelConfig struct {
+	ModelName string `json:"modelName"`
+}
+
+type rawBubble struct {
+	BubbleID   string          `json:"bubbleId"`
+	Type       int             `json:"type"`
+	Text       string          `json:"text"`
+	CreatedAt  json.RawMessage `json:"createdAt"`
+	Thinking   *rawThinking    `json:"thinking"`
+	ToolFormer *rawToolFormer  `json:"toolFormerData"`
+	CodeBlocks []rawCodeBlock  `json:"codeBlocks"`
+	TokenCount *rawTokenCount  `json:"tokenCount"`
+	TimingInfo *rawTimingInfo  `json:"timingInfo"`
+}
+
+type rawThinking struct {
+	Text string `json:"text"`
+}
+
+type rawToolFormer struct {
+	ToolCallID string `json:"toolCallId"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	RawArgs    string `json:"rawArgs"`
+	Params     string `json:"params"`
+	Result     string `json:"result"`
+}
+
+type rawCodeBlock struct {
+	URI        *rawURI `json:"uri"`
+	Content    string  `json:"content"`
+	LanguageID string  `json:"languageId"`
+}
+
+type rawURI struct {
+	Path   string `json:"path"`
+	FSPath string `json:"fsPath"`
+}
+
+type rawTokenCount struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+type rawTimingInfo struct {
+	ClientStartTime float64 `json:"clientStartTime"`
+}
+
+type rawWorkspaceComposers struct {
+	AllComposers []struct {
+		ComposerID string `json:"composerId"`
+	} `json:"allComposers"`
+}
+
+type rawWorkspace struct {
+	Folder string `json:"folder"`
+}
+
+// kv is one row of a key-value table.
+type kv struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ReadFile parses a state.vscdb and returns its most recently updated
+// composer. For a workspace database the choice is limited to composers opened
+// in that workspace, and bubbles are read from the sibling global database.
+// Use ReadSession to select a specific composer.
+func (r *Reader) ReadFile(path string) (*core.Transcript, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	globalDB := path
+	folder := ""
+	var ids []string
+
+	wsDir := filepath.Dir(path)
+	if filepath.Base(filepath.Dir(wsDir)) == "workspaceStorage" {
+		globalDB = filepath.Join(filepath.Dir(filepath.Dir(wsDir)), "globalStorage", "state.vscdb")
+		folder = workspaceFolder(wsDir)
+		var err error
+		ids, err = workspaceComposerIDs(path, r.Report)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no composers found in workspace")
+		}
+	}
+
+	composers, err := readComposers(globalDB, ids, r.Report)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *rawComposer
+	for _, c := range composers {
+		if latest == nil || c.LastUpdatedAt > latest.LastUpdatedAt {
+			latest = c
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no composers found in database")
+	}
+	return buildTranscript(globalDB, latest, folder, r.Report)
+}
+
+// ReadSession locates and parses a composer by its ID.
+func (r *Reader) ReadSession(sessionID string) (*core.Transcript, error) {
+	composers, err := readComposers(r.globalDB(), []string{sessionID}, r.Report)
+	if err != nil {
+		return nil, err
+	}
+	if len(composers) == 0 {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	folders := r.composerFolders()
+	return buildTranscript(r.globalDB(), composers[0], folders[sessionID], r.Report)
+}
+
+// ReadProject returns all composers opened in a workspace. The project is
+// matched against the workspace folder, either verbatim or in Claude's
+// dash-encoded form ("/Users/foo/bar" → "-Users-foo-bar").
+func (r *Reader) ReadProject(project string) ([]*core.Transcript, error) {
+	dirs, err := r.workspaceDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var transcripts []*core.Transcript
+	for _, wsDir := range dirs {
+		folder := workspaceFolder(wsDir)
+		if folder == "" || (folder != project && strings.ReplaceAll(folder, "/", "-") != project) {
+			continue
+		}
+		ids, err := workspaceComposerIDs(filepath.Join(wsDir, "state.vscdb"), r.Report)
+		if err != nil || len(ids) == 0 {
+			continue
+		}
+		composers, err := readComposers(r.globalDB(), ids, r.Report)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range composers {
+			t, err := buildTranscript(r.globalDB(), c, folder, r.Report)
+			if err != nil {
+				r.skipped(c, err)
+				continue
+			}
+			transcripts = append(transcripts, t)
+		}
+	}
+	return transcripts, nil
+}
+
+// ReadAll returns every composer stored in the global database.
+func (r *Reader) ReadAll() ([]*core.Transcript, error) {
+	composers, err := readComposers(r.globalDB(), nil, r.Report)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := r.composerFolders()
+	var all []*core.Transcript
+	for _, c := range composers {
+		t, err := buildTranscript(r.globalDB(), c, folders[c.ComposerID], r.Report)
+		if err != nil {
+			r.skipped(c, err)
+			continue
+		}
+		all = append(all, t)
+	}
+	return all, nil
+}
+
+// Sniff reports whether path is a Cursor state.vscdb database or a Cursor
+// user data directory.
+func (r *Reader) Sniff(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		_, err := os.Stat(filepath.Join(path, "globalStorage", "state.vscdb"))
+		return err == nil
+	}
+	if filepath.Ext(path) != ".vscdb" {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return string(header) == sqliteHeader
+}
+
+func (r *Reader) dir() string {
+	if r.Dir != "" {
+		return r.Dir
+	}
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "Cursor", "User")
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), "Cursor", "User")
+	default:
+		return filepath.Join(home, ".config", "Cursor", "User")
+	}
+}
+
+// skipped reports a composer that failed to read.
+func (r *Reader) skipped(c *rawComposer, err error) {
+	r.Report.Add(reader.Warning{
+		Kind:    reader.WarnSkippedFile,
+		Path:    r.globalDB(),
+		Message: fmt.Sprintf("composer %s: %v", c.ComposerID, err),
+	})
+}
+
+// malformedRow reports a row of db whose value is not valid JSON.
+func malformedRow(report *reader.Report, db string, row kv, err error) {
+	report.Add(reader.Warning{
+		Kind:    reader.WarnMalformedLine,
+		Path:    db,
+		Message: fmt.Sprintf("row %s: %v", row.Key, err),
+	})
+}
+
+func (r *Reader) globalDB() string {
+	return filepath.Join(r.dir(), "globalStorage", "state.vscdb")
+}
+
+// workspaceDirs lists workspaceStorage/<hash>/ directories that have a database.
+func (r *Reader) workspaceDirs() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(r.dir(), "workspaceStorage", "*", "state.vscdb"))
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, len(matches))
+	for i, m := range matches {
+		dirs[i] = filepath.Dir(m)
+	}
+	return dirs, nil
+}
+
+// composerFolders maps composer IDs to the workspace folder they were opened
+// in. Workspaces that cannot be read are skipped.
+func (r *Reader) composerFolders() map[string]string {
+	folders := make(map[string]string)
+	dirs, err := r.workspaceDirs()
+	if err != nil {
+		return folders
+	}
+	for _, wsDir := range dirs {
+		folder := workspaceFolder(wsDir)
+		if folder == "" {
+			continue
+		}
+		ids, err := workspaceComposerIDs(filepath.Join(wsDir, "state.vscdb"), r.Report)
+		if err != nil {
+			continue
+		}
+		for _, id := range ids {
+			folders[id] = folder
+		}
+	}
+	return folders
+}
+
+// workspaceFolder returns the local folder path recorded in workspace.json.
+func workspaceFolder(wsDir string) string {
+	data, err := os.ReadFile(filepath.Join(wsDir, "workspace.json"))
+	if err != nil {
+		return ""
+	}
+	var ws rawWorkspace
+	if err := json.Unmarshal(data, &ws); err != nil || ws.Folder == "" {
+		return ""
+	}
+	u, err := url.Parse(ws.Folder)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return u.Path
+}
+
+// workspaceComposerIDs lists the composers recorded in a workspace database.
+// Rows that are not valid JSON are reported to report.
+func workspaceComposerIDs(db string, report *reader.Report) ([]string, error) {
+	rows, err := query(db, "SELECT key, CAST(value AS TEXT) AS value FROM ItemTable WHERE key = 'composer.composerData'")
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, row := range rows {
+		var wc rawWorkspaceComposers
+		if err := json.Unmarshal([]byte(row.Value), &wc); err != nil {
+			malformedRow(report, db, row, err)
+			continue
+		}
+		for _, c := range wc.AllComposers {
+			ids = append(ids, c.ComposerID)
+		}
+	}
+	return ids, nil
+}
+
+// readComposers reads composerData entries from the global database, limited
+// to ids when non-empty. Results are sorted by creation time. Rows that are
+// not valid JSON are reported to report.
+func readComposers(db string, ids []string, report *reader.Report) ([]*rawComposer, error) {
+	stmt := "SELECT key, CAST(value AS TEXT) AS value FROM cursorDiskKV WHERE key LIKE 'composerData:%'"
+	if len(ids) > 0 {
+		keys := make([]string, len(ids))
+		for i, id := range ids {
+			keys[i] = quote("composerData:" + id)
+		}
+		stmt = "SELECT key, CAST(value AS TEXT) AS value FROM cursorDiskKV WHERE key IN (" + strings.Join(keys, ", ") + ")"
+	}
+
+	rows, err := query(db, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	var composers []*rawComposer
+	for _, row := range rows {
+		var c rawComposer
+		if err := json.Unmarshal([]byte(row.Value), &c); err != nil {
+			malformedRow(report, db, row, err)
+			continue
+		}
+		if c.ComposerID == "" {
+			c.ComposerID = strings.TrimPrefix(row.Key, "composerData:")
+		}
+		if len(c.Headers) == 0 && len(c.Conversation) == 0 {
+			continue // empty composer
+		}
+		composers = append(composers, &c)
+	}
+	sort.Slice(composers, func(i, j int) bool {
+		return composers[i].CreatedAt < composers[j].CreatedAt
+	})
+	return composers, nil
+}
+
+// readBubbles returns the bubbles of a composer in conversation order. Older
+// Cursor versions store bubbles inline in the composer; newer ones store them
+// as separate bubbleId entries listed by the conversation headers. Rows that
+// are not valid JSON are reported to report.
+func readBubbles(db string, c *rawComposer, report *reader.Report) ([]rawBubble, error) {
+	if len(c.Conversation) > 0 {
+		return c.Conversation, nil
+	}
+
+	rows, err := query(db, "SELECT key, CAST(value AS TEXT) AS value FROM cursorDiskKV WHERE key LIKE "+quote("bubbleId:"+c.ComposerID+":%"))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]rawBubble, len(rows))
+	for _, row := range rows {
+		var b rawBubble
+		if err := json.Unmarshal([]byte(row.Value), &b); err != nil {
+			malformedRow(report, db, row, err)
+			continue
+		}
+		if b.BubbleID == "" {
+			b.BubbleID = row.Key[strings.LastIndex(row.Key, ":")+1:]
+		}
+		byID[b.BubbleID] = b
+	}
+
+	bubbles := make([]rawBubble, 0, len(c.Headers))
+	for _, h := range c.Headers {
+		if b, ok := byID[h.BubbleID]; ok {
+			bubbles = append(bubbles, b)
+		}
+	}
+	return bubbles, nil
+}
+
+// buildTranscript assembles a core.Transcript from a composer and its bubbles.
+func buildTranscript(db string, c *rawComposer, folder string, report *reader.Report) (*core.Transcript, error) {
+	bubbles, err := readBubbles(db, c, report)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := mapMessages(bubbles)
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages found in session")
+	}
+
+	t := &core.Transcript{
+		SessionID: c.ComposerID,
+		Agent:     "cursor",
+		Author:    reader.GitAuthor(folder),
+		Dir:       folder,
+		Title:     c.Name,
+		CreatedAt: msTime(c.CreatedAt),
+		Usage:     reader.AggregateUsage(messages),
+		Messages:  messages,
+	}
+	if c.ModelConfig != nil && c.ModelConfig.ModelName != "default" {
+		t.Model = c.ModelConfig.ModelName
+	}
+	if t.Title == "" {
+		t.Title = reader.DeriveTitle(messages)
+	}
+	if c.CreatedAt == 0 && messages[0].Timestamp != nil {
+		t.CreatedAt = *messages[0].Timestamp
+	}
+	if c.LastUpdatedAt > c.CreatedAt && c.CreatedAt != 0 {
+		updated := msTime(c.LastUpdatedAt)
+		t.UpdatedAt = &updated
+	}
+	return t, nil
+}
+
+// mapMessages converts bubbles into core.Message values. Consecutive assistant
+// bubbles (one per step in agent mode) are merged into a single assistant
+// message, and each tool call is followed by its result.
+func mapMessages(bubbles []rawBubble) []core.Message {
+	var messages []core.Message
+	var current *core.Message
+
+	emit := func() {
+		if current != nil && len(current.Content) > 0 {
+			messages = append(messages, *current)
+		}
+		current = nil
+	}
+
+	for _, b := range bubbles {
+		ts := bubbleTime(b)
+		switch b.Type {
+		case bubbleUser:
+			emit()
+			text := strings.TrimSpace(b.Text)
+			if text == "" {
+				continue
+			}
+			messages = append(messages, core.Message{
+				UUID:      b.BubbleID,
+				Role:      core.RoleUser,
+				Timestamp: ts,
+				Content: []core.ContentBlock{{
+					Type:   core.BlockText,
+					Format: core.FormatPlain,
+					Text:   text,
+				}},
+			})
+
+		case bubbleAssistant:
+			if current == nil {
+				current = &core.Message{UUID: b.BubbleID, Role: core.RoleAssistant, Timestamp: ts}
+			}
+			current.Content = append(current.Content, mapAssistantBubble(b)...)
+			if b.TokenCount != nil && (b.TokenCount.InputTokens > 0 || b.TokenCount.OutputTokens > 0) {
+				u := core.Usage{InputTokens: b.TokenCount.InputTokens, OutputTokens: b.TokenCount.OutputTokens}
+				if current.Usage != nil {
+					u.Add(*current.Usage)
+				}
+				current.Usage = &u
+			}
+		}
+	}
+	emit()
+	return messages
+}
+
+func mapAssistantBubble(b rawBubble) []core.ContentBlock {
+	var blocks []core.ContentBlock
+
+	if b.Thinking != nil && strings.TrimSpace(b.Thinking.Text) != "" {
+		blocks = append(blocks, core.ContentBlock{Type: core.BlockThinking, Text: b.Thinking.Text})
+	}
+	if strings.TrimSpace(b.Text) != "" {
+		blocks = append(blocks, core.ContentBlock{Type: core.BlockText, Format: core.FormatMarkdown, Text: b.Text})
+	}
+
+	if tf := b.ToolFormer; tf != nil && tf.Name != "" {
+		args := tf.RawArgs
+		if args == "" {
+			args = tf.Params
+		}
+		blocks = append(blocks, core.ContentBlock{
+			Type:      core.BlockToolUse,
+			ToolUseID: tf.ToolCallID,
+			Name:      tf.Name,
+			Input:     decodeJSON(args),
+		})
+		if tf.Result != "" || tf.Status == "error" {
+			blocks = append(blocks, core.ContentBlock{
+				Type:      core.BlockToolResult,
+				ToolUseID: tf.ToolCallID,
+				Content:   tf.Result,
+				IsError:   tf.Status == "error",
+			})
+		}
+		return blocks
+	}
+
+	// Code blocks attached to a file in chat mode are edits proposed (and
+	// usually applied) by the model. Represent them as edit_file calls so they
+	// read like agent-mode edits.
+	for i, cb := range b.CodeBlocks {
+		path := codeBlockPath(cb)
+		if path == "" || cb.Content == "" {
+			continue
+		}
+		blocks = append(blocks, core.ContentBlock{
+			Type:      core.BlockToolUse,
+			ToolUseID: b.BubbleID + "-" + strconv.Itoa(i),
+			Name:      "edit_file",
+			Input: map[string]any{
+				"target_file": path,
+				"code_edit":   cb.Content,
+				"language":    cb.LanguageID,
+			},
+		})
+	}
+	return blocks
+}
+
+func codeBlockPath(cb rawCodeBlock) string {
+	if cb.URI == nil {
+		return ""
+	}
+	if cb.URI.FSPath != "" {
+		return cb.URI.FSPath
+	}
+	return cb.URI.Path
+}
+
+// bubbleTime returns the bubble timestamp. createdAt is an ISO-8601 string in
+// recent Cursor versions and epoch milliseconds in older ones; timingInfo is
+// used as a fallback.
+func bubbleTime(b rawBubble) *time.Time {
+	if len(b.CreatedAt) > 0 {
+		var s string
+		if err := json.Unmarshal(b.CreatedAt, &s); err == nil {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return &t
+			}
+		}
+		var ms int64
+		if err := json.Unmarshal(b.CreatedAt, &ms); err == nil && ms > 0 {
+			t := msTime(ms)
+			return &t
+		}
+	}
+	if b.TimingInfo != nil && b.TimingInfo.ClientStartTime > 0 {
+		t := msTime(int64(b.TimingInfo.ClientStartTime))
+		return &t
+	}
+	return nil
+}
+
+// decodeJSON parses a JSON object string, preserving non-object input under
+// the "arguments" key.
+func decodeJSON(s string) any {
+	if s == "" {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(s), &m); err == nil && m != nil {
+		return m
+	}
+	return map[string]any{"arguments": s}
+}
+
+// query runs a read-only SQL statement against db with the sqlite3 CLI and
+// decodes its JSON output.
+func query(db, stmt string) ([]kv, error) {
+	// Check first so a missing database fails fast without starting sqlite3.
+	if _, err := os.Stat(db); err != nil {
+		return nil, fmt.Errorf("query %s: %w", db, err)
+	}
+	cmd := exec.Command("sqlite3", "-readonly", "-json", db, stmt)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("query %s: %s", db, msg)
+		}
+		return nil, fmt.Errorf("query %s: %w", db, err)
+	}
+	// sqlite3 prints nothing when there are no rows.
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var rows []kv
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, fmt.Errorf("decode query output: %w", err)
+	}
+	return rows, nil
+}
+
+// quote returns s as a single-quoted SQL string literal.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func msTime(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
 }
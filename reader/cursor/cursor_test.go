@@ -0,0 +1,254 @@
+package cursor
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupUserDir builds a Cursor user directory from the SQL fixtures:
+//
+//	globalStorage/state.vscdb
+//	workspaceStorage/ws1/{state.vscdb,workspace.json}
+func setupUserDir(t *testing.T) *Reader {
+	t.Helper()
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+
+	dir := t.TempDir()
+	loadSQL(t, filepath.Join(dir, "globalStorage", "state.vscdb"), "global.sql")
+
+	wsDir := filepath.Join(dir, "workspaceStorage", "ws1")
+	loadSQL(t, filepath.Join(wsDir, "state.vscdb"), "workspace.sql")
+	data, err := os.ReadFile(filepath.Join("testdata", "workspace.json"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(wsDir, "workspace.json"), data, 0o644))
+
+	return &Reader{Dir: dir}
+}
+
+func loadSQL(t *testing.T, db, fixture string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(db), 0o755))
+	sql, err := os.Open(filepath.Join("testdata", fixture))
+	require.NoError(t, err)
+	defer sql.Close()
+
+	cmd := exec.Command("sqlite3", db)
+	cmd.Stdin = sql
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func readAgent(t *testing.T) *core.Transcript {
+	t.Helper()
+	r := setupUserDir(t)
+	tr, err := r.ReadSession("comp-agent")
+	require.NoError(t, err)
+	return tr
+}
+
+func TestBuildTranscript(t *testing.T) {
+	tr := readAgent(t)
+
+	assert.Equal(t, "comp-agent", tr.SessionID)
+	assert.Equal(t, "cursor", tr.Agent)
+	assert.Equal(t, "claude-4-sonnet", tr.Model)
+	assert.Equal(t, "/work", tr.Dir)
+	assert.Equal(t, "Fix the login bug", tr.Title)
+	assert.False(t, tr.CreatedAt.IsZero())
+	require.NotNil(t, tr.UpdatedAt)
+
+	// user, assistant(thinking, read, result, cmd, error, text), assistant(text, edit)
+	// The blank user bubble is dropped but still ends the assistant run.
+	require.Len(t, tr.Messages, 3)
+	assert.Equal(t, core.RoleUser, tr.Messages[0].Role)
+	assert.Equal(t, core.RoleAssistant, tr.Messages[1].Role)
+	assert.Equal(t, core.RoleAssistant, tr.Messages[2].Role)
+	require.NotNil(t, tr.Messages[0].Timestamp)
+}
+
+func TestBubbleMapping(t *testing.T) {
+	tr := readAgent(t)
+
+	blocks := tr.Messages[1].Content
+	require.Len(t, blocks, 6)
+
+	t.Run("thinking", func(t *testing.T) {
+		assert.Equal(t, core.BlockThinking, blocks[0].Type)
+		assert.Equal(t, "Look at auth.go first.", blocks[0].Text)
+	})
+
+	t.Run("tool call expands to use and result", func(t *testing.T) {
+		assert.Equal(t, core.BlockToolUse, blocks[1].Type)
+		assert.Equal(t, "read_file", blocks[1].Name)
+		assert.Equal(t, "toolu_read", blocks[1].ToolUseID)
+		m, ok := blocks[1].Input.(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, "auth.go", m["target_file"])
+
+		assert.Equal(t, core.BlockToolResult, blocks[2].Type)
+		assert.Equal(t, "toolu_read", blocks[2].ToolUseID)
+		assert.False(t, blocks[2].IsError)
+	})
+
+	t.Run("errored tool falls back to params", func(t *testing.T) {
+		assert.Equal(t, "run_terminal_cmd", blocks[3].Name)
+		m, ok := blocks[3].Input.(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, "go test ./...", m["command"])
+		assert.True(t, blocks[4].IsError)
+		assert.Equal(t, "exit status 1", blocks[4].Content)
+	})
+
+	t.Run("assistant text is markdown", func(t *testing.T) {
+		assert.Equal(t, core.FormatMarkdown, blocks[5].Format)
+		assert.Equal(t, "Fixed the **login** bug.", blocks[5].Text)
+	})
+
+	t.Run("code blocks become edits", func(t *testing.T) {
+		edit := tr.Messages[2].Content[1]
+		assert.Equal(t, core.BlockToolUse, edit.Type)
+		assert.Equal(t, "edit_file", edit.Name)
+		m, ok := edit.Input.(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, "/work/auth.go", m["target_file"])
+		assert.Contains(t, m["code_edit"], "func Login()")
+	})
+}
+
+func TestFileChanges(t *testing.T) {
+	tr := readAgent(t)
+
+	stats, files := core.ComputeChanges(tr)
+	assert.Equal(t, &core.DiffStats{Added: 3, Removed: 0, Changed: 1}, stats)
+	assert.Equal(t, []core.FileChange{
+		{Path: "auth.go", Operations: []core.ToolKind{core.ToolRead}, FirstTurn: 1, LastTurn: 1, ReadOnly: true},
+		{Path: "/work/auth.go", Operations: []core.ToolKind{core.ToolEdit}, Added: 3, FirstTurn: 1, LastTurn: 1},
+	}, files)
+}
+
+func TestUsage(t *testing.T) {
+	tr := readAgent(t)
+
+	u := tr.Messages[1].Usage
+	require.NotNil(t, u)
+	assert.Equal(t, 150, u.InputTokens)
+	assert.Equal(t, 30, u.OutputTokens)
+
+	require.NotNil(t, tr.Usage)
+	assert.Equal(t, 150, tr.Usage.InputTokens)
+}
+
+func TestInlineConversation(t *testing.T) {
+	r := setupUserDir(t)
+
+	tr, err := r.ReadSession("comp-chat")
+	require.NoError(t, err)
+	assert.Equal(t, "what does main.go do?", tr.Title)
+	assert.Empty(t, tr.Dir)
+	require.Len(t, tr.Messages, 2)
+	require.NotNil(t, tr.Messages[0].Timestamp)
+	assert.Equal(t, "It starts the server.", tr.Messages[1].Content[0].Text)
+}
+
+func TestReadFile(t *testing.T) {
+	r := setupUserDir(t)
+
+	t.Run("global database returns latest composer", func(t *testing.T) {
+		tr, err := r.ReadFile(r.globalDB())
+		require.NoError(t, err)
+		assert.Equal(t, "comp-agent", tr.SessionID)
+	})
+
+	t.Run("workspace database", func(t *testing.T) {
+		tr, err := r.ReadFile(filepath.Join(r.Dir, "workspaceStorage", "ws1", "state.vscdb"))
+		require.NoError(t, err)
+		assert.Equal(t, "comp-agent", tr.SessionID)
+		assert.Equal(t, "/work", tr.Dir)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := r.ReadFile(filepath.Join(r.Dir, "nope.vscdb"))
+		assert.Error(t, err)
+	})
+}
+
+func TestReadSession(t *testing.T) {
+	r := setupUserDir(t)
+
+	_, err := r.ReadSession("nonexistent")
+	assert.Error(t, err)
+
+	_, err = r.ReadSession("comp-empty")
+	assert.Error(t, err)
+}
+
+func TestReadProject(t *testing.T) {
+	r := setupUserDir(t)
+
+	for _, project := range []string{"/work", "-work"} {
+		transcripts, err := r.ReadProject(project)
+		require.NoError(t, err)
+		require.Len(t, transcripts, 1, "project %q", project)
+		assert.Equal(t, "comp-agent", transcripts[0].SessionID)
+	}
+
+	transcripts, err := r.ReadProject("/other")
+	require.NoError(t, err)
+	assert.Empty(t, transcripts)
+}
+
+func TestReadAll(t *testing.T) {
+	r := setupUserDir(t)
+
+	transcripts, err := r.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, transcripts, 2)
+	// Sorted by creation time; empty composers are skipped.
+	assert.Equal(t, "comp-chat", transcripts[0].SessionID)
+	assert.Equal(t, "comp-agent", transcripts[1].SessionID)
+}
+
+func TestReportWarnings(t *testing.T) {
+	r := setupUserDir(t)
+	db := r.globalDB()
+	cmd := exec.Command("sqlite3", db,
+		`INSERT INTO cursorDiskKV VALUES ('composerData:comp-broken', '{"composerId":');`+
+			`UPDATE cursorDiskKV SET value = '{"_v":2,' WHERE key = 'bubbleId:comp-agent:b3';`)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	report := &reader.Report{}
+	r.Report = report
+	transcripts, err := r.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, transcripts, 2)
+
+	warnings := report.Warnings()
+	require.Len(t, warnings, 2)
+	for _, w := range warnings {
+		assert.Equal(t, reader.WarnMalformedLine, w.Kind)
+		assert.Equal(t, db, w.Path)
+	}
+	assert.Contains(t, warnings[0].Message, "row composerData:comp-broken: ")
+	assert.Contains(t, warnings[1].Message, "row bubbleId:comp-agent:b3: ")
+}
+
+func TestSniff(t *testing.T) {
+	r := setupUserDir(t)
+
+	assert.True(t, r.Sniff(r.globalDB()))
+	assert.True(t, r.Sniff(filepath.Join(r.Dir, "workspaceStorage", "ws1", "state.vscdb")))
+	assert.True(t, r.Sniff(r.Dir))
+
+	assert.False(t, r.Sniff(filepath.Join("testdata", "global.sql")))
+	assert.False(t, r.Sniff(t.TempDir()))
+}
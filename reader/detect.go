@@ -0,0 +1,32 @@
+package reader
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Sniffer is implemented by readers that can recognise their own session
+// data. Detect uses it to choose a reader when the agent is not specified.
+type Sniffer interface {
+	// Sniff reports whether path is a session file, or a directory laid out
+	// the way the agent stores sessions, that this reader can parse. It only
+	// inspects the first bytes of a file.
+	Sniff(path string) bool
+}
+
+// Detect returns the name of the reader that recognises path. Readers are
+// tried in name order; readers that do not implement Sniffer are skipped.
+func Detect(path string, readers map[string]Reader) (string, error) {
+	names := make([]string, 0, len(readers))
+	for name := range readers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if s, ok := readers[name].(Sniffer); ok && s.Sniff(path) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("cannot detect agent for %s; use --agent", path)
+}
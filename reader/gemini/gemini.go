@@ -0,0 +1,712 @@
+// Package gemini reads Gemini CLI session logs (JSON files in ~/.gemini/tmp/<project-hash>/).
+//
+// Gemini CLI keeps one directory per project, named after the SHA-256 of the
+// project root. It holds two kinds of conversation files:
+//
+//	chats/session-<time>-<id>.json   session record written as the chat progresses
+//	checkpoint-<tag>.json            history saved with /chat save <tag>
+//
+// Session records list messages with their tool calls and token counts.
+// Checkpoints are the raw Gemini API history: an array of Content objects
+// whose parts are text, functionCall or functionResponse. Checkpoints written
+// by /restore wrap that array in a "clientHistory" field.
+package gemini
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+)
+
+// Reader reads Gemini CLI session records and chat checkpoints.
+type Reader struct {
+	// Dir overrides the default temp directory (~/.gemini/tmp/).
+	Dir string
+
+	// Report, when set, collects warnings about skipped sessions and messages
+	// or parts of unknown types.
+	Report *reader.Report
+}
+
+// Raw JSON deserialization types. These mirror the files on disk.
+
+// rawRecord is a chats/session-*.json session record.
+type rawRecord struct {
+	SessionID   string       `json:"sessionId"`
+	ProjectHash string       `json:"projectHash"`
+	StartTime   string       `json:"startTime"`
+	LastUpdated string       `json:"lastUpdated"`
+	Messages    []rawMessage `json:"messages"`
+}
+
+type rawMessage struct {
+	ID            string            `json:"id"`
+	Timestamp     string            `json:"timestamp"`
+	Type          string            `json:"type"`
+	Content       json.RawMessage   `json:"content"`
+	ToolCalls     []rawToolCall     `json:"toolCalls"`
+	Thoughts      []rawThought      `json:"thoughts"`
+	Tokens        *rawTokens        `json:"tokens"`
+	UsageMetadata *rawUsageMetadata `json:"usageMetadata"`
+	Model         string            `json:"model"`
+}
+
+type rawToolCall struct {
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	Args          map[string]any  `json:"args"`
+	Result        []rawPart       `json:"result"`
+	ResultDisplay json.RawMessage `json:"resultDisplay"`
+	Status        string          `json:"status"`
+}
+
+type rawThought struct {
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+type rawTokens struct {
+	Input    int `json:"input"`
+	Output   int `json:"output"`
+	Cached   int `json:"cached"`
+	Thoughts int `json:"thoughts"`
+}
+
+// rawContent is a Gemini API Content object as stored in checkpoints.
+type rawContent struct {
+	Role          string            `json:"role"`
+	Parts         []rawPart         `json:"parts"`
+	UsageMetadata *rawUsageMetadata `json:"usageMetadata"`
+}
+
+type rawPart struct {
+	Text             string               `json:"text"`
+	Thought          bool                 `json:"thought"`
+	FunctionCall     *rawFunctionCall     `json:"functionCall"`
+	FunctionResponse *rawFunctionResponse `json:"functionResponse"`
+
+	// Parts the reader does not map, decoded only to report them.
+	InlineData          json.RawMessage `json:"inlineData"`
+	FileData            json.RawMessage `json:"fileData"`
+	ExecutableCode      json.RawMessage `json:"executableCode"`
+	CodeExecutionResult json.RawMessage `json:"codeExecutionResult"`
+}
+
+// unmappedType returns the type of a part the reader does not map, or "".
+func (p rawPart) unmappedType() string {
+	switch {
+	case p.InlineData != nil:
+		return "inlineData"
+	case p.FileData != nil:
+		return "fileData"
+	case p.ExecutableCode != nil:
+		return "executableCode"
+	case p.CodeExecutionResult != nil:
+		return "codeExecutionResult"
+	}
+	return ""
+}
+
+type rawFunctionCall struct {
+	ID   string         `json:"id"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+type rawFunctionResponse struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type rawUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+}
+
+type rawRestoreCheckpoint struct {
+	ClientHistory []rawContent `json:"clientHistory"`
+}
+
+// contextSetupPrefix starts the synthetic first user turn Gemini CLI sends to
+// set up the session context. It and the model's acknowledgement are dropped.
+const contextSetupPrefix = "This is the Gemini CLI. We are setting up the context"
+
+// ReadFile parses a single session record or checkpoint file.
+func (r *Reader) ReadFile(path string) (*core.Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open session file: %w", err)
+	}
+
+	t, err := parse(data, path, r.Report)
+	if err != nil {
+		return nil, err
+	}
+	if t.Dir == "" {
+		t.Dir = projectRoot(projectDir(path))
+		t.Author = reader.GitAuthor(t.Dir)
+	}
+	return t, nil
+}
+
+// ReadSession locates a session by its ID, or a checkpoint by its tag.
+func (r *Reader) ReadSession(sessionID string) (*core.Transcript, error) {
+	files, err := r.sessionFiles("*")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range files {
+		if checkpointTag(path) == sessionID {
+			return r.ReadFile(path)
+		}
+		if checkpointTag(path) != "" || !strings.Contains(filepath.Base(path), sessionID[:min(8, len(sessionID))]) {
+			continue
+		}
+		t, err := r.ReadFile(path)
+		if err == nil && t.SessionID == sessionID {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("session %s not found", sessionID)
+}
+
+// ReadProject returns all sessions for a project. The project may be the
+// project hash, the project root path, or the root in Claude's dash-encoded
+// form ("/Users/foo/bar" → "-Users-foo-bar").
+func (r *Reader) ReadProject(project string) ([]*core.Transcript, error) {
+	dirs, err := filepath.Glob(filepath.Join(r.dir(), "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var transcripts []*core.Transcript
+	for _, dir := range dirs {
+		if !matchesProject(dir, project) {
+			continue
+		}
+		files, err := r.sessionFiles(filepath.Base(dir))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			t, err := r.ReadFile(path)
+			if err != nil {
+				r.Report.SkippedFile(path, err)
+				continue
+			}
+			transcripts = append(transcripts, t)
+		}
+	}
+	return transcripts, nil
+}
+
+// ReadAll returns every session across all projects.
+func (r *Reader) ReadAll() ([]*core.Transcript, error) {
+	files, err := r.sessionFiles("*")
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*core.Transcript
+	for _, path := range files {
+		t, err := r.ReadFile(path)
+		if err != nil {
+			r.Report.SkippedFile(path, err)
+			continue
+		}
+		all = append(all, t)
+	}
+	return all, nil
+}
+
+func (r *Reader) dir() string {
+	if r.Dir != "" {
+		return r.Dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".gemini", "tmp")
+}
+
+// Sniff reports whether path is a Gemini CLI session record or checkpoint, or
+// a directory laid out like ~/.gemini/tmp or one of its project directories.
+func (r *Reader) Sniff(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		for _, pattern := range []string{"chats", "checkpoint-*.json", filepath.Join("*", "chats"), filepath.Join("*", "checkpoint-*.json")} {
+			if matches, _ := filepath.Glob(filepath.Join(path, pattern)); len(matches) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+	if filepath.Ext(path) != ".json" {
+		return false
+	}
+	return sniffFile(path)
+}
+
+// sniffFile inspects the start of a JSON file for the fields of a session
+// record, a /restore checkpoint, or Gemini API history.
+func sniffFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, 4096)
+	n, _ := io.ReadFull(f, buf)
+	head := bytes.TrimSpace(buf[:n])
+	if len(head) == 0 {
+		return false
+	}
+	switch head[0] {
+	case '[':
+		return bytes.Contains(head, []byte(`"role"`)) && bytes.Contains(head, []byte(`"parts"`))
+	case '{':
+		if bytes.Contains(head, []byte(`"clientHistory"`)) {
+			return true
+		}
+		return bytes.Contains(head, []byte(`"sessionId"`)) && bytes.Contains(head, []byte(`"projectHash"`))
+	}
+	return false
+}
+
+// sessionFiles lists session records and checkpoints in the project
+// directories matching hashGlob.
+func (r *Reader) sessionFiles(hashGlob string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{
+		filepath.Join(r.dir(), hashGlob, "chats", "session-*.json"),
+		filepath.Join(r.dir(), hashGlob, "checkpoint-*.json"),
+	} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchesProject reports whether the project directory dir belongs to project.
+func matchesProject(dir, project string) bool {
+	hash := filepath.Base(dir)
+	if hash == project || hash == projectHash(project) {
+		return true
+	}
+	if strings.HasPrefix(project, "-") && hash == projectHash(strings.ReplaceAll(project, "-", "/")) {
+		return true
+	}
+	root := projectRoot(dir)
+	return root != "" && (root == project || strings.ReplaceAll(root, "/", "-") == project)
+}
+
+// projectHash returns the directory name Gemini CLI uses for a project root.
+func projectHash(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return hex.EncodeToString(sum[:])
+}
+
+// projectDir returns the <project-hash> directory containing path.
+func projectDir(path string) string {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == "chats" {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// projectRoot returns the project root recorded in the project directory's
+// .project_root file, or "" when there is none.
+func projectRoot(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, ".project_root"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// checkpointTag returns the tag of a checkpoint-<tag>.json file, or "".
+func checkpointTag(path string) string {
+	name := filepath.Base(path)
+	if !strings.HasPrefix(name, "checkpoint-") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(name, "checkpoint-"), ".json")
+}
+
+// parse decodes a session record, a checkpoint array, or a /restore
+// checkpoint object. Messages and parts of unknown types are reported to
+// report.
+func parse(data []byte, path string, report *reader.Report) (*core.Transcript, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty session file")
+	}
+
+	if data[0] == '[' {
+		var history []rawContent
+		if err := json.Unmarshal(data, &history); err != nil {
+			return nil, fmt.Errorf("decode checkpoint: %w", err)
+		}
+		return buildFromHistory(history, path, report)
+	}
+
+	var probe struct {
+		Messages      json.RawMessage `json:"messages"`
+		ClientHistory json.RawMessage `json:"clientHistory"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("decode session file: %w", err)
+	}
+	if probe.ClientHistory != nil {
+		var cp rawRestoreCheckpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			return nil, fmt.Errorf("decode checkpoint: %w", err)
+		}
+		return buildFromHistory(cp.ClientHistory, path, report)
+	}
+
+	var rec rawRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("decode session record: %w", err)
+	}
+	return buildFromRecord(rec, path, report)
+}
+
+// buildFromRecord assembles a core.Transcript from a session record.
+func buildFromRecord(rec rawRecord, path string, report *reader.Report) (*core.Transcript, error) {
+	var messages []core.Message
+	model := ""
+
+	for _, rm := range rec.Messages {
+		switch rm.Type {
+		case "user":
+			text := strings.TrimSpace(contentText(rm.Content))
+			if text == "" {
+				continue
+			}
+			messages = append(messages, core.Message{
+				UUID:      rm.ID,
+				Role:      core.RoleUser,
+				Timestamp: timestampPtr(rm.Timestamp),
+				Content: []core.ContentBlock{{
+					Type:   core.BlockText,
+					Format: core.FormatPlain,
+					Text:   text,
+				}},
+			})
+
+		case "gemini":
+			m := core.Message{
+				UUID:      rm.ID,
+				Role:      core.RoleAssistant,
+				Model:     rm.Model,
+				Timestamp: timestampPtr(rm.Timestamp),
+				Content:   mapRecordBlocks(rm),
+			}
+			switch {
+			case rm.Tokens != nil:
+				u := mapUsage(rm.Tokens.Input, rm.Tokens.Cached, rm.Tokens.Output, rm.Tokens.Thoughts)
+				m.Usage = &u
+			case rm.UsageMetadata != nil:
+				u := mapUsageMetadata(*rm.UsageMetadata)
+				m.Usage = &u
+			}
+			if len(m.Content) == 0 && m.Usage == nil {
+				continue
+			}
+			if model == "" {
+				model = rm.Model
+			}
+			messages = append(messages, m)
+
+		case "info", "error", "warning":
+			// UI notices, not conversation.
+
+		default:
+			report.Add(reader.Warning{
+				Kind:    reader.WarnUnknownBlock,
+				Path:    path,
+				Message: fmt.Sprintf("message type %q", rm.Type),
+			})
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages found in session")
+	}
+
+	t := &core.Transcript{
+		SessionID: rec.SessionID,
+		Agent:     "gemini",
+		Model:     model,
+		Title:     reader.DeriveTitle(messages),
+		CreatedAt: parseTime(rec.StartTime),
+		Usage:     reader.AggregateUsage(messages),
+		Messages:  messages,
+	}
+	if t.CreatedAt.IsZero() && messages[0].Timestamp != nil {
+		t.CreatedAt = *messages[0].Timestamp
+	}
+	if updated := timestampPtr(rec.LastUpdated); updated != nil && updated.After(t.CreatedAt) {
+		t.UpdatedAt = updated
+	}
+	return t, nil
+}
+
+func mapRecordBlocks(rm rawMessage) []core.ContentBlock {
+	var blocks []core.ContentBlock
+
+	for _, th := range rm.Thoughts {
+		text := strings.TrimSpace(th.Description)
+		if th.Subject != "" {
+			text = strings.TrimSpace("**" + th.Subject + "**\n\n" + text)
+		}
+		if text != "" {
+			blocks = append(blocks, core.ContentBlock{Type: core.BlockThinking, Text: text})
+		}
+	}
+
+	if text := contentText(rm.Content); strings.TrimSpace(text) != "" {
+		blocks = append(blocks, core.ContentBlock{Type: core.BlockText, Format: core.FormatMarkdown, Text: text})
+	}
+
+	for _, tc := range rm.ToolCalls {
+		blocks = append(blocks, core.ContentBlock{
+			Type:      core.BlockToolUse,
+			ToolUseID: tc.ID,
+			Name:      tc.Name,
+			Input:     tc.Args,
+		})
+
+		content, isErr := "", tc.Status == "error"
+		for _, p := range tc.Result {
+			if p.FunctionResponse != nil {
+				c, e := responseText(p.FunctionResponse.Response)
+				content, isErr = c, isErr || e
+			}
+		}
+		if content == "" {
+			var display string
+			if json.Unmarshal(tc.ResultDisplay, &display) == nil {
+				content = display
+			}
+		}
+		if content == "" && tc.Status == "" {
+			continue // call never completed
+		}
+		blocks = append(blocks, core.ContentBlock{
+			Type:      core.BlockToolResult,
+			ToolUseID: tc.ID,
+			Content:   content,
+			IsError:   isErr,
+		})
+	}
+	return blocks
+}
+
+// buildFromHistory assembles a core.Transcript from Gemini API history.
+// functionResponse parts arrive in user-role contents; they are folded into
+// the preceding model message next to their calls.
+func buildFromHistory(history []rawContent, path string, report *reader.Report) (*core.Transcript, error) {
+	if len(history) > 0 && history[0].Role == "user" && strings.HasPrefix(partsText(history[0].Parts), contextSetupPrefix) {
+		history = history[1:]
+		if len(history) > 0 && history[0].Role == "model" {
+			history = history[1:]
+		}
+	}
+
+	var messages []core.Message
+	// pending holds synthesized IDs of calls without an ID, keyed by tool name,
+	// so responses can be paired with them in order.
+	pending := make(map[string][]string)
+	n := 0
+
+	for _, c := range history {
+		var blocks []core.ContentBlock
+		for _, p := range c.Parts {
+			switch {
+			case p.FunctionCall != nil:
+				id := p.FunctionCall.ID
+				if id == "" {
+					n++
+					id = p.FunctionCall.Name + "-" + strconv.Itoa(n)
+					pending[p.FunctionCall.Name] = append(pending[p.FunctionCall.Name], id)
+				}
+				blocks = append(blocks, core.ContentBlock{
+					Type:      core.BlockToolUse,
+					ToolUseID: id,
+					Name:      p.FunctionCall.Name,
+					Input:     p.FunctionCall.Args,
+				})
+
+			case p.FunctionResponse != nil:
+				id := p.FunctionResponse.ID
+				if q := pending[p.FunctionResponse.Name]; id == "" && len(q) > 0 {
+					id, pending[p.FunctionResponse.Name] = q[0], q[1:]
+				}
+				content, isErr := responseText(p.FunctionResponse.Response)
+				blocks = append(blocks, core.ContentBlock{
+					Type:      core.BlockToolResult,
+					ToolUseID: id,
+					Content:   content,
+					IsError:   isErr,
+				})
+
+			case p.Thought:
+				if strings.TrimSpace(p.Text) != "" {
+					blocks = append(blocks, core.ContentBlock{Type: core.BlockThinking, Text: p.Text})
+				}
+
+			case strings.TrimSpace(p.Text) != "":
+				format := core.FormatMarkdown
+				if c.Role == "user" {
+					format = core.FormatPlain
+				}
+				blocks = append(blocks, core.ContentBlock{Type: core.BlockText, Format: format, Text: p.Text})
+
+			case p.unmappedType() != "":
+				report.Add(reader.Warning{
+					Kind:    reader.WarnUnknownBlock,
+					Path:    path,
+					Message: fmt.Sprintf("part type %q", p.unmappedType()),
+				})
+			}
+		}
+
+		if c.Role == "user" && len(blocks) > 0 && blocks[0].Type == core.BlockToolResult &&
+			len(messages) > 0 && messages[len(messages)-1].Role == core.RoleAssistant {
+			last := &messages[len(messages)-1]
+			last.Content = append(last.Content, blocks...)
+			continue
+		}
+
+		m := core.Message{Role: core.RoleUser, Content: blocks}
+		if c.Role == "model" {
+			m.Role = core.RoleAssistant
+			if c.UsageMetadata != nil {
+				u := mapUsageMetadata(*c.UsageMetadata)
+				m.Usage = &u
+			}
+		}
+		if len(m.Content) == 0 {
+			continue
+		}
+		messages = append(messages, m)
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages found in checkpoint")
+	}
+
+	t := &core.Transcript{
+		SessionID: checkpointTag(path),
+		Agent:     "gemini",
+		Title:     reader.DeriveTitle(messages),
+		Usage:     reader.AggregateUsage(messages),
+		Messages:  messages,
+	}
+	if t.SessionID == "" {
+		t.SessionID = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	// Checkpoints carry no timestamps; fall back to the file time.
+	if info, err := os.Stat(path); err == nil {
+		t.CreatedAt = info.ModTime().UTC()
+	}
+	return t, nil
+}
+
+// contentText decodes message content, which is either a plain string or a
+// list of parts.
+func contentText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var parts []rawPart
+	if err := json.Unmarshal(raw, &parts); err == nil {
+		return partsText(parts)
+	}
+	return ""
+}
+
+func partsText(parts []rawPart) string {
+	var texts []string
+	for _, p := range parts {
+		if p.Text != "" && !p.Thought {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// responseText extracts the output of a functionResponse. Gemini CLI stores
+// it under "output" on success and "error" on failure.
+func responseText(resp map[string]any) (string, bool) {
+	if s, ok := resp["error"].(string); ok {
+		return s, true
+	}
+	if s, ok := resp["output"].(string); ok {
+		return s, false
+	}
+	if len(resp) == 0 {
+		return "", false
+	}
+	data, _ := json.Marshal(resp)
+	return string(data), false
+}
+
+// mapUsage converts Gemini token counts, where the prompt count includes
+// cached tokens and thoughts are billed as output, into core.Usage.
+func mapUsage(prompt, cached, candidates, thoughts int) core.Usage {
+	return core.Usage{
+		InputTokens:     prompt - cached,
+		OutputTokens:    candidates + thoughts,
+		CacheReadTokens: cached,
+	}
+}
+
+func mapUsageMetadata(raw rawUsageMetadata) core.Usage {
+	return mapUsage(raw.PromptTokenCount, raw.CachedContentTokenCount, raw.CandidatesTokenCount, raw.ThoughtsTokenCount)
+}
+
+func timestampPtr(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t := parseTime(s)
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
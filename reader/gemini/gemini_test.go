@@ -0,0 +1,242 @@
+package gemini
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSessionID = "5f1c2a9e-8b7d-4c3e-9a1f-2d6e8b0c4a7f"
+
+func testdataPath(name string) string {
+	return filepath.Join("testdata", name)
+}
+
+func readTestdata(t *testing.T, name string) *core.Transcript {
+	t.Helper()
+	r := &Reader{}
+	tr, err := r.ReadFile(testdataPath(name))
+	require.NoError(t, err)
+	return tr
+}
+
+// setupTmpDir copies testdata files into a temp directory structured as
+// ~/.gemini/tmp/<sha256("/work")>/ with a session record and a checkpoint.
+func setupTmpDir(t *testing.T) *Reader {
+	t.Helper()
+	dir := t.TempDir()
+	projDir := filepath.Join(dir, projectHash("/work"))
+	require.NoError(t, os.MkdirAll(filepath.Join(projDir, "chats"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(projDir, ".project_root"), []byte("/work\n"), 0o644))
+
+	copyFile := func(name, dst string) {
+		data, err := os.ReadFile(testdataPath(name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(dst, data, 0o644))
+	}
+	copyFile("session.json", filepath.Join(projDir, "chats", "session-2026-01-01T09-00-5f1c2a9e.json"))
+	copyFile("checkpoint-fix.json", filepath.Join(projDir, "checkpoint-fix.json"))
+	copyFile("restore.json", filepath.Join(projDir, "logs.json")) // not a session file
+
+	return &Reader{Dir: dir}
+}
+
+func TestSessionRecord(t *testing.T) {
+	tr := readTestdata(t, "session.json")
+
+	assert.Equal(t, testSessionID, tr.SessionID)
+	assert.Equal(t, "gemini", tr.Agent)
+	assert.Equal(t, "gemini-2.5-pro", tr.Model)
+	assert.Equal(t, "fix the failing test", tr.Title)
+	assert.False(t, tr.CreatedAt.IsZero())
+	require.NotNil(t, tr.UpdatedAt)
+
+	t.Run("info messages are dropped", func(t *testing.T) {
+		require.Len(t, tr.Messages, 3)
+		assert.Equal(t, core.RoleUser, tr.Messages[0].Role)
+		assert.Equal(t, core.FormatPlain, tr.Messages[0].Content[0].Format)
+		assert.Equal(t, "The test now **passes**.", tr.Messages[2].Content[0].Text)
+	})
+
+	blocks := tr.Messages[1].Content
+	require.Len(t, blocks, 5)
+
+	t.Run("thoughts become thinking", func(t *testing.T) {
+		assert.Equal(t, core.BlockThinking, blocks[0].Type)
+		assert.Contains(t, blocks[0].Text, "**Running tests**")
+		assert.Contains(t, blocks[0].Text, "I should run the tests first.")
+	})
+
+	t.Run("tool calls expand to use and result", func(t *testing.T) {
+		assert.Equal(t, core.BlockToolUse, blocks[1].Type)
+		assert.Equal(t, "run_shell_command", blocks[1].Name)
+		assert.Equal(t, "go test ./...", blocks[1].Input.(map[string]any)["command"])
+		assert.Equal(t, core.BlockToolResult, blocks[2].Type)
+		assert.Equal(t, blocks[1].ToolUseID, blocks[2].ToolUseID)
+		assert.Equal(t, "FAIL auth_test.go", blocks[2].Content)
+		assert.False(t, blocks[2].IsError)
+	})
+
+	t.Run("errored tool result", func(t *testing.T) {
+		assert.Equal(t, "replace", blocks[3].Name)
+		assert.Equal(t, "old_string not found", blocks[4].Content)
+		assert.True(t, blocks[4].IsError)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		u := tr.Messages[1].Usage
+		require.NotNil(t, u)
+		assert.Equal(t, 200, u.InputTokens)
+		assert.Equal(t, 1000, u.CacheReadTokens)
+		assert.Equal(t, 50, u.OutputTokens) // output + thoughts
+
+		require.NotNil(t, tr.Usage)
+		assert.Equal(t, 400, tr.Usage.InputTokens)
+		assert.Equal(t, 2100, tr.Usage.CacheReadTokens)
+		assert.Equal(t, 70, tr.Usage.OutputTokens)
+	})
+}
+
+func TestFileChanges(t *testing.T) {
+	tr := readTestdata(t, "session.json")
+
+	stats, files := core.ComputeChanges(tr)
+	assert.Equal(t, &core.DiffStats{Added: 1, Removed: 1, Changed: 1}, stats)
+	assert.Equal(t, []core.FileChange{
+		{Path: "/work/auth.go", Operations: []core.ToolKind{core.ToolEdit}, Added: 1, Removed: 1, FirstTurn: 1, LastTurn: 1},
+	}, files)
+}
+
+func TestCheckpoint(t *testing.T) {
+	tr := readTestdata(t, "checkpoint-fix.json")
+
+	assert.Equal(t, "fix", tr.SessionID)
+	assert.Equal(t, "list the files", tr.Title)
+	assert.False(t, tr.CreatedAt.IsZero())
+
+	t.Run("context setup is dropped", func(t *testing.T) {
+		// user, assistant(thinking, call, response), assistant(text)
+		require.Len(t, tr.Messages, 3)
+		assert.Equal(t, core.RoleUser, tr.Messages[0].Role)
+	})
+
+	blocks := tr.Messages[1].Content
+	require.Len(t, blocks, 3)
+
+	t.Run("thought parts become thinking", func(t *testing.T) {
+		assert.Equal(t, core.BlockThinking, blocks[0].Type)
+		assert.Equal(t, "Listing the directory.", blocks[0].Text)
+	})
+
+	t.Run("functionResponse is paired with its call", func(t *testing.T) {
+		assert.Equal(t, core.BlockToolUse, blocks[1].Type)
+		assert.Equal(t, "list_directory", blocks[1].Name)
+		assert.NotEmpty(t, blocks[1].ToolUseID)
+		assert.Equal(t, core.BlockToolResult, blocks[2].Type)
+		assert.Equal(t, blocks[1].ToolUseID, blocks[2].ToolUseID)
+		assert.Equal(t, "main.go\ngo.mod", blocks[2].Content)
+	})
+
+	t.Run("usageMetadata", func(t *testing.T) {
+		u := tr.Messages[1].Usage
+		require.NotNil(t, u)
+		assert.Equal(t, 400, u.InputTokens)
+		assert.Equal(t, 100, u.CacheReadTokens)
+		assert.Equal(t, 20, u.OutputTokens)
+	})
+}
+
+func TestRestoreCheckpoint(t *testing.T) {
+	tr := readTestdata(t, "restore.json")
+
+	assert.Equal(t, "restore", tr.SessionID)
+	require.Len(t, tr.Messages, 2)
+	assert.Equal(t, "hi there", tr.Messages[1].Content[0].Text)
+}
+
+func TestReportWarnings(t *testing.T) {
+	dir := t.TempDir()
+	record := filepath.Join(dir, "session-2026-01-01T09-00-abc.json")
+	require.NoError(t, os.WriteFile(record, []byte(`{"sessionId":"abc","messages":[
+		{"id":"m1","type":"user","content":"hi"},
+		{"id":"m2","type":"compression","content":"summary"},
+		{"id":"m3","type":"info","content":"Loaded 2 tools"},
+		{"id":"m4","type":"gemini","content":"hello"}
+	]}`), 0o644))
+	checkpoint := filepath.Join(dir, "checkpoint-img.json")
+	require.NoError(t, os.WriteFile(checkpoint, []byte(`[
+		{"role":"user","parts":[{"text":"what is this"},{"inlineData":{"mimeType":"image/png","data":"iVBO"}}]},
+		{"role":"model","parts":[{"text":"a chart"}]}
+	]`), 0o644))
+
+	report := &reader.Report{}
+	r := &Reader{Report: report}
+	tr, err := r.ReadFile(record)
+	require.NoError(t, err)
+	assert.Len(t, tr.Messages, 2)
+	tr, err = r.ReadFile(checkpoint)
+	require.NoError(t, err)
+	assert.Len(t, tr.Messages, 2)
+
+	assert.Equal(t, []reader.Warning{
+		{Kind: reader.WarnUnknownBlock, Path: record, Message: `message type "compression"`},
+		{Kind: reader.WarnUnknownBlock, Path: checkpoint, Message: `part type "inlineData"`},
+	}, report.Warnings())
+}
+
+func TestReadSession(t *testing.T) {
+	r := setupTmpDir(t)
+
+	tr, err := r.ReadSession(testSessionID)
+	require.NoError(t, err)
+	assert.Equal(t, testSessionID, tr.SessionID)
+	assert.Equal(t, "/work", tr.Dir)
+
+	tr, err = r.ReadSession("fix")
+	require.NoError(t, err)
+	assert.Equal(t, "fix", tr.SessionID)
+
+	_, err = r.ReadSession("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestReadProject(t *testing.T) {
+	r := setupTmpDir(t)
+
+	for _, project := range []string{"/work", "-work", projectHash("/work")} {
+		transcripts, err := r.ReadProject(project)
+		require.NoError(t, err)
+		assert.Len(t, transcripts, 2, "project %q", project)
+	}
+
+	transcripts, err := r.ReadProject("/other")
+	require.NoError(t, err)
+	assert.Empty(t, transcripts)
+}
+
+func TestReadAll(t *testing.T) {
+	r := setupTmpDir(t)
+
+	transcripts, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, transcripts, 2)
+}
+
+func TestSniff(t *testing.T) {
+	r := setupTmpDir(t)
+
+	assert.True(t, r.Sniff(testdataPath("session.json")))
+	assert.True(t, r.Sniff(testdataPath("checkpoint-fix.json")))
+	assert.True(t, r.Sniff(testdataPath("restore.json")))
+	assert.True(t, r.Sniff(r.Dir))
+	assert.True(t, r.Sniff(filepath.Join(r.Dir, projectHash("/work"))))
+
+	assert.False(t, r.Sniff("../opencode/testdata/storage/session/proj1/ses_main.json"))
+	assert.False(t, r.Sniff("../claude/testdata/simple.jsonl"))
+	assert.False(t, r.Sniff(t.TempDir()))
+}
@@ -0,0 +1,108 @@
+package reader
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sonnes/chitragupt/core"
+)
+
+// TruncatedFieldSize is how much of an oversized string field is kept when a
+// reader shrinks a log line that exceeds its size limit.
+const TruncatedFieldSize = 64 << 10
+
+// TruncatedMarker starts the note appended to strings cut by TruncateStrings.
+const TruncatedMarker = "\n[… truncated "
+
+// GitAuthor returns the git user.name configured in dir, or "" on any error.
+func GitAuthor(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	cmd := exec.Command("git", "config", "user.name")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// DeriveTitle extracts a title from the first line of the first non-empty
+// user text block, truncated to 80 bytes on a word boundary.
+func DeriveTitle(messages []core.Message) string {
+	for _, m := range messages {
+		if m.Role != core.RoleUser {
+			continue
+		}
+		for _, b := range m.Content {
+			if b.Type != core.BlockText {
+				continue
+			}
+			if text := strings.TrimSpace(b.Text); text != "" {
+				line, _, _ := strings.Cut(text, "\n")
+				return Truncate(strings.TrimSpace(line), 80)
+			}
+		}
+	}
+	return ""
+}
+
+// Truncate shortens s to at most maxLen bytes plus an ellipsis, preferring
+// the last space before the limit and never splitting a UTF-8 rune.
+func Truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if i := strings.LastIndex(s[:cut], " "); i > 0 {
+		return s[:i] + "..."
+	}
+	return s[:cut] + "..."
+}
+
+// AggregateUsage sums per-message token usage, returning nil if no message
+// reported any.
+func AggregateUsage(messages []core.Message) *core.Usage {
+	var total core.Usage
+	for _, m := range messages {
+		if m.Usage != nil {
+			total.Add(*m.Usage)
+		}
+	}
+	if total == (core.Usage{}) {
+		return nil
+	}
+	return &total
+}
+
+// TruncateStrings walks a decoded JSON value, cutting long strings at keep
+// bytes (backing off to a rune boundary) and appending TruncatedMarker with
+// the number of bytes removed. Maps and slices are modified in place.
+func TruncateStrings(v any, keep int) any {
+	switch v := v.(type) {
+	case string:
+		if len(v) <= keep {
+			return v
+		}
+		cut := keep
+		for cut > 0 && !utf8.RuneStart(v[cut]) {
+			cut--
+		}
+		return fmt.Sprintf("%s%s%d bytes]", v[:cut], TruncatedMarker, len(v)-cut)
+	case []any:
+		for i := range v {
+			v[i] = TruncateStrings(v[i], keep)
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = TruncateStrings(v[k], keep)
+		}
+	}
+	return v
+}
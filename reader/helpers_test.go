@@ -0,0 +1,70 @@
+package reader
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"single line", "add a login page", "add a login page"},
+		{"multi-line prompt", "  fix the build  \nit fails with:\n  undefined: foo", "fix the build"},
+		{"leading blank lines", "\n\n  refactor auth\nthen add tests", "refactor auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := []core.Message{{
+				Role:    core.RoleUser,
+				Content: []core.ContentBlock{{Type: core.BlockText, Text: tt.text}},
+			}}
+			assert.Equal(t, tt.want, DeriveTitle(messages))
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name   string
+		s      string
+		maxLen int
+		want   string
+	}{
+		{"short", "hello", 10, "hello"},
+		{"word boundary", "hello brave world", 12, "hello brave..."},
+		{"no space", "abcdefgh", 4, "abcd..."},
+		// "é" is two bytes; cutting at byte 2 would split it.
+		{"rune boundary", "héllo", 2, "h..."},
+		{"multi-byte runes", "日本語テキスト", 7, "日本..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Truncate(tt.s, tt.maxLen)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, utf8.ValidString(got))
+		})
+	}
+}
+
+func TestAggregateUsage(t *testing.T) {
+	assert.Nil(t, AggregateUsage([]core.Message{{Role: core.RoleUser}}))
+
+	got := AggregateUsage([]core.Message{
+		{Role: core.RoleAssistant, Usage: &core.Usage{InputTokens: 3, OutputTokens: 5}},
+		{Role: core.RoleUser},
+		{Role: core.RoleAssistant, Usage: &core.Usage{InputTokens: 2, OutputTokens: 1}},
+	})
+	assert.Equal(t, &core.Usage{InputTokens: 5, OutputTokens: 6}, got)
+}
+
+func TestTruncateStrings(t *testing.T) {
+	// Never cut inside a multi-byte rune.
+	got := TruncateStrings(map[string]any{"a": []any{"héllo", 42}}, 2)
+	assert.Equal(t, map[string]any{"a": []any{"h\n[… truncated 5 bytes]", 42}}, got)
+}
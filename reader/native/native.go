@@ -0,0 +1,310 @@
+// Package native reads transcripts already in the standardized format: the
+// JSON written by "cg render --format json", archives of such documents
+// concatenated into one file, or JSONL files holding one transcript per line.
+//
+// Transcripts are looked up in a directory laid out the way cg install writes
+// them (.transcripts/<session-id>/index.json), but any tree of transcript
+// files, or a single archive file, works. Transcripts with a
+// parent_session_id are sub-agents and are not returned on their own, since
+// their parent transcript embeds them in sub_agents.
+package native
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/core/migrate"
+	"github.com/sonnes/chitragupt/reader"
+)
+
+// Reader reads standardized transcript JSON and JSONL files.
+type Reader struct {
+	// Dir overrides the default transcripts directory (.transcripts/ in the
+	// working directory). It may also name an archive file.
+	Dir string
+
+	// Report, when set, collects warnings about skipped files and JSONL lines
+	// that are not transcripts.
+	Report *reader.Report
+}
+
+// ReadFile parses the transcript file at path. A directory is read through
+// its index.json. For an archive holding several transcripts, the last one
+// is returned; set Dir to the archive and use ReadAll to get all of them.
+func (r *Reader) ReadFile(path string) (*core.Transcript, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "index.json")
+	}
+	ts, err := readFile(path, r.Report)
+	if err != nil {
+		return nil, err
+	}
+	return ts[len(ts)-1], nil
+}
+
+// ReadSession returns the transcript with the given session ID, including
+// merged transcripts that list it in session_ids.
+func (r *Reader) ReadSession(sessionID string) (*core.Transcript, error) {
+	// The install layout names each session's directory after it.
+	if t, err := r.ReadFile(filepath.Join(r.dir(), filepath.Base(sessionID), "index.json")); err == nil && hasSession(t, sessionID) {
+		return t, nil
+	}
+
+	all, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range all {
+		if hasSession(t, sessionID) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("session %s not found", sessionID)
+}
+
+// ReadProject returns the transcripts whose working directory is project,
+// given as a path or in the dash-separated form agents use for directory
+// names.
+func (r *Reader) ReadProject(project string) ([]*core.Transcript, error) {
+	all, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var transcripts []*core.Transcript
+	for _, t := range all {
+		if t.Dir == project || strings.ReplaceAll(t.Dir, "/", "-") == project {
+			transcripts = append(transcripts, t)
+		}
+	}
+	return transcripts, nil
+}
+
+// ReadAll returns every top-level transcript under the transcripts directory,
+// or in the archive file Dir names. Files that are not transcripts, such as
+// manifest.json, are ignored.
+func (r *Reader) ReadAll() ([]*core.Transcript, error) {
+	dir := r.dir()
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read transcripts directory: %w", err)
+	}
+
+	var all []*core.Transcript
+	if !info.IsDir() {
+		ts, err := readFile(dir, r.Report)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range ts {
+			if t.ParentSessionID == "" {
+				all = append(all, t)
+			}
+		}
+		return all, nil
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			r.Report.SkippedFile(path, err)
+			return nil
+		}
+		if d.IsDir() || !sniffFile(path) {
+			return nil
+		}
+		ts, err := readFile(path, r.Report)
+		if err != nil {
+			r.Report.SkippedFile(path, err)
+			return nil
+		}
+		for _, t := range ts {
+			if t.ParentSessionID == "" {
+				all = append(all, t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (r *Reader) dir() string {
+	if r.Dir != "" {
+		return r.Dir
+	}
+	return ".transcripts"
+}
+
+// Sniff implements reader.Sniffer. It recognises transcript files and
+// directories holding an index.json transcript.
+func (r *Reader) Sniff(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !info.IsDir() {
+		return sniffFile(path)
+	}
+	for _, pattern := range []string{"index.json", filepath.Join("*", "index.json")} {
+		matches, _ := filepath.Glob(filepath.Join(path, pattern))
+		if len(matches) > 0 && sniffFile(matches[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffFile reports whether path is a JSON or JSONL file whose first object
+// starts with a schema_version or session_id key, as every transcript written
+// by cg does.
+func sniffFile(path string) bool {
+	if ext := filepath.Ext(path); ext != ".json" && ext != ".jsonl" {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(io.LimitReader(f, 4096))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+	key, err := dec.Token()
+	return err == nil && (key == "schema_version" || key == "session_id")
+}
+
+// readFile parses a transcript JSON file, which may hold several
+// concatenated documents, or a JSONL file with one transcript per line. JSONL
+// lines that are not transcripts are reported to report and skipped.
+func readFile(path string, report *reader.Report) ([]*core.Transcript, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open transcript file: %w", err)
+	}
+	defer f.Close()
+
+	if filepath.Ext(path) != ".jsonl" {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("read transcript file: %w", err)
+		}
+		return decodeDocuments(data, path, report)
+	}
+
+	var ts []*core.Transcript
+	br := bufio.NewReader(f)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if t, derr := decode(line, path); derr != nil {
+				report.Add(reader.Warning{Kind: reader.WarnMalformedLine, Path: path, Line: n, Message: derr.Error()})
+			} else {
+				ts = append(ts, t)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read transcript file: %w", err)
+		}
+	}
+	if len(ts) == 0 {
+		return nil, fmt.Errorf("no transcripts found in %s", path)
+	}
+	return ts, nil
+}
+
+// decodeDocuments parses the JSON documents in data, such as the pretty-printed
+// output of several "cg render --format json" runs appended to one file. A
+// file holding one document fails if it is not a transcript. In a file holding
+// several, documents that are not transcripts are reported to report and
+// skipped; a syntax error ends the file, since the documents after it cannot
+// be told apart.
+func decodeDocuments(data []byte, path string, report *reader.Report) ([]*core.Transcript, error) {
+	var ts []*core.Transcript
+	var skipped []reader.Warning
+	var firstErr error
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		off := int(dec.InputOffset())
+		var doc json.RawMessage
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		syntax := err != nil
+		var t *core.Transcript
+		if syntax {
+			err = fmt.Errorf("parse %s: %w", path, err)
+		} else {
+			t, err = decode(doc, path)
+		}
+		if err == nil {
+			ts = append(ts, t)
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		skipped = append(skipped, reader.Warning{
+			Kind:    reader.WarnMalformedLine,
+			Path:    path,
+			Line:    lineAt(data, off),
+			Message: err.Error(),
+		})
+		if syntax {
+			break
+		}
+	}
+	if len(ts) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, fmt.Errorf("no transcripts found in %s", path)
+	}
+	for _, w := range skipped {
+		report.Add(w)
+	}
+	return ts, nil
+}
+
+// lineAt returns the 1-based line of the first non-space byte at or after
+// offset off in data.
+func lineAt(data []byte, off int) int {
+	rest := bytes.TrimLeft(data[off:], " \t\r\n")
+	return bytes.Count(data[:len(data)-len(rest)], []byte("\n")) + 1
+}
+
+// decode parses one transcript read from path, upgrading documents written by
+// earlier versions of cg and rejecting those without the fields every
+// transcript carries.
+func decode(data []byte, path string) (*core.Transcript, error) {
+	t, err := migrate.Transcript(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if t.SessionID == "" || t.Agent == "" {
+		return nil, fmt.Errorf("parse %s: missing session_id or agent", path)
+	}
+	return t, nil
+}
+
+// hasSession reports whether t is, or was merged from, session id.
+func hasSession(t *core.Transcript, id string) bool {
+	return t.SessionID == id || slices.Contains(t.SessionIDs, id)
+}
@@ -0,0 +1,191 @@
+package native
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTranscripts = "testdata/transcripts"
+
+func testdataPath(name string) string {
+	return filepath.Join("testdata", name)
+}
+
+func sessionIDs(ts []*core.Transcript) []string {
+	var ids []string
+	for _, t := range ts {
+		ids = append(ids, t.SessionID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestReadFile(t *testing.T) {
+	r := &Reader{}
+
+	tr, err := r.ReadFile(filepath.Join(testTranscripts, "sess-1", "index.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "sess-1", tr.SessionID)
+	assert.Equal(t, "claude", tr.Agent)
+	assert.Equal(t, "fix the bug", tr.Title)
+	require.Len(t, tr.Messages, 2)
+	assert.Equal(t, core.RoleUser, tr.Messages[0].Role)
+	assert.Equal(t, &core.Usage{InputTokens: 100, OutputTokens: 50, CacheReadTokens: 5, CacheCreationTokens: 10}, tr.Usage)
+
+	// A session directory is read through its index.json.
+	tr, err = r.ReadFile(filepath.Join(testTranscripts, "sess-sub"))
+	require.NoError(t, err)
+	assert.Equal(t, "sess-main-1", tr.SessionID)
+	require.Len(t, tr.SubAgents, 1)
+	assert.Equal(t, "agent-a1", tr.SubAgents[0].SessionID)
+
+	// An archive yields its last transcript.
+	tr, err = r.ReadFile(testdataPath("archive.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "sess-2", tr.SessionID)
+
+	manifest := filepath.Join(testTranscripts, "manifest.json")
+	_, err = r.ReadFile(manifest)
+	assert.EqualError(t, err, "parse "+manifest+": missing session_id or agent")
+}
+
+// writeArchive concatenates the pretty-printed transcripts of the testdata
+// directory into one file, as appending "cg render --format json" output does.
+func writeArchive(t *testing.T, extra string) string {
+	t.Helper()
+	var data []byte
+	for _, name := range []string{"sess-1", "sess-sub"} {
+		b, err := os.ReadFile(filepath.Join(testTranscripts, name, "index.json"))
+		require.NoError(t, err)
+		data = append(data, b...)
+	}
+	path := filepath.Join(t.TempDir(), "archive.json")
+	require.NoError(t, os.WriteFile(path, append(data, extra...), 0o644))
+	return path
+}
+
+func TestReadArchive(t *testing.T) {
+	path := writeArchive(t, "")
+
+	r := &Reader{}
+	tr, err := r.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "sess-main-1", tr.SessionID)
+	require.Len(t, tr.SubAgents, 1)
+
+	report := &reader.Report{}
+	r = &Reader{Dir: path, Report: report}
+	all, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sess-1", "sess-main-1"}, sessionIDs(all))
+	assert.Empty(t, report.Warnings())
+
+	tr, err = r.ReadSession("sess-1")
+	require.NoError(t, err)
+	assert.Equal(t, "fix the bug", tr.Title)
+
+	t.Run("documents that are not transcripts", func(t *testing.T) {
+		path := writeArchive(t, "{\n  \"session_id\": \"sess-3\"\n}\n{\"session_id\":")
+
+		report := &reader.Report{}
+		all, err := (&Reader{Dir: path, Report: report}).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sess-1", "sess-main-1"}, sessionIDs(all))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		// The document opens on the line before its session_id.
+		line := strings.Count(string(data[:strings.Index(string(data), `"sess-3"`)]), "\n")
+
+		warnings := report.Warnings()
+		require.Len(t, warnings, 2)
+		assert.Equal(t, reader.Warning{
+			Kind:    reader.WarnMalformedLine,
+			Path:    path,
+			Line:    line,
+			Message: "parse " + path + ": missing session_id or agent",
+		}, warnings[0])
+		assert.Equal(t, reader.WarnMalformedLine, warnings[1].Kind)
+		assert.Equal(t, line+3, warnings[1].Line)
+		assert.Contains(t, warnings[1].Message, "parse "+path+": ")
+	})
+}
+
+func TestReadAll(t *testing.T) {
+	report := &reader.Report{}
+	r := &Reader{Dir: testTranscripts, Report: report}
+
+	all, err := r.ReadAll()
+	require.NoError(t, err)
+	// Sub-agent files and the manifest are not listed.
+	assert.Equal(t, []string{"sess-1", "sess-main-1"}, sessionIDs(all))
+	assert.Empty(t, report.Warnings())
+
+	_, err = (&Reader{Dir: filepath.Join(t.TempDir(), "missing")}).ReadAll()
+	assert.Error(t, err)
+}
+
+func TestReadAllJSONL(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile(testdataPath("archive.jsonl"))
+	require.NoError(t, err)
+	archive := filepath.Join(dir, "archive.jsonl")
+	require.NoError(t, os.WriteFile(archive, append(data, `{"session_id":"sess-3",`+"\n"...), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"session_id":"x",`), 0o644))
+
+	report := &reader.Report{}
+	all, err := (&Reader{Dir: dir, Report: report}).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sess-1", "sess-2"}, sessionIDs(all))
+
+	warnings := report.Warnings()
+	require.Len(t, warnings, 2)
+	assert.Equal(t, reader.WarnMalformedLine, warnings[0].Kind)
+	assert.Equal(t, archive, warnings[0].Path)
+	assert.Equal(t, 3, warnings[0].Line)
+	assert.Equal(t, reader.WarnSkippedFile, warnings[1].Kind)
+	assert.Equal(t, filepath.Join(dir, "broken.json"), warnings[1].Path)
+}
+
+func TestReadSession(t *testing.T) {
+	r := &Reader{Dir: testTranscripts}
+
+	tr, err := r.ReadSession("sess-main-1")
+	require.NoError(t, err)
+	assert.Equal(t, "sess-main-1", tr.SessionID)
+
+	_, err = r.ReadSession("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestReadProject(t *testing.T) {
+	r := &Reader{Dir: testTranscripts}
+
+	ts, err := r.ReadProject("-work-project")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sess-main-1"}, sessionIDs(ts))
+
+	ts, err = r.ReadProject("/work")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sess-1"}, sessionIDs(ts))
+}
+
+func TestSniff(t *testing.T) {
+	r := &Reader{}
+	assert.True(t, r.Sniff(filepath.Join(testTranscripts, "sess-1", "index.json")))
+	assert.True(t, r.Sniff(testdataPath("archive.jsonl")))
+	assert.True(t, r.Sniff(filepath.Join(testTranscripts, "sess-1")))
+	assert.True(t, r.Sniff(testTranscripts))
+	assert.False(t, r.Sniff(filepath.Join(testTranscripts, "manifest.json")))
+	assert.False(t, r.Sniff("../claude/testdata/simple.jsonl"))
+	assert.False(t, r.Sniff("../gemini/testdata/session.json"))
+	assert.False(t, r.Sniff("../codex/testdata/simple.jsonl"))
+}
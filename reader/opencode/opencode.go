@@ -1,8 +1,576 @@
-// Package opencode reads OpenCode session data (SQLite in ~/.opencode/).
+// Package opencode reads OpenCode session data (JSON files in
+// ~/.local/share/opencode/storage/).
+//
+// OpenCode stores each session as a tree of small JSON documents:
+//
+//	storage/session/<projectID>/<sessionID>.json   session info
+//	storage/message/<sessionID>/<messageID>.json   message info (role, tokens, cost)
+//	storage/part/<messageID>/<partID>.json         message parts (text, reasoning, tool, ...)
+//
+// Sessions with a parentID are child sessions spawned by the task tool; they
+// are attached to their parent as sub-agents.
 package opencode
 
-// Reader reads OpenCode sessions from a SQLite database.
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+)
+
+// Reader reads OpenCode sessions from its JSON storage directory.
 type Reader struct {
-	// DBPath overrides the default database path (~/.opencode/).
-	DBPath string
+	// Dir overrides the default storage directory
+	// ($XDG_DATA_HOME/opencode/storage or ~/.local/share/opencode/storage).
+	Dir string
+
+	// Report, when set, collects warnings about skipped sessions and message
+	// or part documents that could not be read.
+	Report *reader.Report
+}
+
+// Raw JSON deserialization types. These mirror the storage documents on disk.
+
+type rawSession struct {
+	ID        string  `json:"id"`
+	ProjectID string  `json:"projectID"`
+	Directory string  `json:"directory"`
+	ParentID  string  `json:"parentID"`
+	Title     string  `json:"title"`
+	Time      rawTime `json:"time"`
+}
+
+type rawTime struct {
+	Created   int64 `json:"created"`
+	Updated   int64 `json:"updated"`
+	Completed int64 `json:"completed"`
+}
+
+type rawMessage struct {
+	ID         string     `json:"id"`
+	SessionID  string     `json:"sessionID"`
+	Role       string     `json:"role"`
+	Time       rawTime    `json:"time"`
+	ModelID    string     `json:"modelID"`
+	ProviderID string     `json:"providerID"`
+	Cost       float64    `json:"cost"`
+	Tokens     *rawTokens `json:"tokens"`
+}
+
+type rawTokens struct {
+	Input     int `json:"input"`
+	Output    int `json:"output"`
+	Reasoning int `json:"reasoning"`
+	Cache     struct {
+		Read  int `json:"read"`
+		Write int `json:"write"`
+	} `json:"cache"`
+}
+
+type rawPart struct {
+	ID        string        `json:"id"`
+	MessageID string        `json:"messageID"`
+	Type      string        `json:"type"`
+	Text      string        `json:"text"`
+	Synthetic bool          `json:"synthetic"`
+	CallID    string        `json:"callID"`
+	Tool      string        `json:"tool"`
+	State     *rawToolState `json:"state"`
+}
+
+type rawToolState struct {
+	Status   string         `json:"status"`
+	Input    any            `json:"input"`
+	Output   string         `json:"output"`
+	Error    string         `json:"error"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+// ReadFile parses a session info file (storage/session/<projectID>/<id>.json)
+// along with its messages, parts and child sessions.
+func (r *Reader) ReadFile(path string) (*core.Transcript, error) {
+	info, err := readSessionInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	// path: <storage>/session/<projectID>/<sessionID>.json
+	storage := filepath.Dir(filepath.Dir(filepath.Dir(path)))
+	projectGlob := info.ProjectID
+	if projectGlob == "" {
+		projectGlob = "*"
+	}
+	infos, err := listSessions(filepath.Join(storage, "session"), projectGlob, r.Report)
+	if err != nil {
+		return nil, fmt.Errorf("list child sessions: %w", err)
+	}
+	return buildTranscript(storage, info, indexChildren(infos), r.Report)
+}
+
+// ReadSession locates and parses a session by its ID across all projects.
+func (r *Reader) ReadSession(sessionID string) (*core.Transcript, error) {
+	matches, err := filepath.Glob(filepath.Join(r.dir(), "session", "*", sessionID+".json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	return r.ReadFile(matches[0])
+}
+
+// ReadProject returns all top-level sessions for a project. The project may be
+// given as an OpenCode project ID, a working directory, or a working directory
+// in Claude's dash-encoded form ("/Users/foo/bar" → "-Users-foo-bar").
+func (r *Reader) ReadProject(project string) ([]*core.Transcript, error) {
+	infos, err := r.sessionInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	children := indexChildren(infos)
+	var transcripts []*core.Transcript
+	for _, info := range infos {
+		if info.ParentID != "" || !matchesProject(info, project) {
+			continue
+		}
+		t, err := buildTranscript(r.dir(), info, children, r.Report)
+		if err != nil {
+			r.skipped(info, err)
+			continue
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, nil
+}
+
+// ReadAll returns every top-level session transcript. Child sessions are
+// reachable through their parent's SubAgents.
+func (r *Reader) ReadAll() ([]*core.Transcript, error) {
+	infos, err := r.sessionInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	children := indexChildren(infos)
+	var all []*core.Transcript
+	for _, info := range infos {
+		if info.ParentID != "" {
+			continue
+		}
+		t, err := buildTranscript(r.dir(), info, children, r.Report)
+		if err != nil {
+			r.skipped(info, err)
+			continue
+		}
+		all = append(all, t)
+	}
+	return all, nil
+}
+
+// skipped reports a session that failed to read.
+func (r *Reader) skipped(info *rawSession, err error) {
+	skippedSession(r.Report, r.dir(), info, err)
+}
+
+// skippedSession reports a session of the storage directory that failed to
+// read.
+func skippedSession(report *reader.Report, storage string, info *rawSession, err error) {
+	report.Add(reader.Warning{
+		Kind:    reader.WarnSkippedFile,
+		Path:    storage,
+		Message: fmt.Sprintf("session %s: %v", info.ID, err),
+	})
+}
+
+func (r *Reader) dir() string {
+	if r.Dir != "" {
+		return r.Dir
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "opencode", "storage")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "opencode", "storage")
+}
+
+// Sniff reports whether path is an OpenCode session info document or a
+// storage directory.
+func (r *Reader) Sniff(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		for _, sub := range []string{"session", "message"} {
+			if fi, err := os.Stat(filepath.Join(path, sub)); err != nil || !fi.IsDir() {
+				return false
+			}
+		}
+		return true
+	}
+	if !strings.HasSuffix(path, ".json") {
+		return false
+	}
+	s, err := readSessionInfo(path)
+	return err == nil && s.ProjectID != "" && strings.HasPrefix(s.ID, "ses")
+}
+
+// sessionInfos reads every session info document under the storage directory.
+func (r *Reader) sessionInfos() ([]*rawSession, error) {
+	sessionDir := filepath.Join(r.dir(), "session")
+	if _, err := os.Stat(sessionDir); err != nil {
+		return nil, fmt.Errorf("read session directory: %w", err)
+	}
+	return listSessions(sessionDir, "*", r.Report)
+}
+
+func matchesProject(info *rawSession, project string) bool {
+	return info.ProjectID == project ||
+		info.Directory == project ||
+		strings.ReplaceAll(info.Directory, "/", "-") == project
+}
+
+// listSessions reads session info documents in sessionDir/<projectGlob>/,
+// sorted by creation time. Unreadable documents are reported to report.
+func listSessions(sessionDir, projectGlob string, report *reader.Report) ([]*rawSession, error) {
+	paths, err := filepath.Glob(filepath.Join(sessionDir, projectGlob, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []*rawSession
+	for _, p := range paths {
+		info, err := readSessionInfo(p)
+		if err != nil {
+			report.SkippedFile(p, err)
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Time.Created < infos[j].Time.Created
+	})
+	return infos, nil
+}
+
+// indexChildren maps session IDs to their child sessions, keeping the order
+// of infos.
+func indexChildren(infos []*rawSession) map[string][]*rawSession {
+	children := make(map[string][]*rawSession)
+	for _, info := range infos {
+		if info.ParentID != "" {
+			children[info.ParentID] = append(children[info.ParentID], info)
+		}
+	}
+	return children
+}
+
+func readSessionInfo(path string) (*rawSession, error) {
+	var info rawSession
+	if err := readJSON(path, &info); err != nil {
+		return nil, fmt.Errorf("read session info: %w", err)
+	}
+	if info.ID == "" {
+		return nil, fmt.Errorf("read session info: missing id in %s", path)
+	}
+	return &info, nil
+}
+
+// buildTranscript assembles a core.Transcript for a session, including its
+// child sessions, looked up in children, as sub-agents. Documents that cannot
+// be read are reported to report and skipped.
+func buildTranscript(storage string, info *rawSession, children map[string][]*rawSession, report *reader.Report) (*core.Transcript, error) {
+	rawMsgs, err := readMessages(storage, info.ID, report)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []core.Message
+	model := ""
+	childIDs := make(map[string]string) // tool call ID → child session ID
+	for _, rm := range rawMsgs {
+		parts, err := readParts(storage, rm.ID, report)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parts {
+			if id := childSessionID(p); id != "" {
+				childIDs[p.CallID] = id
+			}
+		}
+		m, ok := mapMessage(rm, parts)
+		if !ok {
+			continue
+		}
+		if model == "" && m.Model != "" {
+			model = m.Model
+		}
+		messages = append(messages, m)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("no messages found in session")
+	}
+
+	t := &core.Transcript{
+		SessionID:       info.ID,
+		ParentSessionID: info.ParentID,
+		Agent:           "opencode",
+		Model:           model,
+		Dir:             info.Directory,
+		Title:           info.Title,
+		Usage:           reader.AggregateUsage(messages),
+		Messages:        messages,
+	}
+	if info.Time.Created != 0 {
+		t.CreatedAt = msTime(info.Time.Created)
+	} else if messages[0].Timestamp != nil {
+		t.CreatedAt = *messages[0].Timestamp
+	}
+	if info.ParentID == "" {
+		t.Author = reader.GitAuthor(info.Directory)
+	}
+	if t.Title == "" {
+		t.Title = reader.DeriveTitle(messages)
+	}
+	if info.Time.Updated > info.Time.Created {
+		updated := msTime(info.Time.Updated)
+		t.UpdatedAt = &updated
+	}
+
+	attachChildren(storage, info, t, childIDs, children, report)
+	return t, nil
+}
+
+// readMessages reads all message documents for a session, ordered by creation
+// time (message IDs are monotonic, so they break ties). Unreadable documents
+// are reported to report.
+func readMessages(storage, sessionID string, report *reader.Report) ([]rawMessage, error) {
+	dir := filepath.Join(storage, "message", sessionID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read message directory: %w", err)
+	}
+
+	var msgs []rawMessage
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		var m rawMessage
+		path := filepath.Join(dir, e.Name())
+		if err := readJSON(path, &m); err != nil {
+			report.SkippedFile(path, err)
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	sort.SliceStable(msgs, func(i, j int) bool {
+		if msgs[i].Time.Created != msgs[j].Time.Created {
+			return msgs[i].Time.Created < msgs[j].Time.Created
+		}
+		return msgs[i].ID < msgs[j].ID
+	})
+	return msgs, nil
+}
+
+// readParts reads all part documents for a message in ID order. A message
+// without a part directory has no parts. Unreadable documents are reported to
+// report.
+func readParts(storage, messageID string, report *reader.Report) ([]rawPart, error) {
+	dir := filepath.Join(storage, "part", messageID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read part directory: %w", err)
+	}
+
+	var parts []rawPart
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		var p rawPart
+		path := filepath.Join(dir, e.Name())
+		if err := readJSON(path, &p); err != nil {
+			report.SkippedFile(path, err)
+			continue
+		}
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].ID < parts[j].ID })
+	return parts, nil
+}
+
+// mapMessage converts a message and its parts into a core.Message. Tool parts
+// expand into a tool_use block followed by its tool_result, mirroring how the
+// Claude reader folds results into the assistant message.
+func mapMessage(rm rawMessage, parts []rawPart) (core.Message, bool) {
+	var role core.Role
+	switch rm.Role {
+	case "user":
+		role = core.RoleUser
+	case "assistant":
+		role = core.RoleAssistant
+	default:
+		return core.Message{}, false
+	}
+
+	m := core.Message{UUID: rm.ID, Role: role}
+	if rm.Time.Created != 0 {
+		ts := msTime(rm.Time.Created)
+		m.Timestamp = &ts
+	}
+	if role == core.RoleAssistant {
+		m.Model = rm.ModelID
+		if rm.Tokens != nil || rm.Cost != 0 {
+			u := mapUsage(rm.Tokens, rm.Cost)
+			m.Usage = &u
+		}
+	}
+
+	for _, p := range parts {
+		m.Content = append(m.Content, mapPart(p, role)...)
+	}
+	if len(m.Content) == 0 {
+		return core.Message{}, false
+	}
+	return m, true
+}
+
+func mapPart(p rawPart, role core.Role) []core.ContentBlock {
+	switch p.Type {
+	case "text":
+		if p.Synthetic || strings.TrimSpace(p.Text) == "" {
+			return nil
+		}
+		format := core.FormatPlain
+		if role == core.RoleAssistant {
+			format = core.FormatMarkdown
+		}
+		return []core.ContentBlock{{Type: core.BlockText, Format: format, Text: p.Text}}
+
+	case "reasoning":
+		if strings.TrimSpace(p.Text) == "" {
+			return nil
+		}
+		return []core.ContentBlock{{Type: core.BlockThinking, Text: p.Text}}
+
+	case "tool":
+		use := core.ContentBlock{
+			Type:      core.BlockToolUse,
+			ToolUseID: p.CallID,
+			Name:      p.Tool,
+		}
+		if p.State == nil {
+			return []core.ContentBlock{use}
+		}
+		use.Input = p.State.Input
+		blocks := []core.ContentBlock{use}
+		switch p.State.Status {
+		case "completed":
+			blocks = append(blocks, core.ContentBlock{
+				Type:      core.BlockToolResult,
+				ToolUseID: p.CallID,
+				Content:   p.State.Output,
+			})
+		case "error":
+			blocks = append(blocks, core.ContentBlock{
+				Type:      core.BlockToolResult,
+				ToolUseID: p.CallID,
+				Content:   p.State.Error,
+				IsError:   true,
+			})
+		}
+		return blocks
+
+	default:
+		// step-start, step-finish, snapshot, patch, file, agent: bookkeeping
+		// parts with no transcript content.
+		return nil
+	}
+}
+
+func mapUsage(raw *rawTokens, cost float64) core.Usage {
+	u := core.Usage{Cost: cost}
+	if raw != nil {
+		u.InputTokens = raw.Input
+		u.OutputTokens = raw.Output + raw.Reasoning
+		u.CacheReadTokens = raw.Cache.Read
+		u.CacheCreationTokens = raw.Cache.Write
+	}
+	return u
+}
+
+// childSessionID returns the child session spawned by a task tool part. The
+// task tool records the child session ID in its state metadata.
+func childSessionID(p rawPart) string {
+	if p.Type != "tool" || p.State == nil {
+		return ""
+	}
+	for _, key := range []string{"sessionId", "sessionID"} {
+		if id, ok := p.State.Metadata[key].(string); ok && id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// attachChildren parses the child sessions of info and links them to the
+// tool calls that spawned them, given as a tool call ID → session ID map.
+// Child sessions that fail to read are reported to report.
+func attachChildren(storage string, info *rawSession, t *core.Transcript, childIDs map[string]string, children map[string][]*rawSession, report *reader.Report) {
+	subIndex := make(map[string]*core.Transcript)
+	for _, child := range children[info.ID] {
+		sub, err := buildTranscript(storage, child, children, report)
+		if err != nil {
+			skippedSession(report, storage, child, err)
+			continue
+		}
+		t.SubAgents = append(t.SubAgents, sub)
+		subIndex[sub.SessionID] = sub
+	}
+	if len(subIndex) == 0 {
+		return
+	}
+
+	for i := range t.Messages {
+		for j := range t.Messages[i].Content {
+			b := &t.Messages[i].Content[j]
+			if b.Type != core.BlockToolUse {
+				continue
+			}
+			childID, ok := childIDs[b.ToolUseID]
+			if !ok {
+				continue
+			}
+			if _, found := subIndex[childID]; !found {
+				continue
+			}
+			ref := core.SubAgentRef{AgentID: childID}
+			if m, ok := b.Input.(map[string]any); ok {
+				ref.AgentType, _ = m["subagent_type"].(string)
+			}
+			b.SubAgentRef = &ref
+		}
+	}
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func msTime(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
 }
@@ -0,0 +1,220 @@
+package opencode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/sonnes/chitragupt/reader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStorage = "testdata/storage"
+
+func readMain(t *testing.T) *core.Transcript {
+	t.Helper()
+	r := &Reader{}
+	tr, err := r.ReadFile(filepath.Join(testStorage, "session", "proj1", "ses_main.json"))
+	require.NoError(t, err)
+	return tr
+}
+
+func TestBuildTranscript(t *testing.T) {
+	tr := readMain(t)
+
+	assert.Equal(t, "ses_main", tr.SessionID)
+	assert.Equal(t, "opencode", tr.Agent)
+	assert.Equal(t, "claude-sonnet-4-5", tr.Model)
+	assert.Equal(t, "/work", tr.Dir)
+	assert.Equal(t, "Fix the login bug", tr.Title)
+	assert.False(t, tr.CreatedAt.IsZero())
+	require.NotNil(t, tr.UpdatedAt)
+
+	// user, assistant(thinking, task, result, bash, error), assistant(text, edit, result)
+	require.Len(t, tr.Messages, 3)
+	assert.Equal(t, core.RoleUser, tr.Messages[0].Role)
+	assert.Equal(t, core.RoleAssistant, tr.Messages[1].Role)
+	assert.Equal(t, core.RoleAssistant, tr.Messages[2].Role)
+}
+
+func TestPartMapping(t *testing.T) {
+	tr := readMain(t)
+
+	t.Run("synthetic text parts are dropped", func(t *testing.T) {
+		user := tr.Messages[0]
+		require.Len(t, user.Content, 1)
+		assert.Equal(t, "fix the login bug", user.Content[0].Text)
+		assert.Equal(t, core.FormatPlain, user.Content[0].Format)
+	})
+
+	blocks := tr.Messages[1].Content
+	require.Len(t, blocks, 5)
+
+	t.Run("reasoning becomes thinking", func(t *testing.T) {
+		assert.Equal(t, core.BlockThinking, blocks[0].Type)
+		assert.Equal(t, "Need to find the auth code first.", blocks[0].Text)
+	})
+
+	t.Run("completed tool expands to use and result", func(t *testing.T) {
+		assert.Equal(t, core.BlockToolUse, blocks[1].Type)
+		assert.Equal(t, "task", blocks[1].Name)
+		assert.Equal(t, "toolu_task", blocks[1].ToolUseID)
+		assert.Equal(t, core.BlockToolResult, blocks[2].Type)
+		assert.Equal(t, "toolu_task", blocks[2].ToolUseID)
+		assert.Equal(t, "Found auth.go", blocks[2].Content)
+		assert.False(t, blocks[2].IsError)
+	})
+
+	t.Run("errored tool result", func(t *testing.T) {
+		assert.Equal(t, "bash", blocks[3].Name)
+		m, ok := blocks[3].Input.(map[string]any)
+		require.True(t, ok)
+		assert.Equal(t, "go test ./...", m["command"])
+		assert.True(t, blocks[4].IsError)
+		assert.Equal(t, "exit status 1", blocks[4].Content)
+	})
+
+	t.Run("assistant text is markdown", func(t *testing.T) {
+		b := tr.Messages[2].Content[0]
+		assert.Equal(t, core.FormatMarkdown, b.Format)
+		assert.Equal(t, "Fixed the **login** bug.", b.Text)
+	})
+}
+
+func TestFileChanges(t *testing.T) {
+	tr := readMain(t)
+
+	stats, files := core.ComputeChanges(tr)
+	assert.Equal(t, &core.DiffStats{Added: 3, Removed: 0, Changed: 1}, stats)
+	assert.Equal(t, []core.FileChange{
+		{Path: "/work/auth.go", Operations: []core.ToolKind{core.ToolEdit}, Added: 3, FirstTurn: 1, LastTurn: 1},
+	}, files)
+}
+
+func TestUsage(t *testing.T) {
+	tr := readMain(t)
+
+	u := tr.Messages[1].Usage
+	require.NotNil(t, u)
+	assert.Equal(t, 100, u.InputTokens)
+	assert.Equal(t, 50, u.OutputTokens) // output + reasoning
+	assert.Equal(t, 500, u.CacheReadTokens)
+	assert.Equal(t, 20, u.CacheCreationTokens)
+	assert.InDelta(t, 0.0125, u.Cost, 1e-9)
+
+	require.NotNil(t, tr.Usage)
+	assert.Equal(t, 150, tr.Usage.InputTokens)
+	assert.Equal(t, 70, tr.Usage.OutputTokens)
+	assert.InDelta(t, 0.02, tr.Usage.Cost, 1e-9)
+}
+
+func TestChildSessions(t *testing.T) {
+	tr := readMain(t)
+
+	require.Len(t, tr.SubAgents, 1)
+	sub := tr.SubAgents[0]
+	assert.Equal(t, "ses_child", sub.SessionID)
+	assert.Equal(t, "ses_main", sub.ParentSessionID)
+	assert.Equal(t, "claude-haiku-4-5", sub.Model)
+	assert.Len(t, sub.Messages, 2)
+
+	task := tr.Messages[1].Content[1]
+	require.NotNil(t, task.SubAgentRef)
+	assert.Equal(t, "ses_child", task.SubAgentRef.AgentID)
+	assert.Equal(t, "general", task.SubAgentRef.AgentType)
+}
+
+func TestNestedChildSessions(t *testing.T) {
+	storage := filepath.Join(t.TempDir(), "storage")
+	require.NoError(t, os.CopyFS(storage, os.DirFS(testStorage)))
+	write := func(path, data string) {
+		path = filepath.Join(storage, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	}
+	write("session/proj1/ses_grand.json", `{"id":"ses_grand","projectID":"proj1","directory":"/work","parentID":"ses_child","time":{"created":1767258015000}}`)
+	write("message/ses_grand/msg_gu1.json", `{"id":"msg_gu1","sessionID":"ses_grand","role":"user","time":{"created":1767258015000}}`)
+	write("part/msg_gu1/prt_200.json", `{"id":"prt_200","sessionID":"ses_grand","messageID":"msg_gu1","type":"text","text":"Look deeper"}`)
+
+	transcripts, err := (&Reader{Dir: storage}).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, transcripts, 1)
+	require.Len(t, transcripts[0].SubAgents, 1)
+	child := transcripts[0].SubAgents[0]
+	require.Len(t, child.SubAgents, 1)
+	assert.Equal(t, "ses_grand", child.SubAgents[0].SessionID)
+	assert.Equal(t, "ses_child", child.SubAgents[0].ParentSessionID)
+}
+
+func TestReadSession(t *testing.T) {
+	r := &Reader{Dir: testStorage}
+
+	tr, err := r.ReadSession("ses_main")
+	require.NoError(t, err)
+	assert.Equal(t, "ses_main", tr.SessionID)
+
+	_, err = r.ReadSession("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestReadProject(t *testing.T) {
+	r := &Reader{Dir: testStorage}
+
+	for _, project := range []string{"proj1", "/work", "-work"} {
+		transcripts, err := r.ReadProject(project)
+		require.NoError(t, err)
+		// Child sessions are attached to their parent, not listed separately.
+		assert.Len(t, transcripts, 1, "project %q", project)
+	}
+
+	transcripts, err := r.ReadProject("other")
+	require.NoError(t, err)
+	assert.Empty(t, transcripts)
+}
+
+func TestReadAll(t *testing.T) {
+	r := &Reader{Dir: testStorage}
+
+	transcripts, err := r.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, transcripts, 1)
+	assert.Equal(t, "ses_main", transcripts[0].SessionID)
+}
+
+func TestReportWarnings(t *testing.T) {
+	storage := filepath.Join(t.TempDir(), "storage")
+	require.NoError(t, os.CopyFS(storage, os.DirFS(testStorage)))
+
+	corrupt := filepath.Join(storage, "part", "msg_a1", "prt_015.json")
+	require.NoError(t, os.WriteFile(corrupt, []byte(`{"id":"prt_015",`), 0o644))
+	// A child session without messages cannot be built.
+	require.NoError(t, os.WriteFile(filepath.Join(storage, "session", "proj1", "ses_empty.json"),
+		[]byte(`{"id":"ses_empty","projectID":"proj1","parentID":"ses_main","directory":"/work"}`), 0o644))
+
+	report := &reader.Report{}
+	r := &Reader{Dir: storage, Report: report}
+	transcripts, err := r.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, transcripts, 1)
+	assert.Len(t, transcripts[0].SubAgents, 1)
+
+	warnings := report.Warnings()
+	require.Len(t, warnings, 2)
+	assert.Equal(t, reader.WarnSkippedFile, warnings[0].Kind)
+	assert.Equal(t, corrupt, warnings[0].Path)
+	assert.Equal(t, reader.WarnSkippedFile, warnings[1].Kind)
+	assert.Equal(t, storage, warnings[1].Path)
+	assert.Contains(t, warnings[1].Message, "session ses_empty: ")
+}
+
+func TestSniff(t *testing.T) {
+	r := &Reader{}
+
+	assert.True(t, r.Sniff(filepath.Join(testStorage, "session", "proj1", "ses_main.json")))
+	assert.True(t, r.Sniff(testStorage))
+
+	assert.False(t, r.Sniff("../gemini/testdata/session.json"))
+	assert.False(t, r.Sniff(filepath.Join(testStorage, "session")))
+}
@@ -0,0 +1,77 @@
+package reader
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Report collects diagnostics about session data a reader skipped. Readers
+// accept an optional *Report; a nil Report discards warnings. It is safe for
+// concurrent use.
+type Report struct {
+	mu       sync.Mutex
+	warnings []Warning
+	seen     map[Warning]bool
+}
+
+// Warning describes one piece of skipped session data.
+type Warning struct {
+	Kind    WarningKind `json:"kind"`
+	Path    string      `json:"path,omitempty"`
+	Line    int         `json:"line,omitempty"` // 1-based line number, for line-oriented files
+	Message string      `json:"message"`
+}
+
+// WarningKind enumerates what was skipped.
+type WarningKind string
+
+const (
+	WarnSkippedFile   WarningKind = "skipped_file"   // a session file or directory that could not be read
+	WarnMalformedLine WarningKind = "malformed_line" // a line or database row that is not valid JSON
+	WarnUnknownBlock  WarningKind = "unknown_block"  // a content block of a type the reader does not map
+)
+
+// String formats w as "path:line: kind: message".
+func (w Warning) String() string {
+	loc := w.Path
+	if w.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", loc, w.Line)
+	}
+	if loc == "" {
+		return fmt.Sprintf("%s: %s", w.Kind, w.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, w.Kind, w.Message)
+}
+
+// Add records w. Repeated warnings, such as those from a file read more than
+// once, are recorded once.
+func (r *Report) Add(w Warning) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.seen == nil {
+		r.seen = make(map[Warning]bool)
+	}
+	if r.seen[w] {
+		return
+	}
+	r.seen[w] = true
+	r.warnings = append(r.warnings, w)
+}
+
+// SkippedFile records a session file or directory that failed to read.
+func (r *Report) SkippedFile(path string, err error) {
+	r.Add(Warning{Kind: WarnSkippedFile, Path: path, Message: err.Error()})
+}
+
+// Warnings returns the recorded warnings in the order they were added.
+func (r *Report) Warnings() []Warning {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Warning(nil), r.warnings...)
+}
@@ -0,0 +1,27 @@
+package reader
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	var r Report
+	r.SkippedFile("/s/a.jsonl", errors.New("no messages found"))
+	r.Add(Warning{Kind: WarnMalformedLine, Path: "/s/b.jsonl", Line: 3, Message: "unexpected end of JSON input"})
+	r.SkippedFile("/s/a.jsonl", errors.New("no messages found"))
+
+	got := r.Warnings()
+	assert.Len(t, got, 2)
+	assert.Equal(t, "/s/a.jsonl: skipped_file: no messages found", got[0].String())
+	assert.Equal(t, "/s/b.jsonl:3: malformed_line: unexpected end of JSON input", got[1].String())
+	assert.Equal(t, "unknown_block: x", Warning{Kind: WarnUnknownBlock, Message: "x"}.String())
+}
+
+func TestNilReport(t *testing.T) {
+	var r *Report
+	r.SkippedFile("/s/a.jsonl", errors.New("boom"))
+	assert.Nil(t, r.Warnings())
+}
@@ -0,0 +1,29 @@
+package reader
+
+import "github.com/sonnes/chitragupt/core"
+
+// StreamReader is implemented by readers that can parse a session file
+// incrementally, so very large sessions are never fully held in memory.
+type StreamReader interface {
+	// StreamFile returns the transcript header — every field except
+	// Messages — and a stream of the session's messages. The stream reads
+	// the file afresh on each iteration.
+	StreamFile(path string) (*core.Transcript, core.MessageSeq, error)
+}
+
+// Stream returns the header and message stream for the session file at
+// path. Readers without streaming support read the whole file and stream the
+// messages from memory.
+func Stream(r Reader, path string) (*core.Transcript, core.MessageSeq, error) {
+	if sr, ok := r.(StreamReader); ok {
+		return sr.StreamFile(path)
+	}
+	t, err := r.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages := t.Messages
+	header := *t
+	header.Messages = nil
+	return &header, core.Messages(messages), nil
+}
@@ -0,0 +1,32 @@
+package reader
+
+import "github.com/sonnes/chitragupt/core"
+
+// TailReader is implemented by readers that can follow a session while the
+// agent is still writing it.
+type TailReader interface {
+	// TailFile returns a Tail positioned at the start of the session file
+	// at path.
+	TailFile(path string) (Tail, error)
+
+	// TailSession locates a session by its ID and returns a Tail
+	// positioned at the start of its file.
+	TailSession(sessionID string) (Tail, error)
+}
+
+// Tail reads a session file incrementally. It remembers how far it has read,
+// so each call to Next returns only what was appended since the last one.
+type Tail interface {
+	// Next reads the lines appended since the previous call and returns the
+	// messages they complete. A message that later lines may still add to,
+	// and a partially written last line, are held back.
+	Next() ([]core.Message, error)
+
+	// Flush returns the messages held back by Next. Call it once the
+	// session is finished or no longer followed.
+	Flush() []core.Message
+
+	// Header returns the transcript fields known from the lines read so
+	// far. Messages is always empty.
+	Header() *core.Transcript
+}
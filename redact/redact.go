@@ -45,10 +45,9 @@ func New(cfg Config) *Redactor {
 func (r *Redactor) Transform(t *core.Transcript) error {
 	t.Dir = r.redactString(t.Dir)
 	t.Title = r.redactString(t.Title)
-	for i := range t.Messages {
-		for j := range t.Messages[i].Content {
-			r.redactBlock(&t.Messages[i].Content[j])
-		}
+	r.redactMessages(t.Messages)
+	for _, b := range t.Branches {
+		r.redactMessages(b.Messages)
 	}
 	for _, sub := range t.SubAgents {
 		if err := r.Transform(sub); err != nil {
@@ -58,6 +57,14 @@ func (r *Redactor) Transform(t *core.Transcript) error {
 	return nil
 }
 
+func (r *Redactor) redactMessages(messages []core.Message) {
+	for i := range messages {
+		for j := range messages[i].Content {
+			r.redactBlock(&messages[i].Content[j])
+		}
+	}
+}
+
 func (r *Redactor) redactBlock(b *core.ContentBlock) {
 	switch b.Type {
 	case core.BlockText, core.BlockThinking:
@@ -66,6 +73,10 @@ func (r *Redactor) redactBlock(b *core.ContentBlock) {
 		b.Input = walkAny(b.Input, r.redactString)
 	case core.BlockToolResult:
 		b.Content = r.redactString(b.Content)
+	case core.BlockEvent:
+		if b.Event != nil {
+			b.Event.Summary = r.redactString(b.Event.Summary)
+		}
 	}
 }
 
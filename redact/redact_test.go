@@ -528,3 +528,43 @@ func anyEqual(a, b any) bool {
 		return false
 	}
 }
+
+func TestTransformRedactsBranches(t *testing.T) {
+	transcript := &core.Transcript{
+		SessionID: "test",
+		Agent:     "claude",
+		CreatedAt: time.Now(),
+		Branches: []core.Branch{{
+			ID: "u3",
+			Messages: []core.Message{{
+				Role:    core.RoleUser,
+				Content: []core.ContentBlock{{Type: core.BlockText, Text: "mail alice@example.com"}},
+			}},
+		}},
+	}
+
+	r := New(Config{PII: true})
+	require.NoError(t, r.Transform(transcript))
+
+	assert.NotContains(t, transcript.Branches[0].Messages[0].Content[0].Text, "alice@example.com")
+}
+
+func TestTransformRedactsEventSummary(t *testing.T) {
+	transcript := &core.Transcript{
+		SessionID: "test",
+		Agent:     "claude",
+		CreatedAt: time.Now(),
+		Messages: []core.Message{{
+			Role: core.RoleSystem,
+			Content: []core.ContentBlock{{Type: core.BlockEvent, Event: &core.Event{
+				Kind:    core.EventCompaction,
+				Summary: "user is alice@example.com",
+			}}},
+		}},
+	}
+
+	r := New(Config{PII: true})
+	require.NoError(t, r.Transform(transcript))
+
+	assert.NotContains(t, transcript.Messages[0].Content[0].Event.Summary, "alice@example.com")
+}
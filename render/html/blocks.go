@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"path/filepath"
 	"strings"
 
 	"github.com/sonnes/chitragupt/core"
@@ -22,6 +23,8 @@ func (r *Renderer) renderBlock(b core.ContentBlock, result *core.ContentBlock) (
 		return r.renderToolUseBlock(b, result)
 	case core.BlockToolResult:
 		return renderToolResultBlock(b)
+	case core.BlockImage:
+		return renderImageBlock(b)
 	default:
 		return "", fmt.Errorf("unknown block type: %s", b.Type)
 	}
@@ -67,7 +70,10 @@ func (r *Renderer) renderToolUseBlock(b core.ContentBlock, result *core.ContentB
 	}
 
 	var resultHTML string
-	if result != nil {
+	if result != nil && !result.IsError && strings.EqualFold(b.Name, "read") {
+		resultHTML = renderReadResult(result.Content)
+	}
+	if result != nil && resultHTML == "" {
 		errorClass := ""
 		textClass := ""
 		if result.IsError {
@@ -91,10 +97,7 @@ func (r *Renderer) renderToolUseBlock(b core.ContentBlock, result *core.ContentB
 			typeLabel = ` <span class="text-slate-400 dark:text-slate-500">` +
 				`(` + template.HTMLEscapeString(b.SubAgentRef.AgentType) + `)</span>`
 		}
-		href := "agent-" + template.HTMLEscapeString(b.SubAgentRef.AgentID) + ".html"
-		if r.SubAgentHref != nil {
-			href = r.SubAgentHref(b.SubAgentRef.AgentID)
-		}
+		href := template.HTMLEscapeString(r.subAgentHref(b.SubAgentRef.AgentID))
 		linkCardHTML = `<div class="border-t border-slate-200 dark:border-slate-700 px-4 py-2 flex items-center gap-2 bg-indigo-50 dark:bg-indigo-950">` +
 			`<span class="text-xs">&#128279;</span>` +
 			`<a href="` + href + `" class="text-xs font-medium text-indigo-600 dark:text-indigo-400 hover:underline">` +
@@ -107,7 +110,7 @@ func (r *Renderer) renderToolUseBlock(b core.ContentBlock, result *core.ContentB
 	toolName := template.HTMLEscapeString(b.Name)
 	icon := string(toolIcon(b.Name))
 	summaryDetail := ""
-	if s := toolInputSummary(b.Name, b.Input); s != "" {
+	if s := core.NewToolCall(&b).Summary(); s != "" {
 		summaryDetail = ` <span class="text-xs font-mono text-slate-500 dark:text-slate-400 truncate">` + template.HTMLEscapeString(s) + `</span>`
 	}
 	h := `<details class="bg-slate-50 dark:bg-slate-900 border border-slate-200 dark:border-slate-700 rounded-lg overflow-hidden">` +
@@ -123,6 +126,83 @@ func (r *Renderer) renderToolUseBlock(b core.ContentBlock, result *core.ContentB
 	return template.HTML(h), nil
 }
 
+// numberedLine is one line of `cat -n` style output. Num is "" for lines
+// without a number, such as notes appended after the file content.
+type numberedLine struct {
+	Num  string
+	Text string
+}
+
+// parseNumberedLines splits Read tool output into numbered lines. Each line
+// is a right-aligned number followed by a tab or "→" and the file content. It
+// reports false when the output does not start with a numbered line.
+func parseNumberedLines(s string) ([]numberedLine, bool) {
+	raw := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	lines := make([]numberedLine, 0, len(raw))
+	for i, line := range raw {
+		num, text, ok := splitLineNumber(line)
+		if !ok {
+			if i == 0 {
+				return nil, false
+			}
+			lines = append(lines, numberedLine{Text: line})
+			continue
+		}
+		lines = append(lines, numberedLine{Num: num, Text: text})
+	}
+	return lines, true
+}
+
+// splitLineNumber splits "     12\tcontent" or "    12→content" into its
+// number and content.
+func splitLineNumber(line string) (num, text string, ok bool) {
+	rest := strings.TrimLeft(line, " ")
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return "", "", false
+	}
+	num, rest = rest[:end], rest[end:]
+	if text, found := strings.CutPrefix(rest, "\t"); found {
+		return num, text, true
+	}
+	if text, found := strings.CutPrefix(rest, "→"); found {
+		return num, text, true
+	}
+	return "", "", false
+}
+
+// renderReadResult renders Read tool output as a collapsed, line-numbered
+// listing. It returns "" when the output is not line-numbered.
+func renderReadResult(content string) string {
+	lines, ok := parseNumberedLines(content)
+	if !ok {
+		return ""
+	}
+	var first, last string
+	var rows strings.Builder
+	for _, l := range lines {
+		if l.Num != "" {
+			if first == "" {
+				first = l.Num
+			}
+			last = l.Num
+		}
+		rows.WriteString(`<tr><td class="pr-3 text-right text-slate-400 dark:text-slate-500 select-none align-top">` +
+			l.Num + `</td><td class="whitespace-pre">` + template.HTMLEscapeString(l.Text) + `</td></tr>`)
+	}
+	label := "Line " + first
+	if last != first {
+		label = "Lines " + first + "–" + last
+	}
+	return `<details class="border-t border-slate-200 dark:border-slate-700">` +
+		`<summary class="px-4 py-2 text-xs text-slate-500 dark:text-slate-400 cursor-pointer select-none">` + label + `</summary>` +
+		`<div class="px-4 pb-3 max-h-96 overflow-auto"><table class="text-xs font-mono">` + rows.String() + `</table></div>` +
+		`</details>`
+}
+
 // renderToolResultBlock renders an orphan tool_result with no matching tool_use.
 func renderToolResultBlock(b core.ContentBlock) (template.HTML, error) {
 	escaped := template.HTMLEscapeString(b.Content)
@@ -134,34 +214,34 @@ func renderToolResultBlock(b core.ContentBlock) (template.HTML, error) {
 	return template.HTML(h), nil
 }
 
-// toolInputSummary extracts a short label from tool input for the header line.
-func toolInputSummary(toolName string, input any) string {
-	m, ok := input.(map[string]any)
-	if !ok {
-		return ""
+// renderImageBlock embeds inline image data, links an external image, or
+// falls back to a placeholder when the data was not kept.
+func renderImageBlock(b core.ContentBlock) (template.HTML, error) {
+	label := template.HTMLEscapeString(b.Image.Label())
+	imgClass := `class="max-w-full max-h-[32rem] rounded border border-slate-200 dark:border-slate-700"`
+
+	if uri := b.Image.DataURI(); uri != "" {
+		return template.HTML(`<img src="` + template.HTMLEscapeString(uri) + `" alt="` + label + `" ` + imgClass + `>`), nil
 	}
-	var key string
-	switch strings.ToLower(toolName) {
-	case "read", "write", "edit":
-		key = "file_path"
-	case "bash":
-		key = "command"
-	case "glob":
-		key = "pattern"
-	case "grep":
-		key = "pattern"
-	case "task":
-		key = "description"
-	case "notebookedit":
-		key = "notebook_path"
-	default:
-		return ""
+	if b.Image != nil && isLinkable(b.Image.URL) {
+		href := template.HTMLEscapeString(b.Image.URL)
+		return template.HTML(`<a href="` + href + `" target="_blank" rel="noopener">` +
+			`<img src="` + href + `" alt="` + label + `" ` + imgClass + `></a>`), nil
 	}
-	v, ok := m[key].(string)
-	if !ok || v == "" {
-		return ""
+	return template.HTML(`<div class="text-xs font-mono text-slate-500 dark:text-slate-400 bg-slate-50 dark:bg-slate-900 rounded p-3">` +
+		`[` + label + `]</div>`), nil
+}
+
+// isLinkable reports whether u is safe to use as an image URL: http(s) or a
+// scheme-less file path.
+func isLinkable(u string) bool {
+	if u == "" {
+		return false
+	}
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return true
 	}
-	return v
+	return !strings.Contains(u, ":") || filepath.IsAbs(u)
 }
 
 func formatToolInput(input any) string {
@@ -124,6 +124,44 @@ func TestRenderToolUseBlockPaired(t *testing.T) {
 	assert.NotContains(t, s, "border-red", "non-error should not have red styling")
 }
 
+func TestRenderToolUseBlockReadResult(t *testing.T) {
+	r := testRenderer()
+	use := core.ContentBlock{
+		Type:      core.BlockToolUse,
+		ToolUseID: "t1",
+		Name:      "Read",
+		Input:     map[string]any{"file_path": "/src/main.go"},
+	}
+	result := &core.ContentBlock{
+		Type:      core.BlockToolResult,
+		ToolUseID: "t1",
+		Content:   "     9\tpackage main\n    10\t\n    11→func main() {}\n",
+	}
+
+	out, err := r.renderToolUseBlock(use, result)
+	require.NoError(t, err)
+	s := string(out)
+	assert.Contains(t, s, "Lines 9–11")
+	assert.Contains(t, s, `select-none align-top">11</td><td class="whitespace-pre">func main() {}</td>`)
+	assert.NotContains(t, s, "\tpackage main")
+}
+
+func TestParseNumberedLines(t *testing.T) {
+	lines, ok := parseNumberedLines("     1\tone\n     2→two\n\n<system-reminder>note</system-reminder>")
+	require.True(t, ok)
+	assert.Equal(t, []numberedLine{
+		{Num: "1", Text: "one"},
+		{Num: "2", Text: "two"},
+		{Text: ""},
+		{Text: "<system-reminder>note</system-reminder>"},
+	}, lines)
+
+	_, ok = parseNumberedLines("File does not exist.")
+	assert.False(t, ok)
+	_, ok = parseNumberedLines("2024 was a year")
+	assert.False(t, ok)
+}
+
 func TestRenderToolUseBlockUnpaired(t *testing.T) {
 	r := testRenderer()
 	use := core.ContentBlock{
@@ -275,3 +313,42 @@ func TestFormatToolInput(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderImageBlock(t *testing.T) {
+	tests := []struct {
+		name  string
+		image *core.Image
+		want  []string
+	}{
+		{
+			name:  "inline data is embedded",
+			image: &core.Image{MediaType: "image/png", Data: "iVBORw0K", Width: 4, Height: 3},
+			want:  []string{`<img src="data:image/png;base64,iVBORw0K"`, `alt="image/png 4×3"`},
+		},
+		{
+			name:  "external URL is linked",
+			image: &core.Image{URL: "https://example.com/a.png"},
+			want:  []string{`<a href="https://example.com/a.png"`, `<img src="https://example.com/a.png"`},
+		},
+		{
+			name:  "unsafe URL falls back to placeholder",
+			image: &core.Image{URL: "javascript:alert(1)"},
+			want:  []string{"[image]"},
+		},
+		{
+			name:  "missing data falls back to placeholder",
+			image: &core.Image{MediaType: "image/jpeg"},
+			want:  []string{"[image/jpeg]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := renderImageBlock(core.ContentBlock{Type: core.BlockImage, Image: tt.image})
+			require.NoError(t, err)
+			for _, w := range tt.want {
+				assert.Contains(t, string(out), w)
+			}
+			assert.NotContains(t, string(out), "javascript:")
+		})
+	}
+}
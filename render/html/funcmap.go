@@ -15,6 +15,7 @@ func funcMap() template.FuncMap {
 		"isoTime":        isoTime,
 		"relativeTime":   relativeTime,
 		"formatNumber":   formatNumber,
+		"formatCost":     formatCost,
 		"formatDuration": formatDuration,
 		"toolIcon":       toolIcon,
 		"metaIcon":       metaIcon,
@@ -24,6 +25,9 @@ func funcMap() template.FuncMap {
 // toolIcon returns an inline SVG icon for a tool name (16x16, currentColor).
 func toolIcon(name string) template.HTML {
 	key := strings.ToLower(name)
+	if k := core.ToolKindOf(name); k != core.ToolUnknown {
+		key = string(k)
+	}
 	svg, ok := toolIcons[key]
 	if !ok {
 		svg = toolIcons["_default"]
@@ -107,6 +111,15 @@ func formatNumber(n int) string {
 	return formatNumber(n/1000) + "," + fmt.Sprintf("%03d", n%1000)
 }
 
+// formatCost formats an amount in USD to the cent, showing amounts under a
+// cent as "<$0.01".
+func formatCost(usd float64) string {
+	if usd > 0 && usd < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
+
 func relativeTime(t any) string {
 	switch v := t.(type) {
 	case time.Time:
@@ -59,7 +59,28 @@ func New() *Renderer {
 type pageData struct {
 	Transcript      *core.Transcript
 	Turns           []turnData
-	OverallDuration string // total session duration (e.g. "2m 30s")
+	OverallDuration string     // total session duration (e.g. "2m 30s")
+	Files           []fileData // changed files, for the "Files changed" panel
+	FilesRead       int        // files read but not changed
+	SubAgents       []subAgentData
+	TotalUsage      *core.Usage // usage including sub-agents, nil when they add none
+}
+
+// subAgentData is a sub-agent's row in the usage breakdown.
+type subAgentData struct {
+	Label string
+	Type  string
+	Href  string
+	Model string
+	Usage *core.Usage // including the sub-agent's own sub-agents
+}
+
+// fileData is a changed file with links to the turns that touched it.
+type fileData struct {
+	Path    string // relative to the session directory
+	Change  core.FileChange
+	FirstID string // anchor ID of the first turn that touched the file
+	LastID  string // anchor ID of the last turn, if different
 }
 
 // turnData groups a user prompt with its assistant response cycle.
@@ -69,26 +90,149 @@ type turnData struct {
 	UserText  string          // raw user text for timeline summary
 	Timestamp *time.Time      // user message timestamp (or first assistant timestamp)
 	Duration  string          // time since previous turn
+	Cost      string          // formatted cost of the turn's messages, if known
 	Steps     []template.HTML // rendered intermediate blocks (collapsed)
 	StepCount int             // number of tool invocations
 	Response  []template.HTML // rendered final text blocks (visible)
+	Branches  []branchData    // abandoned branches this turn replaced
+	Event     *core.Event     // set for a session event such as a compaction
+	Summary   template.HTML   // rendered event summary
+
+	parentUUID string // user message parent, for matching branches
+	number     int    // turn number counting from 1, 0 for events
+}
+
+// branchData is an abandoned branch rendered as nested turns.
+type branchData struct {
+	ID     string     // anchor ID (e.g. "branch-0")
+	Edited bool       // true when a later prompt replaced the branch's prompt
+	Turns  []turnData // rendered turns of the branch
 }
 
 // indexData is the template data passed to index.html.
 type indexData struct {
-	Entries []core.ManifestEntry
+	Entries  []core.ManifestEntry
+	HasFiles bool // some entry lists changed files, so the file filter is shown
 }
 
 // RenderIndex writes an HTML index page listing the given manifest entries to w.
 func (r *Renderer) RenderIndex(w io.Writer, entries []core.ManifestEntry) error {
-	return r.tmpl.ExecuteTemplate(w, "index.html", indexData{Entries: entries})
+	data := indexData{Entries: entries}
+	for _, e := range entries {
+		if len(e.Files) > 0 {
+			data.HasFiles = true
+			break
+		}
+	}
+	return r.tmpl.ExecuteTemplate(w, "index.html", data)
 }
 
 // Render writes the transcript as a complete HTML page to w.
 func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
+	turnDatas, err := r.buildTurns(t.Messages, "turn")
+	if err != nil {
+		return err
+	}
+	if err := r.attachBranches(turnDatas, t); err != nil {
+		return err
+	}
+
+	var overallDuration string
+	if t.UpdatedAt != nil && !t.CreatedAt.IsZero() {
+		overallDuration = formatDuration(t.UpdatedAt.Sub(t.CreatedAt))
+	}
+
+	data := pageData{
+		Transcript:      t,
+		Turns:           turnDatas,
+		OverallDuration: overallDuration,
+	}
+	data.Files, data.FilesRead = buildFiles(t, turnDatas)
+	data.SubAgents = r.buildSubAgents(t)
+	if total := t.TotalUsage(); total != nil && (t.Usage == nil || *total != *t.Usage) {
+		data.TotalUsage = total
+	}
+	return r.tmpl.ExecuteTemplate(w, "page.html", data)
+}
+
+// subAgentHref returns the link to the page of sub-agent agentID.
+func (r *Renderer) subAgentHref(agentID string) string {
+	if r.SubAgentHref != nil {
+		return r.SubAgentHref(agentID)
+	}
+	return "agent-" + agentID + ".html"
+}
+
+// buildSubAgents lists t's sub-agents with their usage, labelled by the name
+// or description of the call that started them when it is known.
+func (r *Renderer) buildSubAgents(t *core.Transcript) []subAgentData {
+	if len(t.SubAgents) == 0 {
+		return nil
+	}
+	calls := make(map[string]core.ToolCall)
+	for _, c := range core.ToolCalls(t.Messages) {
+		if ref := c.Use.SubAgentRef; ref != nil {
+			calls[ref.AgentID] = c
+		}
+	}
+
+	subs := make([]subAgentData, 0, len(t.SubAgents))
+	for _, sub := range t.SubAgents {
+		sd := subAgentData{
+			Label: sub.SessionID,
+			Href:  r.subAgentHref(sub.SessionID),
+			Model: sub.Model,
+			Usage: sub.TotalUsage(),
+		}
+		if sub.Title != "" {
+			sd.Label = sub.Title
+		}
+		if c, ok := calls[sub.SessionID]; ok {
+			ref := c.Use.SubAgentRef
+			switch {
+			case ref.AgentName != "":
+				sd.Label = ref.AgentName
+			case c.Summary() != "":
+				sd.Label = c.Summary()
+			}
+			sd.Type = ref.AgentType
+		}
+		subs = append(subs, sd)
+	}
+	return subs
+}
+
+// buildFiles lists the files t changed with the anchors of the turns that
+// touched them, and counts the files it only read.
+func buildFiles(t *core.Transcript, turns []turnData) ([]fileData, int) {
+	ids := make(map[int]string)
+	for _, td := range turns {
+		if td.number > 0 {
+			ids[td.number] = td.ID
+		}
+	}
+
+	var files []fileData
+	read := 0
+	for _, f := range t.Files {
+		if f.ReadOnly {
+			read++
+			continue
+		}
+		fd := fileData{Path: f.RelPath(t.Dir), Change: f, FirstID: ids[f.FirstTurn]}
+		if f.LastTurn != f.FirstTurn {
+			fd.LastID = ids[f.LastTurn]
+		}
+		files = append(files, fd)
+	}
+	return files, read
+}
+
+// buildTurns renders messages into turns whose anchor IDs start with idPrefix.
+func (r *Renderer) buildTurns(messages []core.Message, idPrefix string) ([]turnData, error) {
 	// Build tool_result index: tool_use_id → tool_result block.
 	resultIndex := make(map[string]core.ContentBlock)
-	for _, msg := range t.Messages {
+	for _, msg := range messages {
 		for _, b := range msg.Content {
 			if b.Type == core.BlockToolResult && b.ToolUseID != "" {
 				resultIndex[b.ToolUseID] = b
@@ -98,21 +242,40 @@ func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
 
 	consumed := make(map[string]bool)
 
-	turns := core.GroupTurns(t.Messages)
+	turns := core.GroupTurns(messages)
 	var prevTimestamp *time.Time
 	var turnDatas []turnData
 
+	number := 0
 	for i, turn := range turns {
-		td := turnData{ID: fmt.Sprintf("turn-%d", i)}
+		td := turnData{ID: fmt.Sprintf("%s-%d", idPrefix, i)}
+
+		if turn.Event != nil {
+			td.Event = core.EventOf(turn.Event)
+			td.Timestamp = turn.Event.Timestamp
+			if td.Event.Summary != "" {
+				rendered, err := r.renderBlock(core.ContentBlock{Type: core.BlockText, Text: td.Event.Summary}, nil)
+				if err != nil {
+					return nil, fmt.Errorf("render event summary: %w", err)
+				}
+				td.Summary = rendered
+			}
+			turnDatas = append(turnDatas, td)
+			continue
+		}
+
+		number++
+		td.number = number
 
 		// Render user message blocks.
 		if turn.UserMessage != nil {
 			td.Timestamp = turn.UserMessage.Timestamp
 			td.UserText = userTextSummary(*turn.UserMessage)
+			td.parentUUID = turn.UserMessage.ParentUUID
 			for _, b := range turn.UserMessage.Content {
 				rendered, err := r.renderBlock(b, nil)
 				if err != nil {
-					return fmt.Errorf("render user block: %w", err)
+					return nil, fmt.Errorf("render user block: %w", err)
 				}
 				td.User = append(td.User, rendered)
 			}
@@ -127,6 +290,10 @@ func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
 			prevTimestamp = td.Timestamp
 		}
 
+		if u := turn.Usage(); u != nil && u.Cost > 0 {
+			td.Cost = formatCost(u.Cost)
+		}
+
 		// Split assistant content into steps and response.
 		steps, response := turn.SplitContent()
 		td.StepCount = turn.StepCount()
@@ -134,7 +301,7 @@ func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
 		for _, b := range steps {
 			rendered, err := r.renderContentBlock(b, resultIndex, consumed)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if rendered != "" {
 				td.Steps = append(td.Steps, rendered)
@@ -144,7 +311,7 @@ func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
 		for _, b := range response {
 			rendered, err := r.renderContentBlock(b, resultIndex, consumed)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if rendered != "" {
 				td.Response = append(td.Response, rendered)
@@ -155,18 +322,35 @@ func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
 			turnDatas = append(turnDatas, td)
 		}
 	}
+	return turnDatas, nil
+}
 
-	var overallDuration string
-	if t.UpdatedAt != nil && !t.CreatedAt.IsZero() {
-		overallDuration = formatDuration(t.UpdatedAt.Sub(t.CreatedAt))
+// attachBranches renders each abandoned branch of t and attaches it to the
+// turn whose prompt replaced it. A branch with no replacing prompt — the
+// session was rewound and continued from an earlier point — is attached to
+// the last turn.
+func (r *Renderer) attachBranches(turns []turnData, t *core.Transcript) error {
+	if len(turns) == 0 {
+		return nil
 	}
+	for i, b := range t.Branches {
+		bturns, err := r.buildTurns(b.Messages, fmt.Sprintf("branch-%d", i))
+		if err != nil {
+			return fmt.Errorf("render branch %s: %w", b.ID, err)
+		}
+		bd := branchData{ID: fmt.Sprintf("branch-%d", i), Turns: bturns}
 
-	data := pageData{
-		Transcript:      t,
-		Turns:           turnDatas,
-		OverallDuration: overallDuration,
+		target := len(turns) - 1
+		for j, td := range turns {
+			if td.User != nil && td.parentUUID == b.ParentUUID {
+				target = j
+				bd.Edited = true
+				break
+			}
+		}
+		turns[target].Branches = append(turns[target].Branches, bd)
 	}
-	return r.tmpl.ExecuteTemplate(w, "page.html", data)
+	return nil
 }
 
 // renderContentBlock renders a single content block, handling tool_use/result pairing.
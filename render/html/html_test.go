@@ -2,6 +2,7 @@ package html
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 	"time"
 
@@ -89,6 +90,7 @@ func TestRenderFullPage(t *testing.T) {
 	t.Run("usage stats", func(t *testing.T) {
 		assert.Contains(t, html, "5,000")
 		assert.Contains(t, html, "2,000")
+		assert.NotContains(t, html, "Cost")
 	})
 
 	t.Run("working dir", func(t *testing.T) {
@@ -97,6 +99,47 @@ func TestRenderFullPage(t *testing.T) {
 	})
 }
 
+func TestRenderCost(t *testing.T) {
+	tr := buildTestTranscript()
+	tr.Usage.Cost, tr.Usage.CostEstimated = 0.42, true
+	tr.Messages[1].Usage = &core.Usage{InputTokens: 5000, Cost: 0.125, CostEstimated: true}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().Render(&buf, tr))
+	html := buf.String()
+
+	assert.Contains(t, html, "$0.42")
+	assert.Contains(t, html, "Est. Cost")
+	assert.Contains(t, html, "$0.12") // turn cost beside the steps
+}
+
+func TestRenderSubAgentUsage(t *testing.T) {
+	tr := buildTestTranscript()
+	tr.Messages[1].Content = append(tr.Messages[1].Content, core.ContentBlock{
+		Type: core.BlockToolUse, ToolUseID: "t2", Name: "Task",
+		Input:       map[string]any{"description": "Explore the auth package"},
+		SubAgentRef: &core.SubAgentRef{AgentID: "a1", AgentType: "Explore"},
+	})
+	tr.SubAgents = []*core.Transcript{
+		{SessionID: "a1", Model: "claude-haiku-4-5", Usage: &core.Usage{InputTokens: 120000, OutputTokens: 3000, Cost: 0.25}},
+		{SessionID: "a2", Title: "Unlinked agent"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().Render(&buf, tr))
+	html := buf.String()
+
+	assert.Contains(t, html, "Sub-agents")
+	assert.Contains(t, html, `href="agent-a1.html"`)
+	assert.Contains(t, html, "Explore the auth package")
+	assert.Contains(t, html, "(Explore)")
+	assert.Contains(t, html, "claude-haiku-4-5")
+	assert.Contains(t, html, "120,000")
+	assert.Contains(t, html, "Unlinked agent")
+	assert.Contains(t, html, "125,000", "total includes the session's own usage")
+	assert.Contains(t, html, "$0.25 in total")
+}
+
 func TestRenderMessages(t *testing.T) {
 	tr := buildTestTranscript()
 	r := New()
@@ -304,3 +347,135 @@ func countOccurrences(s, substr string) int {
 	}
 	return count
 }
+
+func TestRenderBranches(t *testing.T) {
+	now := time.Now()
+	text := func(role core.Role, uuid, parent, s string) core.Message {
+		return core.Message{UUID: uuid, ParentUUID: parent, Role: role, Content: []core.ContentBlock{{Type: core.BlockText, Text: s}}}
+	}
+	tr := &core.Transcript{
+		SessionID: "branch-session",
+		Agent:     "claude",
+		CreatedAt: now,
+		Messages: []core.Message{
+			text(core.RoleUser, "u1", "", "Add login"),
+			text(core.RoleAssistant, "a1", "u1", "Login added."),
+			text(core.RoleUser, "u4", "a1", "Add signup instead"),
+			text(core.RoleAssistant, "a4", "u4", "Signup added."),
+		},
+		Branches: []core.Branch{
+			{ID: "u3", ParentUUID: "a1", Messages: []core.Message{
+				text(core.RoleUser, "u3", "a1", "Add logout"),
+				text(core.RoleAssistant, "a3", "u3", "Logout added."),
+			}},
+			{ID: "u9", ParentUUID: "zz", Messages: []core.Message{
+				text(core.RoleUser, "u9", "zz", "Rewound prompt"),
+			}},
+		},
+	}
+
+	r := testRenderer()
+	var buf bytes.Buffer
+	require.NoError(t, r.Render(&buf, tr))
+	html := buf.String()
+
+	assert.Contains(t, html, "Edited prompt — view original branch")
+	assert.Contains(t, html, "Rewound — view abandoned branch")
+	assert.Contains(t, html, `id="branch-0-0"`)
+	assert.Contains(t, html, "Add logout")
+	assert.Contains(t, html, "Rewound prompt")
+
+	// The original branch sits in the turn whose prompt replaced it, before
+	// that prompt.
+	turn := strings.Index(html, `id="turn-1"`)
+	branch := strings.Index(html, `id="branch-0"`)
+	prompt := strings.Index(html, "Add signup instead")
+	assert.True(t, turn < branch && branch < prompt)
+
+	// Branch turns stay out of the main timeline.
+	assert.Equal(t, 2, countOccurrences(html, `href="#turn-`))
+	assert.Equal(t, 0, countOccurrences(html, `href="#branch-`))
+}
+
+func TestRenderCompactionEvent(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "compact-session",
+		Agent:     "claude",
+		CreatedAt: time.Now(),
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "Refactor"}}},
+			{Role: core.RoleSystem, Content: []core.ContentBlock{{Type: core.BlockEvent, Event: &core.Event{
+				Kind: core.EventCompaction, Trigger: "auto", PreTokens: 155123, Summary: "Parser **half** done.",
+			}}}},
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "Keep going"}}},
+		},
+	}
+
+	r := testRenderer()
+	var buf bytes.Buffer
+	require.NoError(t, r.Render(&buf, tr))
+	html := buf.String()
+
+	assert.Contains(t, html, `id="turn-1"`)
+	assert.Contains(t, html, "context compacted (auto, 155,123 tokens)")
+	assert.Contains(t, html, "Summary carried into the new context")
+	assert.Contains(t, html, "half")
+	assert.Less(t, strings.Index(html, "Refactor"), strings.Index(html, "context compacted"))
+	assert.Less(t, strings.Index(html, "context compacted"), strings.Index(html, "Keep going"))
+}
+
+func TestRenderFilesChanged(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "files-session",
+		Agent:     "claude",
+		Dir:       "/home/user/project",
+		CreatedAt: time.Now(),
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "Refactor"}}},
+			{Role: core.RoleAssistant, Content: []core.ContentBlock{
+				{Type: core.BlockToolUse, ToolUseID: "t1", Name: "Read", Input: map[string]any{"file_path": "/home/user/project/go.mod"}},
+				{Type: core.BlockToolUse, ToolUseID: "t2", Name: "Edit", Input: map[string]any{
+					"file_path": "/home/user/project/main.go", "old_string": "a\n", "new_string": "b\nc\n",
+				}},
+			}},
+			{Role: core.RoleSystem, Content: []core.ContentBlock{{Type: core.BlockEvent, Event: &core.Event{Kind: core.EventCompaction}}}},
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "Keep going"}}},
+			{Role: core.RoleAssistant, Content: []core.ContentBlock{
+				{Type: core.BlockToolUse, ToolUseID: "t3", Name: "Write", Input: map[string]any{"file_path": "/home/user/project/main.go", "content": "x\n"}},
+			}},
+		},
+	}
+	tr.DiffStats, tr.Files = core.ComputeChanges(tr)
+
+	r := testRenderer()
+	var buf bytes.Buffer
+	require.NoError(t, r.Render(&buf, tr))
+	html := buf.String()
+
+	assert.Contains(t, html, "Files changed")
+	assert.Contains(t, html, "1 more read")
+	assert.Contains(t, html, `<a href="#turn-0" class="text-slate-900 dark:text-white hover:underline">main.go</a>`)
+	// The second prompt is turn 2 but the third turn on the page, after the event.
+	assert.Contains(t, html, `<a href="#turn-2" class="hover:underline">2</a>`)
+	assert.Contains(t, html, "edit, write")
+	assert.NotContains(t, html, "go.mod</a>")
+}
+
+func TestRenderIndexFileFilter(t *testing.T) {
+	now := time.Date(2026, 2, 15, 10, 0, 0, 0, time.UTC)
+	entries := []core.ManifestEntry{
+		{SessionID: "a", Agent: "claude", CreatedAt: now, Href: "a/index.html", Files: []string{"main.go", "auth/login.go"}},
+		{SessionID: "b", Agent: "claude", CreatedAt: now, Href: "b/index.html"},
+	}
+
+	r := testRenderer()
+	var buf bytes.Buffer
+	require.NoError(t, r.RenderIndex(&buf, entries))
+	html := buf.String()
+	assert.Contains(t, html, `id="file-filter"`)
+	assert.Contains(t, html, `data-files="main.go|auth/login.go|"`)
+
+	buf.Reset()
+	require.NoError(t, r.RenderIndex(&buf, entries[1:]))
+	assert.NotContains(t, buf.String(), `id="file-filter"`)
+}
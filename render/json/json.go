@@ -1,8 +1,154 @@
 // Package json renders transcripts as JSON (serializes the standardized format as-is).
 package json
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/sonnes/chitragupt/core"
+)
+
 // Renderer renders a transcript to JSON.
 type Renderer struct {
 	// Indent controls pretty-printing. When true, output is indented.
 	Indent bool
 }
+
+// New creates a JSON Renderer.
+func New(indent bool) *Renderer {
+	return &Renderer{Indent: indent}
+}
+
+// Render writes the transcript as a single JSON document followed by a newline.
+func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
+	doc := *t
+	doc.SchemaVersion = core.SchemaVersion
+	if doc.Messages == nil {
+		doc.Messages = []core.Message{}
+	}
+	data, err := r.marshal(&doc, "")
+	if err != nil {
+		return fmt.Errorf("marshal transcript: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// header is a transcript without its messages and the fields that follow
+// them. The shallower fields shadow the embedded ones and, being left empty,
+// are omitted.
+type header struct {
+	*core.Transcript
+	Messages  []core.Message     `json:"messages,omitempty"`
+	SubAgents []*core.Transcript `json:"sub_agents,omitempty"`
+	Branches  []core.Branch      `json:"branches,omitempty"`
+}
+
+// RenderStream writes the same document as Render, encoding each message as
+// it arrives instead of marshaling the whole transcript at once. The header
+// fields are marshaled without messages, then the messages array and the
+// fields after it are written one by one.
+func (r *Renderer) RenderStream(w io.Writer, t *core.Transcript, messages core.MessageSeq) error {
+	doc := *t
+	doc.SchemaVersion = core.SchemaVersion
+	data, err := r.marshal(header{Transcript: &doc}, "")
+	if err != nil {
+		return fmt.Errorf("marshal transcript: %w", err)
+	}
+	// Leave the header object open; it always has fields (session_id, agent
+	// and created_at are never omitted).
+	data = bytes.TrimSuffix(data, []byte("}"))
+	data = bytes.TrimRight(data, "\n")
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	if err := r.writeKey(w, "messages"); err != nil {
+		return err
+	}
+	sep, end := ",", "]"
+	if r.Indent {
+		sep, end = ",\n    ", "\n  ]"
+	}
+	n := 0
+	for m, err := range messages {
+		if err != nil {
+			return err
+		}
+		b, err := r.marshal(&m, "    ")
+		if err != nil {
+			return fmt.Errorf("marshal message: %w", err)
+		}
+		prefix := sep
+		if n == 0 {
+			prefix = "[" + sep[1:]
+		}
+		if _, err := io.WriteString(w, prefix); err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		n++
+	}
+	if n == 0 {
+		end = "[]"
+	}
+	if _, err := io.WriteString(w, end); err != nil {
+		return err
+	}
+
+	if len(t.SubAgents) > 0 {
+		if err := r.writeField(w, "sub_agents", t.SubAgents); err != nil {
+			return err
+		}
+	}
+	if len(t.Branches) > 0 {
+		if err := r.writeField(w, "branches", t.Branches); err != nil {
+			return err
+		}
+	}
+
+	end = "}\n"
+	if r.Indent {
+		end = "\n}\n"
+	}
+	_, err = io.WriteString(w, end)
+	return err
+}
+
+// writeKey writes the separator and key of a top-level field that follows
+// other fields.
+func (r *Renderer) writeKey(w io.Writer, key string) error {
+	format := `,%q:`
+	if r.Indent {
+		format = ",\n  %q: "
+	}
+	_, err := fmt.Fprintf(w, format, key)
+	return err
+}
+
+// writeField writes a top-level field that follows other fields.
+func (r *Renderer) writeField(w io.Writer, key string, v any) error {
+	b, err := r.marshal(v, "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", key, err)
+	}
+	if err := r.writeKey(w, key); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r *Renderer) marshal(v any, prefix string) ([]byte, error) {
+	if r.Indent {
+		return json.MarshalIndent(v, prefix, "  ")
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,116 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixture() *core.Transcript {
+	ts := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	return &core.Transcript{
+		SessionID: "s1",
+		Agent:     "claude",
+		Title:     `say "messages":null`,
+		CreatedAt: ts,
+		Usage:     &core.Usage{InputTokens: 10, OutputTokens: 5},
+		Messages: []core.Message{
+			{Role: core.RoleUser, Timestamp: &ts, Content: []core.ContentBlock{
+				{Type: core.BlockText, Format: core.FormatPlain, Text: "hi <b>"},
+			}},
+			{Role: core.RoleAssistant, Content: []core.ContentBlock{
+				{Type: core.BlockToolUse, ToolUseID: "t1", Name: "Bash", Input: map[string]any{"command": "ls"}},
+				{Type: core.BlockText, Format: core.FormatMarkdown, Text: "done"},
+			}},
+		},
+		SubAgents: []*core.Transcript{{SessionID: "a1", Agent: "claude", CreatedAt: ts}},
+	}
+}
+
+func TestRenderMatchesMarshal(t *testing.T) {
+	tr := fixture()
+
+	// The renderer stamps the schema version without changing tr.
+	want := *tr
+	want.SchemaVersion = core.SchemaVersion
+	compact, err := json.Marshal(&want)
+	require.NoError(t, err)
+	indented, err := json.MarshalIndent(&want, "", "  ")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, New(false).Render(&buf, tr))
+	assert.Equal(t, string(compact)+"\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, New(true).Render(&buf, tr))
+	assert.Equal(t, string(indented)+"\n", buf.String())
+	assert.Zero(t, tr.SchemaVersion)
+}
+
+func TestRenderNoMessages(t *testing.T) {
+	tr := fixture()
+	tr.Messages = nil
+
+	for _, indent := range []bool{false, true} {
+		var buf bytes.Buffer
+		require.NoError(t, New(indent).Render(&buf, tr))
+
+		var got core.Transcript
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.NotNil(t, got.Messages)
+		assert.Empty(t, got.Messages)
+	}
+}
+
+func TestRenderStreamError(t *testing.T) {
+	seq := func(yield func(core.Message, error) bool) {
+		yield(core.Message{}, errors.New("read failed"))
+	}
+
+	var buf bytes.Buffer
+	err := New(false).RenderStream(&buf, fixture(), seq)
+	assert.EqualError(t, err, "read failed")
+}
+
+func TestRenderStreamMatchesRender(t *testing.T) {
+	withBranches := fixture()
+	withBranches.Branches = []core.Branch{{ID: "b1", Messages: withBranches.Messages[:1]}}
+	noMessages := fixture()
+	noMessages.Messages = nil
+	noMessages.SubAgents = nil
+	tricky := fixture()
+	tricky.Title = "}\n]\"messages\": null}"
+
+	tests := []struct {
+		name string
+		tr   *core.Transcript
+	}{
+		{"sub-agents", fixture()},
+		{"branches", withBranches},
+		{"no messages", noMessages},
+		{"title with JSON syntax", tricky},
+	}
+	for _, tt := range tests {
+		for _, indent := range []bool{false, true} {
+			r := New(indent)
+			var want, got bytes.Buffer
+			require.NoError(t, r.Render(&want, tt.tr))
+			require.NoError(t, r.RenderStream(&got, tt.tr, core.Messages(tt.tr.Messages)))
+			assert.Equal(t, want.String(), got.String(), "%s, indent=%v", tt.name, indent)
+
+			var back core.Transcript
+			require.NoError(t, json.Unmarshal(got.Bytes(), &back))
+			assert.Equal(t, tt.tr.Title, back.Title)
+			assert.Len(t, back.Messages, len(tt.tr.Messages))
+			assert.Len(t, back.SubAgents, len(tt.tr.SubAgents))
+			assert.Len(t, back.Branches, len(tt.tr.Branches))
+		}
+	}
+}
@@ -1,5 +1,318 @@
 // Package markdown renders transcripts as Markdown documents.
 package markdown
 
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/sonnes/chitragupt/core"
+)
+
 // Renderer renders a transcript to Markdown.
 type Renderer struct{}
+
+// New creates a Markdown Renderer.
+func New() *Renderer {
+	return &Renderer{}
+}
+
+// Render writes the transcript as a Markdown document to w.
+func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
+	return r.RenderStream(w, t, core.Messages(t.Messages))
+}
+
+// RenderStream writes the header of t followed by one section per turn in
+// messages, holding only one turn in memory at a time.
+func (r *Renderer) RenderStream(w io.Writer, t *core.Transcript, messages core.MessageSeq) error {
+	writeHeader(w, t)
+
+	n := 0
+	for turn, err := range core.StreamTurns(messages) {
+		if err != nil {
+			return err
+		}
+		if turn.Event == nil {
+			n++
+		}
+		writeTurn(w, turn, n)
+	}
+	return nil
+}
+
+// writeHeader renders the title and session metadata.
+func writeHeader(w io.Writer, t *core.Transcript) {
+	title := t.Title
+	if title == "" && t.SessionID != "" {
+		title = "Session " + t.SessionID
+	}
+	fmt.Fprintf(w, "# %s\n", title)
+
+	var parts []string
+	if t.Author != "" {
+		parts = append(parts, "@"+t.Author)
+	} else if t.Agent != "" {
+		parts = append(parts, "@"+t.Agent)
+	}
+	if !t.CreatedAt.IsZero() {
+		parts = append(parts, t.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	if t.Model != "" {
+		parts = append(parts, "`"+t.Model+"`")
+	}
+	if t.Dir != "" {
+		dir := "`" + t.Dir + "`"
+		if t.GitBranch != "" {
+			dir += " (" + t.GitBranch + ")"
+		}
+		parts = append(parts, dir)
+	}
+	if len(parts) > 0 {
+		fmt.Fprintf(w, "\n%s\n", strings.Join(parts, " · "))
+	}
+	writeFiles(w, t)
+}
+
+// writeFiles renders the files t changed as a table linking to the turns
+// that touched them.
+func writeFiles(w io.Writer, t *core.Transcript) {
+	var rows []string
+	read := 0
+	for _, f := range t.Files {
+		if f.ReadOnly {
+			read++
+			continue
+		}
+		var lines []string
+		if f.Added > 0 {
+			lines = append(lines, fmt.Sprintf("+%d", f.Added))
+		}
+		if f.Removed > 0 {
+			lines = append(lines, fmt.Sprintf("−%d", f.Removed))
+		}
+		ops := make([]string, len(f.Operations))
+		for i, op := range f.Operations {
+			ops[i] = string(op)
+		}
+		turns := turnLink(f.FirstTurn)
+		if f.LastTurn != f.FirstTurn {
+			turns += "–" + turnLink(f.LastTurn)
+		}
+		path := strings.ReplaceAll(f.RelPath(t.Dir), "|", "\\|")
+		rows = append(rows, fmt.Sprintf("| `%s` | %s | %s | %s |", path, strings.Join(lines, " "), strings.Join(ops, ", "), turns))
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	fmt.Fprint(w, "\n### Files changed\n\n| File | Lines | Tools | Turns |\n| --- | --- | --- | --- |\n")
+	fmt.Fprintln(w, strings.Join(rows, "\n"))
+	switch {
+	case read == 1:
+		fmt.Fprint(w, "\n1 more file read.\n")
+	case read > 1:
+		fmt.Fprintf(w, "\n%d more files read.\n", read)
+	}
+}
+
+// turnLink links to the heading of turn n.
+func turnLink(n int) string {
+	return fmt.Sprintf("[%d](#turn-%d)", n, n)
+}
+
+// writeTurn renders a numbered turn: the user prompt, the steps folded into
+// a <details> block, and the response. Events are not numbered.
+func writeTurn(w io.Writer, turn core.Turn, n int) {
+	if turn.Event != nil {
+		writeEvent(w, core.EventOf(turn.Event))
+		return
+	}
+
+	fmt.Fprintf(w, "\n## Turn %d\n", n)
+
+	if turn.UserMessage != nil {
+		fmt.Fprint(w, "\n### User\n")
+		for _, b := range turn.UserMessage.Content {
+			switch b.Type {
+			case core.BlockText:
+				if text := core.CleanUserText(b.Text); text != "" {
+					fmt.Fprintf(w, "\n%s\n", text)
+				}
+			case core.BlockImage:
+				fmt.Fprintf(w, "\n%s\n", imagePlaceholder(b))
+			}
+		}
+	}
+
+	steps, response := turn.SplitContent()
+	if len(steps) == 0 && len(response) == 0 {
+		return
+	}
+
+	fmt.Fprint(w, "\n### Assistant\n")
+	if len(steps) > 0 {
+		label := "Thinking"
+		if n := turn.StepCount(); n > 0 {
+			label = fmt.Sprintf("%d steps", n)
+		}
+		fmt.Fprintf(w, "\n<details>\n<summary>%s</summary>\n", label)
+		writeSteps(w, steps)
+		fmt.Fprint(w, "\n</details>\n")
+	}
+	for _, b := range response {
+		if text := strings.TrimSpace(b.Text); text != "" {
+			fmt.Fprintf(w, "\n%s\n", text)
+		}
+	}
+}
+
+// writeSteps renders the steps of a turn in order. Tool results and the
+// images tools returned are shown with the call they answer.
+func writeSteps(w io.Writer, steps []core.ContentBlock) {
+	results := make(map[string]core.ContentBlock)
+	images := make(map[string][]core.ContentBlock)
+	for _, b := range steps {
+		switch {
+		case b.Type == core.BlockToolResult && b.ToolUseID != "":
+			results[b.ToolUseID] = b
+		case b.Type == core.BlockImage && b.ToolUseID != "":
+			images[b.ToolUseID] = append(images[b.ToolUseID], b)
+		}
+	}
+
+	for _, b := range steps {
+		switch b.Type {
+		case core.BlockText:
+			if text := strings.TrimSpace(b.Text); text != "" {
+				fmt.Fprintf(w, "\n%s\n", text)
+			}
+		case core.BlockThinking:
+			if text := strings.TrimSpace(b.Text); text != "" {
+				fmt.Fprintf(w, "\n<details>\n<summary>Thinking</summary>\n\n%s\n\n</details>\n", text)
+			}
+		case core.BlockToolUse:
+			var result *core.ContentBlock
+			if r, ok := results[b.ToolUseID]; ok {
+				result = &r
+			}
+			writeToolUse(w, b, result, images[b.ToolUseID])
+		case core.BlockToolResult:
+			if _, ok := results[b.ToolUseID]; !ok || b.ToolUseID == "" {
+				writeOutput(w, b)
+			}
+		case core.BlockImage:
+			if b.ToolUseID == "" {
+				fmt.Fprintf(w, "\n%s\n", imagePlaceholder(b))
+			}
+		}
+	}
+}
+
+// writeToolUse renders a tool call as a <details> block holding its input,
+// its result and the images it returned, followed by a link to the
+// sub-agent it started.
+func writeToolUse(w io.Writer, b core.ContentBlock, result *core.ContentBlock, images []core.ContentBlock) {
+	fmt.Fprintf(w, "\n<details>\n<summary>%s</summary>\n", summarizeToolUse(b))
+	if lang, input := toolInput(b); input != "" {
+		fmt.Fprintf(w, "\n%s\n", fence(lang, input))
+	}
+	if result != nil {
+		writeOutput(w, *result)
+	}
+	for _, img := range images {
+		fmt.Fprintf(w, "\n%s\n", imagePlaceholder(img))
+	}
+	fmt.Fprint(w, "\n</details>\n")
+
+	if ref := b.SubAgentRef; ref != nil {
+		label := ref.AgentID
+		if ref.AgentName != "" {
+			label = ref.AgentName
+		}
+		if ref.AgentType != "" {
+			label += " (" + ref.AgentType + ")"
+		}
+		fmt.Fprintf(w, "\n→ [%s](agent-%s.md)\n", escapeLinkText(label), ref.AgentID)
+	}
+}
+
+// writeOutput renders a tool result as a fenced block, labelled when it is
+// an error.
+func writeOutput(w io.Writer, b core.ContentBlock) {
+	content := strings.TrimRight(b.Content, "\n")
+	if content == "" {
+		return
+	}
+	if b.IsError {
+		fmt.Fprint(w, "\n**Error**\n")
+	}
+	fmt.Fprintf(w, "\n%s\n", fence("", content))
+}
+
+// toolInput returns a tool call's input for a fenced block: a shell command
+// as-is, anything else as indented JSON.
+func toolInput(b core.ContentBlock) (lang, input string) {
+	if m, ok := b.Input.(map[string]any); ok && len(m) == 1 {
+		if cmd, ok := m["command"].(string); ok {
+			return "sh", cmd
+		}
+	}
+	if b.Input == nil {
+		return "", ""
+	}
+	data, err := json.MarshalIndent(b.Input, "", "  ")
+	if err != nil {
+		return "", fmt.Sprintf("%v", b.Input)
+	}
+	return "json", string(data)
+}
+
+// fence wraps s in a code fence longer than any run of backticks in s.
+func fence(lang, s string) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	f := strings.Repeat("`", max(3, longest+1))
+	return f + lang + "\n" + s + "\n" + f
+}
+
+// escapeLinkText escapes the characters that would end Markdown link text.
+func escapeLinkText(s string) string {
+	return strings.NewReplacer("[", "\\[", "]", "\\]").Replace(s)
+}
+
+// writeEvent renders a session event as a rule with its label and summary.
+func writeEvent(w io.Writer, ev *core.Event) {
+	fmt.Fprintf(w, "\n---\n\n*%s*\n", ev.Label())
+	if summary := strings.TrimSpace(ev.Summary); summary != "" {
+		fmt.Fprintf(w, "\n<details>\n<summary>Summary</summary>\n\n%s\n\n</details>\n", summary)
+	}
+	fmt.Fprint(w, "\n---\n")
+}
+
+// imagePlaceholder describes an image block, e.g. "*[image: image/png 1280×720]*".
+func imagePlaceholder(b core.ContentBlock) string {
+	return "*[image: " + b.Image.Label() + "]*"
+}
+
+// summarizeToolUse renders a tool call as its name and most relevant input
+// in HTML, for a <summary> line, e.g. "<b>Bash</b> <code>git status</code>".
+func summarizeToolUse(b core.ContentBlock) string {
+	s := "<b>" + html.EscapeString(b.Name) + "</b>"
+	v := core.NewToolCall(&b).Summary()
+	if v == "" {
+		return s
+	}
+	if i := strings.IndexByte(v, '\n'); i >= 0 {
+		v = v[:i] + "…"
+	}
+	return s + " <code>" + html.EscapeString(v) + "</code>"
+}
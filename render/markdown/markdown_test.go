@@ -0,0 +1,159 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	ts := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	tr := &core.Transcript{
+		SessionID: "s1",
+		Agent:     "claude",
+		Title:     "Fix the build",
+		Model:     "claude-sonnet-4",
+		Dir:       "/repo",
+		GitBranch: "main",
+		CreatedAt: ts,
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{
+				{Type: core.BlockText, Text: "Fix the build"},
+			}},
+			{Role: core.RoleAssistant, Content: []core.ContentBlock{
+				{Type: core.BlockToolUse, ToolUseID: "t1", Name: "Bash", Input: map[string]any{"command": "go build ./..."}},
+			}},
+			{Role: core.RoleUser, Content: []core.ContentBlock{
+				{Type: core.BlockToolResult, ToolUseID: "t1", Content: "ok"},
+			}},
+			{Role: core.RoleAssistant, Content: []core.ContentBlock{
+				{Type: core.BlockText, Text: "The build passes."},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().Render(&buf, tr))
+
+	want := "# Fix the build\n" +
+		"\n@claude · 2025-01-15 10:00 · `claude-sonnet-4` · `/repo` (main)\n" +
+		"\n## Turn 1\n" +
+		"\n### User\n" +
+		"\nFix the build\n" +
+		"\n### Assistant\n" +
+		"\n<details>\n<summary>1 steps</summary>\n" +
+		"\n<details>\n<summary><b>Bash</b> <code>go build ./...</code></summary>\n" +
+		"\n```sh\ngo build ./...\n```\n" +
+		"\n```\nok\n```\n" +
+		"\n</details>\n" +
+		"\n</details>\n" +
+		"\nThe build passes.\n"
+	assert.Equal(t, want, buf.String())
+}
+
+func TestRenderStreamError(t *testing.T) {
+	seq := func(yield func(core.Message, error) bool) {
+		yield(core.Message{}, errors.New("read failed"))
+	}
+
+	var buf bytes.Buffer
+	err := New().RenderStream(&buf, &core.Transcript{SessionID: "s1"}, seq)
+	assert.EqualError(t, err, "read failed")
+	assert.Equal(t, "# Session s1\n", buf.String())
+}
+
+func TestRenderImages(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "s1",
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{
+				{Type: core.BlockImage, Image: &core.Image{MediaType: "image/png", Width: 4, Height: 3}},
+			}},
+			{Role: core.RoleAssistant, Content: []core.ContentBlock{
+				{Type: core.BlockToolUse, ToolUseID: "t1", Name: "Read", Input: map[string]any{"file_path": "a.png"}},
+				{Type: core.BlockImage, ToolUseID: "t1", Image: &core.Image{URL: "a.png"}},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().Render(&buf, tr))
+	assert.Contains(t, buf.String(), "\n*[image: image/png 4×3]*\n")
+	assert.Contains(t, buf.String(), "<summary><b>Read</b> <code>a.png</code></summary>\n"+
+		"\n```json\n{\n  \"file_path\": \"a.png\"\n}\n```\n"+
+		"\n*[image: image]*\n\n</details>\n")
+}
+
+func TestRenderCompactionEvent(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "s1",
+		Messages: []core.Message{
+			{Role: core.RoleSystem, Content: []core.ContentBlock{{Type: core.BlockEvent, Event: &core.Event{
+				Kind: core.EventCompaction, Trigger: "manual", Summary: "Parser half done.",
+			}}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().Render(&buf, tr))
+	assert.Contains(t, buf.String(), "\n---\n\n*context compacted (manual)*\n\n<details>\n<summary>Summary</summary>\n\nParser half done.\n\n</details>\n\n---\n")
+}
+
+func TestRenderToolDetails(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "s1",
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "Explore"}}},
+			{Role: core.RoleAssistant, Content: []core.ContentBlock{
+				{Type: core.BlockThinking, Text: "Look around first."},
+				{Type: core.BlockToolUse, ToolUseID: "t1", Name: "Bash", Input: map[string]any{"command": "cat README.md"}},
+				{Type: core.BlockToolResult, ToolUseID: "t1", Content: "Use ```go``` fences.\n"},
+				{Type: core.BlockToolUse, ToolUseID: "t2", Name: "Grep", Input: map[string]any{"pattern": "<T>"}},
+				{Type: core.BlockToolResult, ToolUseID: "t2", Content: "no matches", IsError: true},
+				{Type: core.BlockToolUse, ToolUseID: "t3", Name: "Task", Input: map[string]any{"description": "find tests"},
+					SubAgentRef: &core.SubAgentRef{AgentID: "ab12", AgentName: "scout", AgentType: "Explore"}},
+			}},
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "Thanks"}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().Render(&buf, tr))
+	s := buf.String()
+
+	assert.Contains(t, s, "\n## Turn 1\n")
+	assert.Contains(t, s, "\n## Turn 2\n")
+	assert.Contains(t, s, "<summary>3 steps</summary>")
+	assert.Contains(t, s, "<details>\n<summary>Thinking</summary>\n\nLook around first.\n\n</details>\n")
+	// The fence outgrows backticks in the output.
+	assert.Contains(t, s, "\n````\nUse ```go``` fences.\n````\n")
+	assert.Contains(t, s, "<summary><b>Grep</b> <code>&lt;T&gt;</code></summary>")
+	assert.Contains(t, s, "\n**Error**\n\n```\nno matches\n```\n")
+	assert.Contains(t, s, "\n→ [scout (Explore)](agent-ab12.md)\n")
+}
+
+func TestRenderFilesChanged(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "s1",
+		Dir:       "/work",
+		Files: []core.FileChange{
+			{Path: "/work/go.mod", Operations: []core.ToolKind{core.ToolRead}, FirstTurn: 1, LastTurn: 1, ReadOnly: true},
+			{Path: "/work/main.go", Operations: []core.ToolKind{core.ToolRead, core.ToolEdit}, Added: 3, Removed: 1, FirstTurn: 1, LastTurn: 2},
+			{Path: "/tmp/a|b.txt", Operations: []core.ToolKind{core.ToolWrite}, Added: 1, FirstTurn: 2, LastTurn: 2},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().Render(&buf, tr))
+	assert.Contains(t, buf.String(), "\n### Files changed\n\n"+
+		"| File | Lines | Tools | Turns |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| `main.go` | +3 −1 | read, edit | [1](#turn-1)–[2](#turn-2) |\n"+
+		"| `/tmp/a\\|b.txt` | +1 | write | [2](#turn-2) |\n"+
+		"\n1 more file read.\n")
+}
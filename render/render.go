@@ -12,3 +12,10 @@ import (
 type Renderer interface {
 	Render(w io.Writer, t *core.Transcript) error
 }
+
+// StreamRenderer is implemented by renderers that can write a transcript
+// while its messages are still being read. t carries the header fields; its
+// Messages are ignored in favour of the stream.
+type StreamRenderer interface {
+	RenderStream(w io.Writer, t *core.Transcript, messages core.MessageSeq) error
+}
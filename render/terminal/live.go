@@ -0,0 +1,90 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sonnes/chitragupt/core"
+)
+
+// RenderLive writes the header of t, then each message in messages as soon as
+// it arrives: a USER card for each prompt, followed by the agent's tool calls
+// and text one line at a time. Unlike RenderStream it never waits for a turn
+// to finish, so it suits sessions the agent is still writing; in exchange,
+// steps are listed as they happen rather than counted, and turn costs are not
+// shown.
+func (r *Renderer) RenderLive(w io.Writer, t *core.Transcript, messages core.MessageSeq) error {
+	width := r.termWidth()
+	l := liveWriter{w: w, width: width, contentWidth: max(width-4, 40)}
+
+	writeHeader(w, t)
+	for m, err := range messages {
+		if err != nil {
+			return err
+		}
+		l.add(&m)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// liveWriter writes a session one message at a time.
+type liveWriter struct {
+	w                   io.Writer
+	width, contentWidth int
+
+	prevTimestamp *time.Time
+	inAssistant   bool // the ASSISTANT badge of the current turn is written
+}
+
+func (l *liveWriter) add(msg *core.Message) {
+	switch {
+	case core.EventOf(msg) != nil:
+		writeEvent(l.w, msg, l.contentWidth, l.width)
+		l.inAssistant = false
+	case core.IsPrompt(msg):
+		var duration string
+		if ts := msg.Timestamp; ts != nil {
+			if l.prevTimestamp != nil {
+				duration = formatDuration(ts.Sub(*l.prevTimestamp))
+			}
+			l.prevTimestamp = ts
+		}
+		writeUser(l.w, msg, duration, "", l.contentWidth, l.width)
+		l.inAssistant = false
+	case msg.Role == core.RoleAssistant:
+		l.assistant(msg)
+	}
+}
+
+// assistant writes the tool calls and text of an assistant message, opening
+// the turn's ASSISTANT section first if needed.
+func (l *liveWriter) assistant(msg *core.Message) {
+	for _, b := range msg.Content {
+		var line string
+		switch b.Type {
+		case core.BlockToolUse:
+			line = styleToolDetail.Render(summarizeToolUse(b))
+		case core.BlockImage:
+			line = styleToolDetail.Render(imagePlaceholder(b))
+		case core.BlockText:
+			text := strings.TrimSpace(b.Text)
+			if text == "" {
+				continue
+			}
+			line = truncate(text, l.contentWidth)
+		default:
+			continue
+		}
+
+		if !l.inAssistant {
+			writeSeparator(l.w, l.width)
+			fmt.Fprintln(l.w)
+			fmt.Fprintln(l.w, " "+styleAssistantBadge.Render("ASSISTANT"))
+			l.inAssistant = true
+		}
+		fmt.Fprintln(l.w, "  "+line)
+	}
+}
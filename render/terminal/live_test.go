@@ -0,0 +1,56 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/sonnes/chitragupt/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderLiveFlushesPartialTurn(t *testing.T) {
+	prompt := func(text string) core.Message {
+		return core.Message{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: text}}}
+	}
+	turn := []core.Message{
+		prompt("Fix the tests"),
+		{Role: core.RoleAssistant, Content: []core.ContentBlock{
+			{Type: core.BlockThinking, Text: "Run them first."},
+			{Type: core.BlockToolUse, ToolUseID: "t1", Name: "Bash", Input: map[string]any{"command": "go test ./..."}},
+		}},
+		{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockToolResult, ToolUseID: "t1", Content: "FAIL"}}},
+		{Role: core.RoleAssistant, Content: []core.ContentBlock{{Type: core.BlockText, Text: "One test fails."}}},
+	}
+
+	var buf bytes.Buffer
+	var beforeNext string
+	messages := func(yield func(core.Message, error) bool) {
+		for _, m := range turn {
+			if !yield(m, nil) {
+				return
+			}
+		}
+		// The turn is still open: nothing marks its end until the next
+		// prompt arrives.
+		beforeNext = ansi.Strip(buf.String())
+		yield(prompt("Now fix it"), nil)
+	}
+
+	r := &Renderer{Width: 80}
+	require.NoError(t, r.RenderLive(&buf, &core.Transcript{SessionID: "live"}, messages))
+
+	assert.Contains(t, beforeNext, " USER")
+	assert.Contains(t, beforeNext, "  Fix the tests\n")
+	assert.Contains(t, beforeNext, " ASSISTANT")
+	assert.Contains(t, beforeNext, "go test ./...")
+	assert.Contains(t, beforeNext, "  One test fails.\n")
+	assert.NotContains(t, beforeNext, "Run them first.")
+	assert.NotContains(t, beforeNext, "FAIL")
+
+	out := ansi.Strip(buf.String())
+	assert.Equal(t, 1, strings.Count(out, " ASSISTANT"))
+	assert.Greater(t, strings.Index(out, "Now fix it"), strings.Index(out, "One test fails."))
+}
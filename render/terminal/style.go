@@ -34,4 +34,6 @@ var (
 	styleToolDetail = lipgloss.NewStyle().Foreground(colorDim)
 
 	styleSeparator = lipgloss.NewStyle().Foreground(colorDim)
+
+	styleEvent = lipgloss.NewStyle().Foreground(colorChanged)
 )
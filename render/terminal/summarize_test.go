@@ -43,6 +43,16 @@ func TestSummarizeToolUse(t *testing.T) {
 			block:  core.ContentBlock{Type: core.BlockToolUse, Name: "Grep", Input: map[string]any{"pattern": "func main"}},
 			expect: "[grep: func main]",
 		},
+		{
+			name:   "codex shell argv",
+			block:  core.ContentBlock{Type: core.BlockToolUse, Name: "shell", Input: map[string]any{"command": []any{"bash", "-lc", "go test ./..."}}},
+			expect: "[shell: go test ./...]",
+		},
+		{
+			name:   "task description",
+			block:  core.ContentBlock{Type: core.BlockToolUse, Name: "Task", Input: map[string]any{"description": "Find auth", "prompt": "Find the auth files"}},
+			expect: "[task: Find auth]",
+		},
 		{
 			name:   "nil input",
 			block:  core.ContentBlock{Type: core.BlockToolUse, Name: "TodoRead", Input: nil},
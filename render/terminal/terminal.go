@@ -28,6 +28,12 @@ func New() *Renderer {
 
 // Render writes the transcript as ANSI-colored turn cards to w.
 func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
+	return r.RenderStream(w, t, core.Messages(t.Messages))
+}
+
+// RenderStream writes the header of t followed by a turn card for each turn
+// in messages, holding only one turn in memory at a time.
+func (r *Renderer) RenderStream(w io.Writer, t *core.Transcript, messages core.MessageSeq) error {
 	width := r.termWidth()
 	contentWidth := width - 4
 	if contentWidth < 40 {
@@ -36,10 +42,13 @@ func (r *Renderer) Render(w io.Writer, t *core.Transcript) error {
 
 	writeHeader(w, t)
 
-	turns := core.GroupTurns(t.Messages)
 	var prevTimestamp *time.Time
 
-	for _, turn := range turns {
+	for turn, err := range core.StreamTurns(messages) {
+		if err != nil {
+			return err
+		}
+
 		var ts *time.Time
 		if turn.UserMessage != nil {
 			ts = turn.UserMessage.Timestamp
@@ -131,23 +140,30 @@ func writeHeader(w io.Writer, t *core.Transcript) {
 // writeUsage renders token counters in two rows: values then labels.
 func writeUsage(w io.Writer, u *core.Usage) {
 	type stat struct {
-		value int
+		value string
 		label string
 	}
 	stats := []stat{
-		{u.InputTokens, "INPUT"},
-		{u.OutputTokens, "OUTPUT"},
+		{formatNumber(u.InputTokens), "INPUT"},
+		{formatNumber(u.OutputTokens), "OUTPUT"},
 	}
 	if u.CacheReadTokens > 0 {
-		stats = append(stats, stat{u.CacheReadTokens, "CACHE READ"})
+		stats = append(stats, stat{formatNumber(u.CacheReadTokens), "CACHE READ"})
 	}
 	if u.CacheCreationTokens > 0 {
-		stats = append(stats, stat{u.CacheCreationTokens, "CACHE WRITE"})
+		stats = append(stats, stat{formatNumber(u.CacheCreationTokens), "CACHE WRITE"})
+	}
+	if u.Cost > 0 {
+		label := "COST"
+		if u.CostEstimated {
+			label = "EST. COST"
+		}
+		stats = append(stats, stat{formatCost(u.Cost), label})
 	}
 
 	var values, labels []string
 	for _, s := range stats {
-		formatted := formatNumber(s.value)
+		formatted := s.value
 		colWidth := max(len(formatted), len(s.label))
 		values = append(values, fmt.Sprintf("%*s", colWidth, formatted))
 		labels = append(labels, fmt.Sprintf("%-*s", colWidth, s.label))
@@ -166,32 +182,18 @@ func writeSeparator(w io.Writer, width int) {
 
 // writeTurn renders a full turn: user prompt, steps summary, and response.
 func writeTurn(w io.Writer, turn core.Turn, duration string, contentWidth, width int) {
+	if turn.Event != nil {
+		writeEvent(w, turn.Event, contentWidth, width)
+		return
+	}
+
 	// User message.
 	if turn.UserMessage != nil {
-		writeSeparator(w, width)
-
-		header := styleUserBadge.Render("USER")
-		var metaParts []string
-		if turn.UserMessage.Timestamp != nil {
-			metaParts = append(metaParts, formatTime(*turn.UserMessage.Timestamp))
-		}
-		if duration != "" {
-			metaParts = append(metaParts, duration)
-		}
-		if len(metaParts) > 0 {
-			header += "    " + styleMeta.Render(strings.Join(metaParts, "    "))
-		}
-		fmt.Fprintln(w)
-		fmt.Fprintln(w, " "+header)
-
-		for _, b := range turn.UserMessage.Content {
-			if b.Type == core.BlockText {
-				text := core.CleanUserText(b.Text)
-				if text != "" {
-					fmt.Fprintln(w, "  "+truncate(text, contentWidth))
-				}
-			}
+		var cost string
+		if u := turn.Usage(); u != nil && u.Cost > 0 {
+			cost = formatCost(u.Cost)
 		}
+		writeUser(w, turn.UserMessage, duration, cost, contentWidth, width)
 	}
 
 	steps, response := turn.SplitContent()
@@ -201,8 +203,11 @@ func writeTurn(w io.Writer, turn core.Turn, duration string, contentWidth, width
 	if stepCount > 0 {
 		var toolNames []string
 		for _, b := range steps {
-			if b.Type == core.BlockToolUse {
+			switch b.Type {
+			case core.BlockToolUse:
 				toolNames = append(toolNames, summarizeToolUse(b))
+			case core.BlockImage:
+				toolNames = append(toolNames, imagePlaceholder(b))
 			}
 		}
 
@@ -235,6 +240,58 @@ func writeTurn(w io.Writer, turn core.Turn, duration string, contentWidth, width
 	}
 }
 
+// writeUser renders the USER card of a prompt. duration and cost are shown
+// next to its timestamp when set.
+func writeUser(w io.Writer, msg *core.Message, duration, cost string, contentWidth, width int) {
+	writeSeparator(w, width)
+
+	header := styleUserBadge.Render("USER")
+	var metaParts []string
+	if msg.Timestamp != nil {
+		metaParts = append(metaParts, formatTime(*msg.Timestamp))
+	}
+	if duration != "" {
+		metaParts = append(metaParts, duration)
+	}
+	if cost != "" {
+		metaParts = append(metaParts, cost)
+	}
+	if len(metaParts) > 0 {
+		header += "    " + styleMeta.Render(strings.Join(metaParts, "    "))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, " "+header)
+
+	for _, b := range msg.Content {
+		switch b.Type {
+		case core.BlockText:
+			text := core.CleanUserText(b.Text)
+			if text != "" {
+				fmt.Fprintln(w, "  "+truncate(text, contentWidth))
+			}
+		case core.BlockImage:
+			fmt.Fprintln(w, "  "+styleToolDetail.Render(imagePlaceholder(b)))
+		}
+	}
+}
+
+// writeEvent renders a session event as a labelled rule, followed by the
+// first line of its summary.
+func writeEvent(w io.Writer, msg *core.Message, contentWidth, width int) {
+	ev := core.EventOf(msg)
+	label := styleEvent.Render(" " + ev.Label() + " ")
+	if msg.Timestamp != nil {
+		label += styleMeta.Render(formatTime(*msg.Timestamp) + " ")
+	}
+	rule := strings.Repeat("─", max(2, min(width, 72)-lipgloss.Width(label)-2))
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, styleSeparator.Render("──")+label+styleSeparator.Render(rule))
+	if ev.Summary != "" {
+		fmt.Fprintln(w, "  "+styleToolDetail.Render(truncate(ev.Summary, contentWidth)))
+	}
+}
+
 // truncate shortens text to maxWidth, appending "..." if needed.
 // Multi-line text is reduced to the first line.
 func truncate(s string, maxWidth int) string {
@@ -294,3 +351,12 @@ func formatNumber(n int) string {
 	}
 	return formatNumber(n/1000) + "," + fmt.Sprintf("%03d", n%1000)
 }
+
+// formatCost formats an amount in USD to the cent, showing amounts under a
+// cent as "<$0.01".
+func formatCost(usd float64) string {
+	if usd > 0 && usd < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
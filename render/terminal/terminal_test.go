@@ -50,6 +50,14 @@ func TestRenderHeader(t *testing.T) {
 	assert.Contains(t, out, "OUTPUT")
 	assert.Contains(t, out, "CACHE READ")
 	assert.Contains(t, out, "CACHE WRITE")
+	assert.NotContains(t, out, "COST")
+
+	tr.Usage.Cost, tr.Usage.CostEstimated = 1.8512, true
+	buf.Reset()
+	require.NoError(t, r.Render(&buf, tr))
+	out = ansi.Strip(buf.String())
+	assert.Contains(t, out, "$1.85")
+	assert.Contains(t, out, "EST. COST")
 }
 
 func TestRenderBasicTranscript(t *testing.T) {
@@ -249,6 +257,67 @@ func TestRenderThinkingBlocks(t *testing.T) {
 	assert.Contains(t, out, "Here's the answer.")
 }
 
+func TestRenderImagePlaceholders(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "test-images",
+		Agent:     "claude",
+		CreatedAt: time.Now(),
+		Messages: []core.Message{
+			{
+				Role: core.RoleUser,
+				Content: []core.ContentBlock{
+					{Type: core.BlockText, Text: "Button is off"},
+					{Type: core.BlockImage, Image: &core.Image{MediaType: "image/png", Data: "x", Width: 1280, Height: 720}},
+				},
+			},
+			{
+				Role: core.RoleAssistant,
+				Content: []core.ContentBlock{
+					{Type: core.BlockToolUse, ToolUseID: "t1", Name: "Read", Input: map[string]any{"file_path": "a.png"}},
+					{Type: core.BlockImage, ToolUseID: "t1", Image: &core.Image{MediaType: "image/png", Width: 4, Height: 3}},
+					{Type: core.BlockText, Text: "Fixed."},
+				},
+			},
+		},
+	}
+
+	r := &Renderer{Width: 80}
+	var buf bytes.Buffer
+	require.NoError(t, r.Render(&buf, tr))
+
+	out := ansi.Strip(buf.String())
+	assert.Contains(t, out, "[image: image/png 1280×720]")
+	assert.Contains(t, out, "[read: a.png]")
+	assert.Contains(t, out, "[image: image/png 4×3]")
+	assert.Contains(t, out, "1 steps")
+}
+
+func TestRenderCompactionEvent(t *testing.T) {
+	tr := &core.Transcript{
+		SessionID: "test-compaction",
+		Agent:     "claude",
+		CreatedAt: time.Now(),
+		Messages: []core.Message{
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "Refactor"}}},
+			{Role: core.RoleSystem, Content: []core.ContentBlock{{Type: core.BlockEvent, Event: &core.Event{
+				Kind: core.EventCompaction, Trigger: "auto", PreTokens: 155123, Summary: "Parser half done.\nMore detail.",
+			}}}},
+			{Role: core.RoleUser, Content: []core.ContentBlock{{Type: core.BlockText, Text: "Keep going"}}},
+		},
+	}
+
+	r := &Renderer{Width: 80}
+	var buf bytes.Buffer
+	require.NoError(t, r.Render(&buf, tr))
+
+	out := ansi.Strip(buf.String())
+	assert.Contains(t, out, "── context compacted (auto, 155,123 tokens) ─")
+	assert.Contains(t, out, "  Parser half done.\n")
+	assert.NotContains(t, out, "More detail.")
+	assert.Less(t, strings.Index(out, "Refactor"), strings.Index(out, "context compacted"))
+	assert.Less(t, strings.Index(out, "context compacted"), strings.Index(out, "Keep going"))
+}
+
 func TestRenderMessageTimestamps(t *testing.T) {
 	t1 := time.Date(2026, 2, 3, 3, 26, 0, 0, time.UTC)
 	t2 := t1.Add(5 * time.Second)
@@ -296,6 +365,13 @@ func TestFormatNumber(t *testing.T) {
 	}
 }
 
+func TestFormatCost(t *testing.T) {
+	assert.Equal(t, "$0.00", formatCost(0))
+	assert.Equal(t, "<$0.01", formatCost(0.004))
+	assert.Equal(t, "$0.01", formatCost(0.01))
+	assert.Equal(t, "$12.35", formatCost(12.345))
+}
+
 func TestFormatDuration(t *testing.T) {
 	tests := []struct {
 		in   time.Duration